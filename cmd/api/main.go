@@ -3,6 +3,7 @@ package main
 import (
 	"backend_proyecto_verde/internal/config"
 	"backend_proyecto_verde/internal/handlers"
+	"backend_proyecto_verde/internal/realtime"
 	"backend_proyecto_verde/internal/repository/postgres"
 	"backend_proyecto_verde/internal/routes"
 	"backend_proyecto_verde/pkg/database"
@@ -10,6 +11,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
+	// Incluye la base de zonas horarias por si la imagen no la trae
+	_ "time/tzdata"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -45,6 +49,12 @@ func main() {
 	if err := runMigrations(dbConfig); err != nil {
 		log.Fatalf("Error al ejecutar migraciones: %v", err)
 	}
+	// Zona horaria en la que empieza el día para los topes diarios de los torneos
+	zonaHoraria := getEnv("ZONA_HORARIA", "America/Merida")
+	if _, err := time.LoadLocation(zonaHoraria); err != nil {
+		log.Fatalf("Zona horaria no válida %q: %v", zonaHoraria, err)
+	}
+
 	// Inicializar el cliente de BunnyStorage
 	bunnyClient, storageZone, err := config.InitBunnyStorageClient()
 	if err != nil {
@@ -53,20 +63,26 @@ func main() {
 
 	// Inicializar repositorios
 	userRepo := postgres.NewUserRepository(db)
-	torneoRepo := postgres.NewTorneoRepository(db)
-	userActionsRepo := postgres.NewUserActionsRepository(db)
-	userFriendsRepo := postgres.NewUserFriendsRepository(db)
-	medallasRepo := postgres.NewMedallasRepository(db, userRepo)
+	torneoRepo := postgres.NewTorneoRepository(db, zonaHoraria)
+	userActionsRepo := postgres.NewUserActionsRepository(db, zonaHoraria)
+	userFriendsRepo := postgres.NewUserFriendsRepository(db, zonaHoraria)
+	medallasRepo := postgres.NewMedallasRepository(db, zonaHoraria)
+	temporadasRepo := postgres.NewTemporadasRepository(db)
 
 	// Inicializar handlers
+	marcadorHandler := handlers.NewMarcadorHandler(torneoRepo, userRepo, realtime.NewHub())
 	userHandler := handlers.NewUserHandler(userRepo)
-	torneoHandler := handlers.NewTorneoHandler(torneoRepo)
-	userActionsHandler := handlers.NewUserActionsHandler(userActionsRepo, medallasRepo, bunnyClient, storageZone)
+	torneoHandler := handlers.NewTorneoHandler(torneoRepo, marcadorHandler)
+	userActionsHandler := handlers.NewUserActionsHandler(userActionsRepo, medallasRepo, marcadorHandler, bunnyClient, storageZone)
 	userFriendsHandler := handlers.NewUserFriendsHandler(userFriendsRepo)
-	medallasHandler := handlers.NewMedallasHandler(medallasRepo)
+	medallasHandler := handlers.NewMedallasHandler(medallasRepo, bunnyClient, storageZone)
+	temporadasHandler := handlers.NewTemporadasHandler(temporadasRepo)
+	// Capas del avatar que trae la app, para los artículos sin capa subida
+	avatarHandler := handlers.NewAvatarHandler(userRepo, bunnyClient, storageZone,
+		getEnv("AVATAR_ASSETS_DIR", "frontend/assets/assets"))
 
 	// Inicializar cron jobs
-	initCronJobs(torneoHandler)
+	initCronJobs(torneoHandler, temporadasHandler, medallasHandler)
 
 	// Configurar rutas
 	router := routes.SetupRoutes(
@@ -75,6 +91,9 @@ func main() {
 		userActionsHandler,
 		userFriendsHandler,
 		medallasHandler,
+		temporadasHandler,
+		marcadorHandler,
+		avatarHandler,
 	)
 
 	// Configurar CORS usando rs/cors
@@ -96,13 +115,18 @@ func main() {
 }
 
 // initCronJobs configura y arranca todos los trabajos programados
-func initCronJobs(torneoHandler *handlers.TorneoHandler) {
+func initCronJobs(torneoHandler *handlers.TorneoHandler, temporadasHandler *handlers.TemporadasHandler, medallasHandler *handlers.MedallasHandler) {
 	// Crear una nueva instancia del programador cron
 	c := cron.New(cron.WithSeconds())
 
 	// Añadir trabajo para finalizar torneos vencidos (cada minuto)
 	// El formato es: segundo minuto hora díaDelMes mes díaDeLaSemana
 	_, err := c.AddFunc("0 * * * * *", func() {
+		// Primero se cierran las rondas de las eliminatorias para que la final tenga ganador
+		if err := torneoHandler.AvanzarEliminatorias(); err != nil {
+			log.Printf("Error al avanzar torneos eliminatoria: %v", err)
+		}
+
 		log.Println("Ejecutando verificación de torneos vencidos...")
 		if err := torneoHandler.FinalizeTournaments(); err != nil {
 			log.Printf("Error al finalizar torneos vencidos: %v", err)
@@ -114,6 +138,28 @@ func initCronJobs(torneoHandler *handlers.TorneoHandler) {
 		log.Printf("Error al programar trabajo de finalización de torneos: %v", err)
 	}
 
+	// Añadir trabajo para crear los torneos de las plantillas recurrentes (cada minuto,
+	// desfasado para que primero se finalicen los torneos vencidos)
+	_, err = c.AddFunc("30 * * * * *", func() {
+		if err := temporadasHandler.GenerarTorneosProgramados(); err != nil {
+			log.Printf("Error al generar torneos programados: %v", err)
+		}
+	})
+	if err != nil {
+		log.Printf("Error al programar trabajo de torneos recurrentes: %v", err)
+	}
+
+	// Añadir trabajo para otorgar por lotes las medallas nuevas o cambiadas a los
+	// usuarios existentes (cada 10 segundos)
+	_, err = c.AddFunc("*/10 * * * * *", func() {
+		if err := medallasHandler.ProcesarBackfills(); err != nil {
+			log.Printf("Error al otorgar medallas retroactivas: %v", err)
+		}
+	})
+	if err != nil {
+		log.Printf("Error al programar trabajo de medallas retroactivas: %v", err)
+	}
+
 	// Iniciar el programador en una goroutine
 	c.Start()
 
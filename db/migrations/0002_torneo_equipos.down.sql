@@ -0,0 +1,22 @@
+ALTER TABLE torneos ADD COLUMN ganador_versus BOOLEAN;
+ALTER TABLE torneo_estadisticas ADD COLUMN equipo BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Solo los dos primeros equipos pueden representarse con el booleano
+UPDATE torneo_estadisticas te
+SET equipo = (e.orden = 0)
+FROM torneo_equipos e
+WHERE e.id = te.id_equipo;
+
+UPDATE torneos t
+SET ganador_versus = (e.orden = 0)
+FROM torneo_equipos e
+WHERE e.id = t.ganador_equipo;
+
+ALTER TABLE torneo_estadisticas ALTER COLUMN equipo DROP DEFAULT;
+
+ALTER TABLE torneos DROP CONSTRAINT IF EXISTS fk_torneos_ganador_equipo;
+ALTER TABLE torneos DROP COLUMN IF EXISTS ganador_equipo;
+ALTER TABLE torneo_estadisticas DROP CONSTRAINT IF EXISTS fk_torneo_estadisticas_equipo;
+ALTER TABLE torneo_estadisticas DROP COLUMN IF EXISTS id_equipo;
+
+DROP TABLE IF EXISTS torneo_equipos;
@@ -0,0 +1,48 @@
+-- Equipos de torneo: reemplaza el booleano torneo_estadisticas.equipo
+-- para permitir más de dos equipos en la modalidad Versus
+CREATE TABLE torneo_equipos (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  id_torneo UUID NOT NULL,
+  nombre VARCHAR(100) NOT NULL,
+  color VARCHAR(20) NOT NULL,
+  id_capitan UUID,
+  orden INT NOT NULL DEFAULT 0,
+  CONSTRAINT pk_torneo_equipos PRIMARY KEY (id),
+  CONSTRAINT fk_torneo_equipos_torneo FOREIGN KEY (id_torneo) REFERENCES torneos(id) ON DELETE CASCADE,
+  CONSTRAINT fk_torneo_equipos_capitan FOREIGN KEY (id_capitan) REFERENCES user_access(id) ON DELETE SET NULL,
+  CONSTRAINT uq_torneo_equipos_nombre UNIQUE (id_torneo, nombre)
+);
+
+CREATE INDEX idx_torneo_equipos_torneo ON torneo_equipos (id_torneo);
+
+ALTER TABLE torneo_estadisticas ADD COLUMN id_equipo UUID;
+ALTER TABLE torneo_estadisticas
+  ADD CONSTRAINT fk_torneo_estadisticas_equipo FOREIGN KEY (id_equipo) REFERENCES torneo_equipos(id) ON DELETE SET NULL;
+
+ALTER TABLE torneos ADD COLUMN ganador_equipo UUID;
+ALTER TABLE torneos
+  ADD CONSTRAINT fk_torneos_ganador_equipo FOREIGN KEY (ganador_equipo) REFERENCES torneo_equipos(id) ON DELETE SET NULL;
+
+-- Migrar los dos equipos booleanos de los torneos Versus existentes
+-- (equipo = true pasa a "Equipo A", equipo = false a "Equipo B")
+INSERT INTO torneo_equipos (id_torneo, nombre, color, orden)
+SELECT id, 'Equipo A', '#2E7D32', 0 FROM torneos WHERE modalidad = 'Versus';
+
+INSERT INTO torneo_equipos (id_torneo, nombre, color, orden)
+SELECT id, 'Equipo B', '#1565C0', 1 FROM torneos WHERE modalidad = 'Versus';
+
+UPDATE torneo_estadisticas te
+SET id_equipo = e.id
+FROM torneo_equipos e
+WHERE e.id_torneo = te.id_torneo
+AND e.orden = CASE WHEN te.equipo THEN 0 ELSE 1 END;
+
+UPDATE torneos t
+SET ganador_equipo = e.id
+FROM torneo_equipos e
+WHERE e.id_torneo = t.id
+AND t.ganador_versus IS NOT NULL
+AND e.orden = CASE WHEN t.ganador_versus THEN 0 ELSE 1 END;
+
+ALTER TABLE torneo_estadisticas DROP COLUMN equipo;
+ALTER TABLE torneos DROP COLUMN ganador_versus;
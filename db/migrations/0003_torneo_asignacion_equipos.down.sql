@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS torneo_cambios_equipo;
+
+ALTER TABLE torneos DROP CONSTRAINT IF EXISTS chk_torneos_asignacion_equipos;
+ALTER TABLE torneos
+  DROP COLUMN IF EXISTS asignacion_equipos,
+  DROP COLUMN IF EXISTS equipos_bloqueados,
+  DROP COLUMN IF EXISTS permitir_cambio_equipo,
+  DROP COLUMN IF EXISTS cambio_requiere_aprobacion;
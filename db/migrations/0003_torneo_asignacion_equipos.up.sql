@@ -0,0 +1,28 @@
+-- Reglas de asignación y cambio de equipos en torneos Versus
+ALTER TABLE torneos
+  ADD COLUMN asignacion_equipos VARCHAR(20) NOT NULL DEFAULT 'libre',
+  ADD COLUMN equipos_bloqueados BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN permitir_cambio_equipo BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN cambio_requiere_aprobacion BOOLEAN NOT NULL DEFAULT TRUE;
+
+ALTER TABLE torneos
+  ADD CONSTRAINT chk_torneos_asignacion_equipos CHECK (asignacion_equipos IN ('libre', 'cantidad', 'puntos'));
+
+-- Solicitudes de cambio de equipo (necesita torneos, user_access y torneo_equipos)
+CREATE TABLE torneo_cambios_equipo (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  id_torneo UUID NOT NULL,
+  id_jugador UUID NOT NULL,
+  id_equipo_origen UUID,
+  id_equipo_destino UUID NOT NULL,
+  estado VARCHAR(20) NOT NULL DEFAULT 'pendiente' CHECK (estado IN ('pendiente', 'aprobado', 'rechazado', 'cancelado')),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  resuelto_at TIMESTAMP,
+  CONSTRAINT pk_torneo_cambios_equipo PRIMARY KEY (id),
+  CONSTRAINT fk_torneo_cambios_equipo_torneo FOREIGN KEY (id_torneo) REFERENCES torneos(id) ON DELETE CASCADE,
+  CONSTRAINT fk_torneo_cambios_equipo_jugador FOREIGN KEY (id_jugador) REFERENCES user_access(id) ON DELETE CASCADE,
+  CONSTRAINT fk_torneo_cambios_equipo_origen FOREIGN KEY (id_equipo_origen) REFERENCES torneo_equipos(id) ON DELETE SET NULL,
+  CONSTRAINT fk_torneo_cambios_equipo_destino FOREIGN KEY (id_equipo_destino) REFERENCES torneo_equipos(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_torneo_cambios_equipo_torneo ON torneo_cambios_equipo (id_torneo, estado);
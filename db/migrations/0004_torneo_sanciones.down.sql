@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS torneo_sanciones;
+
+ALTER TABLE torneo_estadisticas DROP COLUMN IF EXISTS motivo_deshabilitado;
@@ -0,0 +1,20 @@
+-- Motivo visible para el jugador cuando el organizador deshabilita su puntuación
+ALTER TABLE torneo_estadisticas ADD COLUMN motivo_deshabilitado TEXT;
+
+-- Sanciones aplicadas por organizadores a participantes (necesita torneos y user_access)
+CREATE TABLE torneo_sanciones (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  id_torneo UUID NOT NULL,
+  id_jugador UUID NOT NULL,
+  id_organizador UUID NOT NULL,
+  tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('deshabilitado', 'habilitado', 'expulsado', 'baneado')),
+  motivo TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  levantado_at TIMESTAMP,
+  CONSTRAINT pk_torneo_sanciones PRIMARY KEY (id),
+  CONSTRAINT fk_torneo_sanciones_torneo FOREIGN KEY (id_torneo) REFERENCES torneos(id) ON DELETE CASCADE,
+  CONSTRAINT fk_torneo_sanciones_jugador FOREIGN KEY (id_jugador) REFERENCES user_access(id) ON DELETE CASCADE,
+  CONSTRAINT fk_torneo_sanciones_organizador FOREIGN KEY (id_organizador) REFERENCES user_access(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_torneo_sanciones_jugador ON torneo_sanciones (id_torneo, id_jugador);
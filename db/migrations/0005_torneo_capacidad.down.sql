@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS torneo_lista_espera;
+
+ALTER TABLE torneos
+  DROP COLUMN IF EXISTS fecha_cierre_inscripcion,
+  DROP COLUMN IF EXISTS max_por_equipo,
+  DROP COLUMN IF EXISTS max_participantes;
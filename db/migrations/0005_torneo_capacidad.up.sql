@@ -0,0 +1,21 @@
+-- Capacidad y cierre de inscripciones de los torneos
+ALTER TABLE torneos
+  ADD COLUMN max_participantes INT CHECK (max_participantes > 0),
+  ADD COLUMN max_por_equipo INT CHECK (max_por_equipo > 0),
+  ADD COLUMN fecha_cierre_inscripcion TIMESTAMP;
+
+-- Lista de espera de jugadores que intentaron inscribirse con el torneo lleno
+CREATE TABLE torneo_lista_espera (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  id_torneo UUID NOT NULL,
+  id_jugador UUID NOT NULL,
+  id_equipo UUID,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT pk_torneo_lista_espera PRIMARY KEY (id),
+  CONSTRAINT uq_torneo_lista_espera UNIQUE (id_torneo, id_jugador),
+  CONSTRAINT fk_torneo_lista_espera_torneo FOREIGN KEY (id_torneo) REFERENCES torneos(id) ON DELETE CASCADE,
+  CONSTRAINT fk_torneo_lista_espera_jugador FOREIGN KEY (id_jugador) REFERENCES user_access(id) ON DELETE CASCADE,
+  CONSTRAINT fk_torneo_lista_espera_equipo FOREIGN KEY (id_equipo) REFERENCES torneo_equipos(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_torneo_lista_espera_torneo ON torneo_lista_espera (id_torneo, created_at);
@@ -0,0 +1,5 @@
+ALTER TABLE torneos
+  DROP CONSTRAINT IF EXISTS chk_torneos_geocerca_tipo,
+  DROP COLUMN IF EXISTS geocerca_poligono,
+  DROP COLUMN IF EXISTS geocerca_radio_metros,
+  DROP COLUMN IF EXISTS geocerca_tipo;
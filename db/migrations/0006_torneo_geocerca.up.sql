@@ -0,0 +1,8 @@
+-- Área del evento: solo puntúan las acciones hechas dentro de ella
+ALTER TABLE torneos
+  ADD COLUMN geocerca_tipo VARCHAR(20) NOT NULL DEFAULT 'ninguna',
+  ADD COLUMN geocerca_radio_metros INT CHECK (geocerca_radio_metros > 0),
+  ADD COLUMN geocerca_poligono JSONB;
+
+ALTER TABLE torneos
+  ADD CONSTRAINT chk_torneos_geocerca_tipo CHECK (geocerca_tipo IN ('ninguna', 'radio', 'poligono'));
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_user_actions_torneo;
+
+ALTER TABLE user_actions
+  DROP CONSTRAINT fk_user_actions_torneo,
+  ADD CONSTRAINT fk_user_actions_torneo FOREIGN KEY (id_torneo) REFERENCES torneos(id) ON DELETE CASCADE;
+
+ALTER TABLE user_actions
+  DROP COLUMN IF EXISTS puntos_torneo;
+
+DROP TABLE IF EXISTS torneo_reglas_accion;
+
+ALTER TABLE torneos
+  DROP COLUMN IF EXISTS bono_lugar_nuevo,
+  DROP COLUMN IF EXISTS max_puntos_diarios;
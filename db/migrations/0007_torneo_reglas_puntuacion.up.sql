@@ -0,0 +1,25 @@
+-- Reglas de puntuación propias de cada torneo
+ALTER TABLE torneos
+  ADD COLUMN max_puntos_diarios INT CHECK (max_puntos_diarios > 0),
+  ADD COLUMN bono_lugar_nuevo INT NOT NULL DEFAULT 0 CHECK (bono_lugar_nuevo >= 0);
+
+-- Puntos por tipo de acción. Los tipos sin fila usan los puntos por defecto
+CREATE TABLE torneo_reglas_accion (
+  id_torneo UUID NOT NULL,
+  tipo_accion VARCHAR(50) NOT NULL CHECK (tipo_accion IN ('ayuda', 'alerta', 'descubrimiento')),
+  puntos INT NOT NULL CHECK (puntos >= 0),
+  habilitado BOOLEAN NOT NULL DEFAULT TRUE,
+  CONSTRAINT pk_torneo_reglas_accion PRIMARY KEY (id_torneo, tipo_accion),
+  CONSTRAINT fk_torneo_reglas_accion_torneo FOREIGN KEY (id_torneo) REFERENCES torneos(id) ON DELETE CASCADE
+);
+
+-- Puntos que cada acción sumó en su torneo (para el tope diario y para descontarlos al borrarla)
+ALTER TABLE user_actions
+  ADD COLUMN puntos_torneo INT NOT NULL DEFAULT 0;
+
+-- Borrar un torneo no debe borrar las acciones de los jugadores
+ALTER TABLE user_actions
+  DROP CONSTRAINT fk_user_actions_torneo,
+  ADD CONSTRAINT fk_user_actions_torneo FOREIGN KEY (id_torneo) REFERENCES torneos(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_user_actions_torneo ON user_actions (id_torneo, user_id, created_at);
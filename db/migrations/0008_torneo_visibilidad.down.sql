@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_torneos_publicos;
+
+ALTER TABLE torneos
+  DROP CONSTRAINT IF EXISTS chk_torneos_visibilidad,
+  DROP COLUMN IF EXISTS visibilidad;
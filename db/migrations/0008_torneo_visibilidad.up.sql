@@ -0,0 +1,10 @@
+-- Visibilidad de los torneos. Los existentes solo se podían encontrar por código,
+-- así que quedan como no listados; los nuevos son públicos por defecto
+ALTER TABLE torneos
+  ADD COLUMN visibilidad VARCHAR(20) NOT NULL DEFAULT 'no_listada';
+
+ALTER TABLE torneos
+  ALTER COLUMN visibilidad SET DEFAULT 'publica',
+  ADD CONSTRAINT chk_torneos_visibilidad CHECK (visibilidad IN ('publica', 'no_listada', 'privada'));
+
+CREATE INDEX idx_torneos_publicos ON torneos (visibilidad, finalizado, fecha_inicio);
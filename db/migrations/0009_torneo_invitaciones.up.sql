@@ -0,0 +1,21 @@
+-- Invitaciones de amigos a un torneo (necesita torneos, user_access y torneo_equipos)
+CREATE TABLE torneo_invitaciones (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  id_torneo UUID NOT NULL,
+  id_invitador UUID NOT NULL,
+  id_invitado UUID NOT NULL,
+  id_equipo UUID,
+  estado VARCHAR(20) NOT NULL DEFAULT 'pendiente' CHECK (estado IN ('pendiente', 'aceptada', 'rechazada', 'cancelada')),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  respondida_at TIMESTAMP,
+  CONSTRAINT pk_torneo_invitaciones PRIMARY KEY (id),
+  CONSTRAINT fk_torneo_invitaciones_torneo FOREIGN KEY (id_torneo) REFERENCES torneos(id) ON DELETE CASCADE,
+  CONSTRAINT fk_torneo_invitaciones_invitador FOREIGN KEY (id_invitador) REFERENCES user_access(id) ON DELETE CASCADE,
+  CONSTRAINT fk_torneo_invitaciones_invitado FOREIGN KEY (id_invitado) REFERENCES user_access(id) ON DELETE CASCADE,
+  CONSTRAINT fk_torneo_invitaciones_equipo FOREIGN KEY (id_equipo) REFERENCES torneo_equipos(id) ON DELETE SET NULL,
+  CONSTRAINT check_no_self_invitation CHECK (id_invitador <> id_invitado)
+);
+
+-- Un jugador solo puede tener una invitación pendiente por torneo
+CREATE UNIQUE INDEX uq_torneo_invitaciones_pendiente ON torneo_invitaciones (id_torneo, id_invitado) WHERE estado = 'pendiente';
+CREATE INDEX idx_torneo_invitaciones_invitado ON torneo_invitaciones (id_invitado, estado);
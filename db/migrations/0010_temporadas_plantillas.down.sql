@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_torneos_temporada;
+
+ALTER TABLE torneos
+  DROP CONSTRAINT IF EXISTS fk_torneos_plantilla,
+  DROP CONSTRAINT IF EXISTS fk_torneos_temporada,
+  DROP COLUMN IF EXISTS id_plantilla,
+  DROP COLUMN IF EXISTS id_temporada;
+
+DROP TABLE IF EXISTS torneo_plantillas;
+DROP TABLE IF EXISTS temporadas;
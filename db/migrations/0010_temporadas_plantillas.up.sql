@@ -0,0 +1,42 @@
+-- Temporadas: agrupan torneos y llevan una clasificación acumulada
+CREATE TABLE temporadas (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  id_creator UUID NOT NULL,
+  nombre VARCHAR(100) NOT NULL,
+  fecha_inicio TIMESTAMP NOT NULL,
+  fecha_fin TIMESTAMP NOT NULL,
+  puntuacion VARCHAR(20) NOT NULL DEFAULT 'puntos' CHECK (puntuacion IN ('puntos', 'posicion')),
+  puntos_posicion INT[] NOT NULL DEFAULT '{10, 8, 6, 5, 4, 3, 2, 1}',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT pk_temporadas PRIMARY KEY (id),
+  CONSTRAINT fk_temporadas_creator FOREIGN KEY (id_creator) REFERENCES user_access(id) ON DELETE CASCADE,
+  CONSTRAINT check_temporadas_fechas CHECK (fecha_fin > fecha_inicio)
+);
+
+-- Plantillas de torneos recurrentes. configuracion guarda el torneo a crear en cada ejecución
+CREATE TABLE torneo_plantillas (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  id_creator UUID NOT NULL,
+  id_temporada UUID,
+  nombre VARCHAR(100) NOT NULL,
+  recurrencia VARCHAR(100) NOT NULL,
+  duracion_minutos INT NOT NULL CHECK (duracion_minutos > 0),
+  configuracion JSONB NOT NULL,
+  activa BOOLEAN NOT NULL DEFAULT TRUE,
+  proxima_ejecucion TIMESTAMP NOT NULL,
+  ultima_ejecucion TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT pk_torneo_plantillas PRIMARY KEY (id),
+  CONSTRAINT fk_torneo_plantillas_creator FOREIGN KEY (id_creator) REFERENCES user_access(id) ON DELETE CASCADE,
+  CONSTRAINT fk_torneo_plantillas_temporada FOREIGN KEY (id_temporada) REFERENCES temporadas(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_torneo_plantillas_proxima ON torneo_plantillas (activa, proxima_ejecucion);
+
+ALTER TABLE torneos
+  ADD COLUMN id_temporada UUID,
+  ADD COLUMN id_plantilla UUID,
+  ADD CONSTRAINT fk_torneos_temporada FOREIGN KEY (id_temporada) REFERENCES temporadas(id) ON DELETE SET NULL,
+  ADD CONSTRAINT fk_torneos_plantilla FOREIGN KEY (id_plantilla) REFERENCES torneo_plantillas(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_torneos_temporada ON torneos (id_temporada);
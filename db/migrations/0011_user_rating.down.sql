@@ -0,0 +1,7 @@
+UPDATE torneos SET asignacion_equipos = 'puntos' WHERE asignacion_equipos = 'rating';
+ALTER TABLE torneos DROP CONSTRAINT chk_torneos_asignacion_equipos;
+ALTER TABLE torneos
+  ADD CONSTRAINT chk_torneos_asignacion_equipos CHECK (asignacion_equipos IN ('libre', 'cantidad', 'puntos'));
+
+DROP TABLE IF EXISTS user_rating_historial;
+DROP TABLE IF EXISTS user_rating;
@@ -0,0 +1,33 @@
+-- Rating de habilidad (ELO) de cada jugador. Se actualiza al finalizar cada torneo
+CREATE TABLE user_rating (
+  user_id UUID NOT NULL,
+  rating INT NOT NULL DEFAULT 1500,
+  torneos_calificados INT NOT NULL DEFAULT 0,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT pk_user_rating PRIMARY KEY (user_id),
+  CONSTRAINT fk_user_rating_user FOREIGN KEY (user_id) REFERENCES user_access(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_rating_rating ON user_rating (rating DESC);
+
+-- Historial de cambios de rating por torneo
+CREATE TABLE user_rating_historial (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL,
+  id_torneo UUID,
+  rating_anterior INT NOT NULL,
+  rating_nuevo INT NOT NULL,
+  posicion INT NOT NULL,
+  participantes INT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT pk_user_rating_historial PRIMARY KEY (id),
+  CONSTRAINT fk_user_rating_historial_user FOREIGN KEY (user_id) REFERENCES user_access(id) ON DELETE CASCADE,
+  CONSTRAINT fk_user_rating_historial_torneo FOREIGN KEY (id_torneo) REFERENCES torneos(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_user_rating_historial_user ON user_rating_historial (user_id, created_at DESC);
+
+-- Nuevo modo de asignación de equipos equilibrado por rating
+ALTER TABLE torneos DROP CONSTRAINT chk_torneos_asignacion_equipos;
+ALTER TABLE torneos
+  ADD CONSTRAINT chk_torneos_asignacion_equipos CHECK (asignacion_equipos IN ('libre', 'cantidad', 'puntos', 'rating'));
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS torneo_llaves;
+
+ALTER TABLE torneos
+  DROP CONSTRAINT IF EXISTS chk_torneos_duracion_ronda,
+  DROP CONSTRAINT IF EXISTS chk_torneos_formato,
+  DROP COLUMN IF EXISTS duracion_ronda_minutos,
+  DROP COLUMN IF EXISTS formato;
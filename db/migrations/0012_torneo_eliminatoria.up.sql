@@ -0,0 +1,29 @@
+-- Formato de competición: clásico (gana quien más puntúa) o eliminatoria por rondas
+ALTER TABLE torneos
+  ADD COLUMN formato VARCHAR(20) NOT NULL DEFAULT 'clasico',
+  ADD COLUMN duracion_ronda_minutos INT,
+  ADD CONSTRAINT chk_torneos_formato CHECK (formato IN ('clasico', 'eliminatoria')),
+  ADD CONSTRAINT chk_torneos_duracion_ronda CHECK (
+    formato <> 'eliminatoria' OR (duracion_ronda_minutos IS NOT NULL AND duracion_ronda_minutos > 0)
+  );
+
+-- Enfrentamientos de la llave. Los competidores son jugadores (Individual) o equipos (Versus).
+-- El ganador del enfrentamiento (ronda, posicion) pasa a (ronda + 1, posicion / 2)
+CREATE TABLE torneo_llaves (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  id_torneo UUID NOT NULL,
+  ronda INT NOT NULL,
+  posicion INT NOT NULL,
+  competidor_a UUID NOT NULL,
+  competidor_b UUID,
+  semilla_a INT NOT NULL,
+  semilla_b INT,
+  puntos_a INT,
+  puntos_b INT,
+  ganador UUID,
+  inicio TIMESTAMP NOT NULL,
+  fin TIMESTAMP NOT NULL,
+  CONSTRAINT pk_torneo_llaves PRIMARY KEY (id),
+  CONSTRAINT fk_torneo_llaves_torneo FOREIGN KEY (id_torneo) REFERENCES torneos(id) ON DELETE CASCADE,
+  CONSTRAINT uq_torneo_llaves_posicion UNIQUE (id_torneo, ronda, posicion)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS torneo_staff;
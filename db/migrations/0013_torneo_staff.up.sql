@@ -0,0 +1,18 @@
+-- Organizadores de cada torneo: un dueño y cualquier cantidad de coorganizadores.
+-- torneos.id_creator se mantiene como el dueño actual
+CREATE TABLE torneo_staff (
+  id_torneo UUID NOT NULL,
+  user_id UUID NOT NULL,
+  rol VARCHAR(20) NOT NULL CHECK (rol IN ('dueno', 'coorganizador')),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT pk_torneo_staff PRIMARY KEY (id_torneo, user_id),
+  CONSTRAINT fk_torneo_staff_torneo FOREIGN KEY (id_torneo) REFERENCES torneos(id) ON DELETE CASCADE,
+  CONSTRAINT fk_torneo_staff_user FOREIGN KEY (user_id) REFERENCES user_access(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX uq_torneo_staff_dueno ON torneo_staff (id_torneo) WHERE rol = 'dueno';
+CREATE INDEX idx_torneo_staff_user ON torneo_staff (user_id);
+
+INSERT INTO torneo_staff (id_torneo, user_id, rol)
+SELECT id, id_creator, 'dueno'
+FROM torneos;
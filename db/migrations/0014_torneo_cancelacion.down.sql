@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS user_notificaciones;
+
+ALTER TABLE torneos
+  DROP CONSTRAINT IF EXISTS chk_torneos_cancelado,
+  DROP COLUMN IF EXISTS motivo_cancelacion,
+  DROP COLUMN IF EXISTS fecha_cancelacion,
+  DROP COLUMN IF EXISTS cancelado;
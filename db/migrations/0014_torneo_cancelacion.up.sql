@@ -0,0 +1,22 @@
+-- Un torneo cancelado queda finalizado y sin ganador, y se conserva para el historial
+ALTER TABLE torneos
+  ADD COLUMN cancelado BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN fecha_cancelacion TIMESTAMP,
+  ADD COLUMN motivo_cancelacion TEXT,
+  ADD CONSTRAINT chk_torneos_cancelado CHECK (NOT cancelado OR finalizado);
+
+-- Avisos para los usuarios (necesita user_access y torneos)
+CREATE TABLE user_notificaciones (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL,
+  tipo VARCHAR(40) NOT NULL,
+  mensaje TEXT NOT NULL,
+  id_torneo UUID,
+  leida BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT pk_user_notificaciones PRIMARY KEY (id),
+  CONSTRAINT fk_user_notificaciones_user FOREIGN KEY (user_id) REFERENCES user_access(id) ON DELETE CASCADE,
+  CONSTRAINT fk_user_notificaciones_torneo FOREIGN KEY (id_torneo) REFERENCES torneos(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_user_notificaciones_user ON user_notificaciones (user_id, leida, created_at DESC);
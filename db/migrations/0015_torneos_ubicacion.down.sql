@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_torneos_publicos_ubicacion;
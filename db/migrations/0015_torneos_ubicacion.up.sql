@@ -0,0 +1,3 @@
+-- Índice para el prefiltro por rectángulo de la búsqueda de torneos cercanos
+CREATE INDEX idx_torneos_publicos_ubicacion ON torneos (ubicacion_a_latitud, ubicacion_a_longitud)
+  WHERE visibilidad = 'publica' AND finalizado = false;
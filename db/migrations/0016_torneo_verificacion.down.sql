@@ -0,0 +1,9 @@
+ALTER TABLE user_actions
+  DROP CONSTRAINT IF EXISTS fk_user_actions_anulada_por,
+  DROP COLUMN IF EXISTS puntos_anulados,
+  DROP COLUMN IF EXISTS motivo_anulacion,
+  DROP COLUMN IF EXISTS anulada_por,
+  DROP COLUMN IF EXISTS anulada_at;
+
+ALTER TABLE torneos
+  DROP COLUMN IF EXISTS minutos_verificacion;
@@ -0,0 +1,14 @@
+-- Periodo opcional de revisión tras la fecha de fin. Mientras dura, el torneo queda
+-- pendiente de verificación: los organizadores pueden anular acciones y el ganador
+-- se decide al cerrarse el periodo o cuando un organizador confirma los resultados
+ALTER TABLE torneos
+  ADD COLUMN minutos_verificacion INT CHECK (minutos_verificacion > 0);
+
+-- Anulación de acciones por un organizador. La acción conserva en puntos_anulados lo
+-- que había sumado y puntos_torneo pasa a cero
+ALTER TABLE user_actions
+  ADD COLUMN anulada_at TIMESTAMP,
+  ADD COLUMN anulada_por UUID,
+  ADD COLUMN motivo_anulacion TEXT,
+  ADD COLUMN puntos_anulados INT NOT NULL DEFAULT 0,
+  ADD CONSTRAINT fk_user_actions_anulada_por FOREIGN KEY (anulada_por) REFERENCES user_access(id) ON DELETE SET NULL;
@@ -0,0 +1,27 @@
+-- Criterios de las medallas. Los criterios de un mismo grupo deben cumplirse todos (AND)
+-- y basta con cumplir un grupo (OR)
+CREATE TABLE medalla_criterios (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  id_medalla UUID NOT NULL,
+  grupo INT NOT NULL DEFAULT 0,
+  metrica VARCHAR(255) NOT NULL,
+  operador VARCHAR(2) NOT NULL CHECK (operador IN ('>=', '>', '<=', '<', '=')),
+  umbral INT NOT NULL,
+  CONSTRAINT pk_medalla_criterios PRIMARY KEY (id),
+  CONSTRAINT fk_medalla_criterios_medalla FOREIGN KEY (id_medalla) REFERENCES medallas(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_medalla_criterios_medalla ON medalla_criterios (id_medalla, grupo);
+
+-- Cada indicador requiere_* pasa a ser un grupo propio, lo que conserva la semántica OR
+INSERT INTO medalla_criterios (id_medalla, grupo, metrica, operador, umbral)
+SELECT m.id, r.grupo, r.metrica, '>=', m.numero_requerido
+FROM medallas m
+CROSS JOIN LATERAL (VALUES
+  (0, 'amigos', m.requiere_amistades),
+  (1, 'puntos', m.requiere_puntos),
+  (2, 'acciones', m.requiere_acciones),
+  (3, 'torneos', m.requiere_torneos),
+  (4, 'torneos_ganados', m.requiere_victoria_torneos)
+) AS r(grupo, metrica, activo)
+WHERE r.activo AND m.numero_requerido IS NOT NULL;
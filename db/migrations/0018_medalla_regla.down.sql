@@ -0,0 +1 @@
+ALTER TABLE medallas DROP COLUMN IF EXISTS regla;
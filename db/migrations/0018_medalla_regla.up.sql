@@ -0,0 +1,3 @@
+-- Texto original de la regla con la que se creó la medalla; los criterios que se
+-- evalúan siguen en medalla_criterios
+ALTER TABLE medallas ADD COLUMN regla TEXT;
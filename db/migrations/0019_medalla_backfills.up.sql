@@ -0,0 +1,23 @@
+-- Otorgamiento retroactivo de medallas: al crear o cambiar una medalla se encola una
+-- evaluación de todos los usuarios que el cron procesa por lotes
+CREATE TABLE medalla_backfills (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  id_medalla UUID NOT NULL,
+  estado VARCHAR(20) NOT NULL DEFAULT 'pendiente'
+    CHECK (estado IN ('pendiente', 'en_proceso', 'completado', 'cancelado', 'error')),
+  total_usuarios INT NOT NULL DEFAULT 0,
+  procesados INT NOT NULL DEFAULT 0,
+  otorgadas INT NOT NULL DEFAULT 0,
+  -- Último usuario evaluado; los lotes avanzan por id para no recorrer la tabla otra vez
+  ultimo_usuario UUID,
+  error TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  iniciado_at TIMESTAMP,
+  terminado_at TIMESTAMP,
+  CONSTRAINT pk_medalla_backfills PRIMARY KEY (id),
+  CONSTRAINT fk_medalla_backfills_medalla FOREIGN KEY (id_medalla) REFERENCES medallas(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_medalla_backfills_medalla ON medalla_backfills (id_medalla, created_at DESC);
+CREATE INDEX idx_medalla_backfills_activos ON medalla_backfills (created_at)
+  WHERE estado IN ('pendiente', 'en_proceso');
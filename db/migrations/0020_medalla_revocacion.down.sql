@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS medallas_revocadas;
+ALTER TABLE medallas DROP COLUMN IF EXISTS politica_revocacion;
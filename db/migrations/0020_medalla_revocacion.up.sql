@@ -0,0 +1,18 @@
+-- Política de cada medalla ante usuarios que dejan de cumplir sus criterios
+ALTER TABLE medallas ADD COLUMN politica_revocacion VARCHAR(20) NOT NULL DEFAULT 'revocable'
+  CHECK (politica_revocacion IN ('permanente', 'revocable'));
+
+-- Historial de medallas que los usuarios perdieron
+CREATE TABLE medallas_revocadas (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  id_usuario UUID NOT NULL,
+  id_medalla UUID NOT NULL,
+  fecha_ganada TIMESTAMP NOT NULL,
+  fecha_revocada TIMESTAMP NOT NULL DEFAULT NOW(),
+  motivo TEXT NOT NULL,
+  CONSTRAINT pk_medallas_revocadas PRIMARY KEY (id),
+  CONSTRAINT fk_medallas_revocadas_usuario FOREIGN KEY (id_usuario) REFERENCES user_access(id) ON DELETE CASCADE,
+  CONSTRAINT fk_medallas_revocadas_medalla FOREIGN KEY (id_medalla) REFERENCES medallas(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_medallas_revocadas_usuario ON medallas_revocadas (id_usuario, fecha_revocada DESC);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_user_actions_recientes;
+DROP INDEX IF EXISTS idx_medallas_ganadas_medalla;
+ALTER TABLE medallas
+  DROP COLUMN IF EXISTS retirada_at,
+  DROP COLUMN IF EXISTS imagen,
+  DROP COLUMN IF EXISTS orden,
+  DROP COLUMN IF EXISTS categoria;
@@ -0,0 +1,11 @@
+-- Catálogo de medallas: arte, categoría, orden de presentación y retiro
+ALTER TABLE medallas
+  ADD COLUMN categoria VARCHAR(50),
+  ADD COLUMN orden INT NOT NULL DEFAULT 0,
+  ADD COLUMN imagen VARCHAR(255),
+  -- Una medalla retirada ya no se puede ganar; quien la tiene la conserva
+  ADD COLUMN retirada_at TIMESTAMP;
+
+-- Rareza: poseedores de cada medalla entre los usuarios con acciones recientes
+CREATE INDEX idx_medallas_ganadas_medalla ON medallas_ganadas (id_medalla, id_usuario);
+CREATE INDEX idx_user_actions_recientes ON user_actions (created_at, user_id) WHERE deleted_at IS NULL;
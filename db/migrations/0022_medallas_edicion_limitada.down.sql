@@ -0,0 +1,7 @@
+ALTER TABLE medallas
+  DROP CONSTRAINT IF EXISTS chk_medallas_ventana,
+  DROP CONSTRAINT IF EXISTS fk_medallas_torneo,
+  DROP COLUMN IF EXISTS ciudad,
+  DROP COLUMN IF EXISTS id_torneo,
+  DROP COLUMN IF EXISTS disponible_hasta,
+  DROP COLUMN IF EXISTS disponible_desde;
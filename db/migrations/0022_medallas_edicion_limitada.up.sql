@@ -0,0 +1,9 @@
+-- Medallas por tiempo limitado y de eventos: solo se pueden ganar dentro de su ventana y,
+-- opcionalmente, participando en un torneo o con acciones en una ciudad
+ALTER TABLE medallas
+  ADD COLUMN disponible_desde TIMESTAMP,
+  ADD COLUMN disponible_hasta TIMESTAMP,
+  ADD COLUMN id_torneo UUID,
+  ADD COLUMN ciudad VARCHAR(100),
+  ADD CONSTRAINT fk_medallas_torneo FOREIGN KEY (id_torneo) REFERENCES torneos(id) ON DELETE SET NULL,
+  ADD CONSTRAINT chk_medallas_ventana CHECK (disponible_desde IS NULL OR disponible_hasta IS NULL OR disponible_desde < disponible_hasta);
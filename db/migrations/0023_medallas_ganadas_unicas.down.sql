@@ -0,0 +1 @@
+ALTER TABLE medallas_ganadas DROP CONSTRAINT IF EXISTS uq_medallas_ganadas_usuario_medalla;
@@ -0,0 +1,18 @@
+-- Una medalla se gana una sola vez: se conserva la primera asignación de cada par
+DELETE FROM medallas_ganadas
+WHERE id IN (
+  SELECT id FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY id_usuario, id_medalla ORDER BY fecha_ganada, id) AS n
+    FROM medallas_ganadas
+  ) duplicadas
+  WHERE n > 1
+);
+
+-- Los duplicados también inflaron el contador de medallas pendientes
+UPDATE user_stats us
+SET pending_medalla = LEAST(us.pending_medalla,
+  (SELECT COUNT(*) FROM medallas_ganadas mg WHERE mg.id_usuario = us.user_id))
+WHERE us.pending_medalla > 0;
+
+ALTER TABLE medallas_ganadas
+  ADD CONSTRAINT uq_medallas_ganadas_usuario_medalla UNIQUE (id_usuario, id_medalla);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_avatar_items;
+DROP TABLE IF EXISTS avatar_items;
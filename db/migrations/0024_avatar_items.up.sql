@@ -0,0 +1,56 @@
+-- Catálogo de artículos de avatar. valor es lo que se guarda en la columna de
+-- user_profile que indica categoria
+CREATE TABLE avatar_items (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  categoria VARCHAR(30) NOT NULL
+    CHECK (categoria IN ('cabello', 'vestimenta', 'barba', 'detalle_facial', 'detalle_adicional')),
+  valor TEXT NOT NULL,
+  nombre VARCHAR(255),
+  tipo_desbloqueo VARCHAR(20) NOT NULL DEFAULT 'libre'
+    CHECK (tipo_desbloqueo IN ('libre', 'medalla', 'puntos', 'nivel', 'victoria_torneo')),
+  -- Puntos, nivel o torneos ganados que pide el artículo
+  umbral INT,
+  id_medalla UUID,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  CONSTRAINT pk_avatar_items PRIMARY KEY (id),
+  CONSTRAINT uq_avatar_items_valor UNIQUE (categoria, valor),
+  CONSTRAINT fk_avatar_items_medalla FOREIGN KEY (id_medalla) REFERENCES medallas(id) ON DELETE CASCADE,
+  CONSTRAINT ck_avatar_items_medalla CHECK ((tipo_desbloqueo = 'medalla') = (id_medalla IS NOT NULL)),
+  CONSTRAINT ck_avatar_items_umbral CHECK (
+    (tipo_desbloqueo IN ('puntos', 'nivel', 'victoria_torneo')) = (umbral IS NOT NULL AND umbral > 0)
+  )
+);
+
+-- Inventario: artículos que cada usuario ya desbloqueó. Los libres no se guardan y
+-- un desbloqueo no se pierde aunque después baje el puntaje o se revoque la medalla
+CREATE TABLE user_avatar_items (
+  id_usuario UUID NOT NULL,
+  id_item UUID NOT NULL,
+  desbloqueado_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  CONSTRAINT pk_user_avatar_items PRIMARY KEY (id_usuario, id_item),
+  CONSTRAINT fk_user_avatar_items_usuario FOREIGN KEY (id_usuario) REFERENCES user_access(id) ON DELETE CASCADE,
+  CONSTRAINT fk_user_avatar_items_item FOREIGN KEY (id_item) REFERENCES avatar_items(id) ON DELETE CASCADE
+);
+
+-- Los artículos que trae la app (frontend/assets/assets/accesorios, donde '0' es no
+-- llevar nada) y los valores que ya usan los perfiles quedan como artículos libres, para
+-- que nadie pierda su avatar ni las opciones que ya tenía
+INSERT INTO avatar_items (categoria, valor) VALUES
+  ('cabello', 'default'), ('cabello', '1'), ('cabello', '2'), ('cabello', '3'), ('cabello', '4'), ('cabello', '5'), ('cabello', '6'), ('cabello', '7'), ('cabello', '8'), ('cabello', '9'), ('cabello', '10'),
+  ('vestimenta', 'default'), ('vestimenta', '1'), ('vestimenta', '2'), ('vestimenta', '3'), ('vestimenta', '4'), ('vestimenta', '5'),
+  ('barba', '0'), ('barba', '1'), ('barba', '2'), ('barba', '3'), ('barba', '4'), ('barba', '5'), ('barba', '6'),
+  ('detalle_facial', '0'), ('detalle_facial', '1'), ('detalle_facial', '2'),
+  ('detalle_adicional', '0'), ('detalle_adicional', '1'), ('detalle_adicional', '2');
+
+INSERT INTO avatar_items (categoria, valor)
+SELECT DISTINCT c.categoria, c.valor
+FROM user_profile up
+CROSS JOIN LATERAL (VALUES
+  ('cabello', up.cabello),
+  ('vestimenta', up.vestimenta),
+  ('barba', up.barba),
+  ('detalle_facial', up.detalle_facial),
+  ('detalle_adicional', up.detalle_adicional)
+) AS c(categoria, valor)
+WHERE c.valor IS NOT NULL AND c.valor <> ''
+ON CONFLICT (categoria, valor) DO NOTHING;
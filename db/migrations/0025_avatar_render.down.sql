@@ -0,0 +1,2 @@
+ALTER TABLE user_profile DROP COLUMN IF EXISTS avatar_hash;
+ALTER TABLE avatar_items DROP COLUMN IF EXISTS capa;
@@ -0,0 +1,8 @@
+-- Capa de imagen de cada artículo de avatar para componer el avatar en el servidor:
+-- un PNG cuadrado con transparencia, del mismo encuadre en todos los artículos. Si es
+-- nula se usa la capa que trae la app en accesorios/<categoria>/<valor>.png
+ALTER TABLE avatar_items ADD COLUMN capa TEXT;
+
+-- Hash de las capas que forman el avatar del usuario. Identifica los renders en caché;
+-- se borra al editar el perfil o cambiar una capa y se recalcula en el siguiente render
+ALTER TABLE user_profile ADD COLUMN avatar_hash VARCHAR(64);
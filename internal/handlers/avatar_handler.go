@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"backend_proyecto_verde/internal/repository/postgres"
+	"backend_proyecto_verde/internal/utils"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/bunnystorage-go"
+	"github.com/gorilla/mux"
+)
+
+const (
+	ladoAvatarPorDefecto = 256
+	ladoAvatarMinimo     = 16
+	ladoAvatarMaximo     = 1024
+	// Las capas pueden ser más grandes que el render más grande; las de la app miden 1120
+	ladoCapaMaximo = 2048
+	// maxRendersAvatar es el tope de renders en memoria; al llenarse la caché se vacía
+	maxRendersAvatar = 2000
+	// maxCapasAvatar es el tope de capas descargadas que se guardan en memoria
+	maxCapasAvatar = 500
+)
+
+// AvatarHandler dibuja los avatares a partir de las capas de sus artículos y gestiona
+// esas capas. Los renders se guardan en memoria por hash de avatar, formato y tamaño;
+// como EditUserProfile borra el hash del perfil, un avatar editado nunca usa un render viejo.
+// Los artículos sin capa subida usan la de la app en dirAssets/accesorios, y debajo de
+// todo se dibuja dirAssets/personaje_base.png
+type AvatarHandler struct {
+	repo        *postgres.UserRepository
+	bunnyClient *bunnystorage.Client
+	storageZone string
+	dirAssets   string
+
+	mu      sync.Mutex
+	renders map[string][]byte
+	capas   map[string][]byte // Contenido de cada capa por URL o ruta; cada subida usa una URL nueva
+}
+
+func NewAvatarHandler(repo *postgres.UserRepository, bunnyClient *bunnystorage.Client, storageZone, dirAssets string) *AvatarHandler {
+	return &AvatarHandler{
+		repo:        repo,
+		bunnyClient: bunnyClient,
+		storageZone: storageZone,
+		dirAssets:   dirAssets,
+		renders:     make(map[string][]byte),
+		capas:       make(map[string][]byte),
+	}
+}
+
+// GetAvatar devuelve el avatar del usuario como SVG o PNG de size x size píxeles, para
+// usarlo directamente como URL de imagen
+func (h *AvatarHandler) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, formato := vars["id"], vars["formato"]
+
+	lado := ladoAvatarPorDefecto
+	if valor := r.URL.Query().Get("size"); valor != "" {
+		n, err := strconv.Atoi(valor)
+		if err != nil || n < ladoAvatarMinimo || n > ladoAvatarMaximo {
+			utils.RespondWithValidationError(w, "Tamaño no válido",
+				fmt.Sprintf("size debe ser un entero entre %d y %d", ladoAvatarMinimo, ladoAvatarMaximo))
+			return
+		}
+		lado = n
+	}
+
+	hash, capas, err := h.repo.GetCapasAvatar(id)
+	if err != nil {
+		if errors.Is(err, postgres.ErrPerfilNoEncontrado) {
+			utils.RespondWithNotFound(w, "Perfil de usuario no encontrado", err.Error())
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al obtener el avatar", err.Error())
+		return
+	}
+
+	clave := fmt.Sprintf("%s-%d.%s", hash, lado, formato)
+	etag := `"` + clave + `"`
+	// El avatar cambia con el perfil sin cambiar de URL, así que el cliente lo guarda
+	// pero lo revalida con el ETag en cada uso
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	h.mu.Lock()
+	contenido, ok := h.renders[clave]
+	h.mu.Unlock()
+
+	if !ok {
+		contenido, err = h.dibujarAvatar(r.Context(), capas, lado, formato)
+		if err != nil {
+			utils.RespondWithInternalServerError(w, "Error al dibujar el avatar", err.Error())
+			return
+		}
+
+		h.mu.Lock()
+		if len(h.renders) >= maxRendersAvatar {
+			h.renders = make(map[string][]byte)
+		}
+		h.renders[clave] = contenido
+		h.mu.Unlock()
+	}
+
+	if formato == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+	} else {
+		w.Header().Set("Content-Type", "image/png")
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(contenido)
+}
+
+// dibujarAvatar compone el personaje base y las capas en el formato pedido. Las capas
+// de la app que no existen, como el '0' de no llevar nada, se omiten
+func (h *AvatarHandler) dibujarAvatar(ctx context.Context, fuentes []string, lado int, formato string) ([]byte, error) {
+	rutas := []string{capaPersonajeBase}
+	for _, fuente := range fuentes {
+		if !strings.HasPrefix(fuente, "https://") {
+			fuente = "accesorios/" + fuente
+		}
+		rutas = append(rutas, fuente)
+	}
+
+	var capas [][]byte
+	var nombres []string
+	for _, fuente := range rutas {
+		contenido, err := h.obtenerCapa(ctx, fuente)
+		if err != nil {
+			return nil, err
+		}
+		if len(contenido) > 0 {
+			capas = append(capas, contenido)
+			nombres = append(nombres, fuente)
+		}
+	}
+
+	if formato == "svg" {
+		return utils.AvatarSVG(capas, lado), nil
+	}
+
+	imagenes := make([]image.Image, len(capas))
+	for i, capa := range capas {
+		img, err := png.Decode(bytes.NewReader(capa))
+		if err != nil {
+			return nil, fmt.Errorf("la capa %s no es un PNG válido: %w", nombres[i], err)
+		}
+		imagenes[i] = img
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, utils.ComponerAvatar(imagenes, lado)); err != nil {
+		return nil, fmt.Errorf("error al codificar el avatar: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// capaPersonajeBase es la capa que va debajo de todos los artículos
+const capaPersonajeBase = "personaje_base.png"
+
+// obtenerCapa devuelve el PNG de una capa desde la memoria, el almacenamiento (si es una
+// URL) o los assets de la app (si es una ruta dentro de dirAssets). Devuelve vacío si
+// la app no trae esa capa
+func (h *AvatarHandler) obtenerCapa(ctx context.Context, fuente string) ([]byte, error) {
+	h.mu.Lock()
+	contenido, ok := h.capas[fuente]
+	h.mu.Unlock()
+	if ok {
+		return contenido, nil
+	}
+
+	var err error
+	if strings.HasPrefix(fuente, "https://") {
+		fileName := fuente[strings.LastIndex(fuente, "/")+1:]
+		contenido, _, err = h.bunnyClient.Download(ctx, "/avatar", fileName)
+		if err != nil {
+			return nil, fmt.Errorf("error al descargar la capa %s: %w", fileName, err)
+		}
+	} else {
+		contenido, err = os.ReadFile(filepath.Join(h.dirAssets, filepath.Clean("/"+fuente)))
+		if errors.Is(err, fs.ErrNotExist) {
+			contenido, err = []byte{}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error al leer la capa %s: %w", fuente, err)
+		}
+	}
+
+	h.mu.Lock()
+	if len(h.capas) >= maxCapasAvatar {
+		h.capas = make(map[string][]byte)
+	}
+	h.capas[fuente] = contenido
+	h.mu.Unlock()
+
+	return contenido, nil
+}
+
+// SubirCapaAvatarItem sube la capa con la que se dibuja un artículo de avatar y
+// reemplaza la anterior. Debe ser un PNG cuadrado con transparencia
+func (h *AvatarHandler) SubirCapaAvatarItem(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := r.ParseMultipartForm(5 << 20); err != nil {
+		utils.RespondWithBadRequest(w, "Error al procesar el formulario", err.Error())
+		return
+	}
+
+	file, _, err := r.FormFile("capa")
+	if err != nil {
+		utils.RespondWithBadRequest(w, "La capa es obligatoria", err.Error())
+		return
+	}
+	defer file.Close()
+
+	contenido, err := io.ReadAll(file)
+	if err != nil {
+		utils.RespondWithBadRequest(w, "Error al leer la capa", err.Error())
+		return
+	}
+
+	config, err := png.DecodeConfig(bytes.NewReader(contenido))
+	if err != nil {
+		utils.RespondWithValidationError(w, "Formato de capa no válido", "la capa debe ser un PNG")
+		return
+	}
+	if config.Width != config.Height || config.Width < ladoAvatarMinimo || config.Width > ladoCapaMaximo {
+		utils.RespondWithValidationError(w, "Tamaño de capa no válido",
+			fmt.Sprintf("la capa debe ser cuadrada, de %d a %d píxeles por lado", ladoAvatarMinimo, ladoCapaMaximo))
+		return
+	}
+
+	fileName := fmt.Sprintf("%s-%d.png", id, time.Now().Unix())
+	if _, err := h.bunnyClient.Upload(context.Background(), "/avatar", fileName, "", bytes.NewReader(contenido)); err != nil {
+		utils.RespondWithInternalServerError(w, "Error al subir la capa", err.Error())
+		return
+	}
+	capa := fmt.Sprintf("https://%s/avatar/%s", h.storageZone, fileName)
+
+	anterior, err := h.repo.CambiarCapaAvatarItem(id, capa)
+	if err != nil {
+		h.borrarCapa(capa)
+		if errors.Is(err, postgres.ErrItemAvatarNoEncontrado) {
+			utils.RespondWithNotFound(w, "Artículo de avatar no encontrado", err.Error())
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al guardar la capa del artículo de avatar", err.Error())
+		return
+	}
+
+	if anterior != nil {
+		h.borrarCapa(*anterior)
+	}
+
+	utils.RespondWithSuccess(w, map[string]string{"capa": capa}, "Capa del artículo de avatar actualizada correctamente")
+}
+
+// borrarCapa elimina del almacenamiento una capa a partir de su URL. Solo registra los
+// errores, porque el artículo ya no la usa
+func (h *AvatarHandler) borrarCapa(capa string) {
+	fileName := capa[strings.LastIndex(capa, "/")+1:]
+	if _, err := h.bunnyClient.Delete(context.Background(), "/avatar", fileName); err != nil {
+		log.Printf("Error al eliminar la capa de avatar %s: %v", fileName, err)
+	}
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/internal/realtime"
+	"backend_proyecto_verde/internal/repository/postgres"
+	"backend_proyecto_verde/internal/utils"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// Intervalo de los comentarios que mantienen viva la conexión. Debe ser menor que
+	// el proxy_read_timeout de nginx (60 segundos por defecto)
+	intervaloHeartbeat = 20 * time.Second
+	// Espera que se sugiere al cliente antes de reconectarse, en milisegundos
+	reintentoMarcadorMs = 3000
+)
+
+// MarcadorHandler transmite el marcador de los torneos en vivo mediante Server-Sent Events
+type MarcadorHandler struct {
+	torneoRepo *postgres.TorneoRepository
+	userRepo   *postgres.UserRepository
+	hub        *realtime.Hub
+}
+
+func NewMarcadorHandler(torneoRepo *postgres.TorneoRepository, userRepo *postgres.UserRepository, hub *realtime.Hub) *MarcadorHandler {
+	return &MarcadorHandler{torneoRepo: torneoRepo, userRepo: userRepo, hub: hub}
+}
+
+// StreamMarcador abre un flujo de eventos con el marcador del torneo. Al conectarse (y
+// al reconectarse) se envía el estado completo en un evento "marcador"; luego llegan
+// los eventos "accion" y "marcador" a medida que los participantes puntúan. Cada 20
+// segundos se envía un comentario para mantener la conexión abierta
+func (h *MarcadorHandler) StreamMarcador(w http.ResponseWriter, r *http.Request) {
+	torneoID := mux.Vars(r)["id"]
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.RespondWithInternalServerError(w, "El servidor no admite respuestas en streaming", "http.Flusher no disponible")
+		return
+	}
+
+	torneo, err := h.torneoRepo.GetTorneoByID(torneoID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondWithNotFound(w, "Torneo no encontrado", "No se encontró el torneo con el ID proporcionado")
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al obtener el torneo", err.Error())
+		return
+	}
+
+	// Suscribirse antes de leer el estado para no perder eventos intermedios
+	eventos, cancelar := h.hub.Suscribir(torneoID)
+	defer cancelar()
+
+	marcador, err := h.marcador(torneoID, torneo.Finalizado)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener el marcador", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Evita que nginx acumule la respuesta antes de enviarla
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", reintentoMarcadorMs)
+	inicial := realtime.Evento{ID: h.hub.UltimoID(torneoID), Tipo: models.EventoMarcador, Datos: marcador}
+	if err := escribirEvento(w, inicial); err != nil {
+		return
+	}
+
+	if torneo.Finalizado {
+		tipo := models.EventoFinalizado
+		if torneo.Cancelado {
+			tipo = models.EventoCancelado
+		}
+		escribirEvento(w, realtime.Evento{ID: inicial.ID, Tipo: tipo, Datos: torneo})
+		flusher.Flush()
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(intervaloHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case evento, ok := <-eventos:
+			// El canal se cierra si el torneo terminó o si el cliente se atrasó;
+			// en el segundo caso el cliente se reconecta y recibe el estado completo
+			if !ok {
+				return
+			}
+			if err := escribirEvento(w, evento); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// escribirEvento escribe un evento con el formato de Server-Sent Events
+func escribirEvento(w http.ResponseWriter, evento realtime.Evento) error {
+	datos, err := json.Marshal(evento.Datos)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", evento.ID, evento.Tipo, datos)
+	return err
+}
+
+// marcador arma el estado completo del marcador de un torneo
+func (h *MarcadorHandler) marcador(torneoID string, finalizado bool) (*models.MarcadorTorneo, error) {
+	jugadores, err := h.userRepo.GetRankingTorneo(torneoID)
+	if err != nil {
+		return nil, err
+	}
+
+	equipos, err := h.torneoRepo.GetRankingEquipos(torneoID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MarcadorTorneo{
+		IDTorneo:      torneoID,
+		Finalizado:    finalizado,
+		Jugadores:     jugadores,
+		Equipos:       equipos,
+		ActualizadoAt: time.Now(),
+	}, nil
+}
+
+// PublicarAccion avisa a los suscriptores del torneo de una acción recién registrada y,
+// si puntuó, envía el marcador actualizado
+func (h *MarcadorHandler) PublicarAccion(action *models.UserAction) {
+	resultado := action.ResultadoTorneo
+	if resultado == nil || h.hub.Suscriptores(resultado.IDTorneo) == 0 {
+		return
+	}
+
+	h.hub.Publicar(resultado.IDTorneo, models.EventoAccion, models.AccionMarcador{
+		IDAccion:   action.ID,
+		UserID:     action.UserID,
+		TipoAccion: action.TipoAccion,
+		Lugar:      action.Lugar,
+		Puntua:     resultado.Puntua,
+		Puntos:     resultado.Puntos,
+		Motivo:     resultado.Motivo,
+		CreatedAt:  action.CreatedAt,
+	})
+
+	if resultado.Puntua {
+		h.PublicarMarcador(resultado.IDTorneo)
+	}
+}
+
+// PublicarMarcador envía el marcador actualizado a los suscriptores del torneo
+func (h *MarcadorHandler) PublicarMarcador(torneoID string) {
+	if h.hub.Suscriptores(torneoID) == 0 {
+		return
+	}
+
+	marcador, err := h.marcador(torneoID, false)
+	if err != nil {
+		log.Printf("Error al obtener el marcador del torneo %s: %v", torneoID, err)
+		return
+	}
+
+	h.hub.Publicar(torneoID, models.EventoMarcador, marcador)
+}
+
+// PublicarFin envía el marcador final y el evento de cierre (finalizado o cancelado) y
+// desconecta a los suscriptores del torneo
+func (h *MarcadorHandler) PublicarFin(torneoID string, tipo string) {
+	if h.hub.Suscriptores(torneoID) == 0 {
+		return
+	}
+
+	marcador, err := h.marcador(torneoID, true)
+	if err != nil {
+		log.Printf("Error al obtener el marcador del torneo %s: %v", torneoID, err)
+	} else {
+		h.hub.Publicar(torneoID, models.EventoMarcador, marcador)
+	}
+
+	torneo, err := h.torneoRepo.GetTorneoByID(torneoID)
+	if err != nil {
+		log.Printf("Error al obtener el torneo %s: %v", torneoID, err)
+	} else {
+		h.hub.Publicar(torneoID, tipo, torneo)
+	}
+
+	h.hub.Cerrar(torneoID)
+}
@@ -4,18 +4,37 @@ import (
 	"backend_proyecto_verde/internal/models"
 	"backend_proyecto_verde/internal/repository/postgres"
 	"backend_proyecto_verde/internal/utils"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
+	"git.sr.ht/~jamesponddotco/bunnystorage-go"
 	"github.com/gorilla/mux"
 )
 
+// extensionesImagenMedalla son los formatos aceptados para el arte de las medallas
+var extensionesImagenMedalla = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/webp": "webp",
+}
+
 type MedallasHandler struct {
-	repo *postgres.MedallasRepository
+	repo        *postgres.MedallasRepository
+	bunnyClient *bunnystorage.Client
+	storageZone string
 }
 
-func NewMedallasHandler(repo *postgres.MedallasRepository) *MedallasHandler {
-	return &MedallasHandler{repo: repo}
+func NewMedallasHandler(repo *postgres.MedallasRepository, bunnyClient *bunnystorage.Client, storageZone string) *MedallasHandler {
+	return &MedallasHandler{repo: repo, bunnyClient: bunnyClient, storageZone: storageZone}
 }
 
 func (h *MedallasHandler) CreateMedalla(w http.ResponseWriter, r *http.Request) {
@@ -25,6 +44,24 @@ func (h *MedallasHandler) CreateMedalla(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	criterios, message, detail := prepararCriteriosMedalla(medalla.Regla, medalla.Criterios)
+	if message != "" {
+		utils.RespondWithValidationError(w, message, detail)
+		return
+	}
+	medalla.Criterios = criterios
+	if medalla.Regla != nil && strings.TrimSpace(*medalla.Regla) == "" {
+		medalla.Regla = nil
+	}
+
+	if medalla.PoliticaRevocacion == "" {
+		medalla.PoliticaRevocacion = models.PoliticaRevocable
+	}
+	if message, detail := validarMedalla(&medalla); message != "" {
+		utils.RespondWithValidationError(w, message, detail)
+		return
+	}
+
 	if err := h.repo.CreateMedalla(&medalla); err != nil {
 		utils.RespondWithDatabaseError(w, "Error al crear la medalla", err.Error())
 		return
@@ -33,8 +70,331 @@ func (h *MedallasHandler) CreateMedalla(w http.ResponseWriter, r *http.Request)
 	utils.RespondWithCreated(w, medalla, "Medalla creada correctamente")
 }
 
+// validarMedalla valida los datos de catálogo de una medalla. Devuelve un mensaje vacío
+// si son válidos
+func validarMedalla(medalla *models.Medalla) (string, string) {
+	if strings.TrimSpace(medalla.Nombre) == "" {
+		return "Nombre de medalla requerido", "nombre no puede estar vacío"
+	}
+	if medalla.PoliticaRevocacion != models.PoliticaRevocable && medalla.PoliticaRevocacion != models.PoliticaPermanente {
+		return "Política de revocación no válida", "politica_revocacion debe ser 'revocable' o 'permanente'"
+	}
+	if medalla.Categoria != nil && len(*medalla.Categoria) > 50 {
+		return "Categoría no válida", "categoria no puede tener más de 50 caracteres"
+	}
+	if medalla.DisponibleDesde != nil && medalla.DisponibleHasta != nil && !medalla.DisponibleDesde.Before(*medalla.DisponibleHasta) {
+		return "Ventana de disponibilidad no válida", "disponible_desde debe ser anterior a disponible_hasta"
+	}
+	if medalla.Ciudad != nil && (strings.TrimSpace(*medalla.Ciudad) == "" || len(*medalla.Ciudad) > 100) {
+		return "Ciudad no válida", "ciudad debe tener entre 1 y 100 caracteres"
+	}
+
+	return "", ""
+}
+
+// UpdateMedalla edita una medalla del catálogo. Los campos que no se envían se
+// conservan; si se envía regla o criterios, o en una medalla sin regla cambian los
+// requiere_* o numero_requerido, se reemplazan los criterios. Si cambian los criterios
+// o la disponibilidad la medalla se vuelve a otorgar a quienes ya la merecen
+func (h *MedallasHandler) UpdateMedalla(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	actual, err := h.repo.GetMedalla(id)
+	if err != nil {
+		if errors.Is(err, postgres.ErrMedallaNoEncontrada) {
+			utils.RespondWithNotFound(w, "Medalla no encontrada", err.Error())
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al obtener la medalla", err.Error())
+		return
+	}
+
+	// Los campos del cuerpo se decodifican sobre la medalla actual
+	medalla := *actual
+	medalla.Regla, medalla.Criterios = nil, nil
+	if err := json.NewDecoder(r.Body).Decode(&medalla); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	cambioCriterios := medalla.Regla != nil || medalla.Criterios != nil
+	if cambioCriterios {
+		criterios, message, detail := prepararCriteriosMedalla(medalla.Regla, medalla.Criterios)
+		if message != "" {
+			utils.RespondWithValidationError(w, message, detail)
+			return
+		}
+		medalla.Criterios = criterios
+		if medalla.Regla != nil && strings.TrimSpace(*medalla.Regla) == "" {
+			medalla.Regla = nil
+		}
+	} else if actual.Regla == nil && !mismosIndicadores(&medalla, actual) {
+		// Sin regla, los criterios salen de los requiere_* y numero_requerido: se
+		// vuelven a armar con los nuevos valores
+		cambioCriterios = true
+	} else {
+		medalla.Regla, medalla.Criterios = actual.Regla, actual.Criterios
+	}
+
+	if message, detail := validarMedalla(&medalla); message != "" {
+		utils.RespondWithValidationError(w, message, detail)
+		return
+	}
+
+	// Los datos que no se editan en esta ruta se conservan
+	medalla.ID = id
+	medalla.Imagen = actual.Imagen
+	medalla.RetiradaAt = actual.RetiradaAt
+
+	// Con otros criterios u otra disponibilidad puede haber usuarios que ya la merecen
+	reotorgar := cambioCriterios ||
+		!mismaFecha(medalla.DisponibleDesde, actual.DisponibleDesde) ||
+		!mismaFecha(medalla.DisponibleHasta, actual.DisponibleHasta) ||
+		!mismoTexto(medalla.IDTorneo, actual.IDTorneo) ||
+		!mismoTexto(medalla.Ciudad, actual.Ciudad)
+
+	if err := h.repo.UpdateMedalla(&medalla, cambioCriterios, reotorgar); err != nil {
+		if errors.Is(err, postgres.ErrMedallaRetirada) {
+			utils.RespondWithConflict(w, err.Error(), "una medalla retirada no se puede editar")
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al actualizar la medalla", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, medalla, "Medalla actualizada correctamente")
+}
+
+// mismosIndicadores indica si dos medallas piden las mismas métricas y el mismo número
+func mismosIndicadores(a, b *models.Medalla) bool {
+	mismoNumero := a.NumeroRequerido == nil && b.NumeroRequerido == nil ||
+		a.NumeroRequerido != nil && b.NumeroRequerido != nil && *a.NumeroRequerido == *b.NumeroRequerido
+
+	return mismoNumero &&
+		a.RequiereAmistades == b.RequiereAmistades &&
+		a.RequierePuntos == b.RequierePuntos &&
+		a.RequiereAcciones == b.RequiereAcciones &&
+		a.RequiereTorneos == b.RequiereTorneos &&
+		a.RequiereVictoriaTorneos == b.RequiereVictoriaTorneos
+}
+
+// mismaFecha compara dos fechas opcionales
+func mismaFecha(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// mismoTexto compara dos textos opcionales
+func mismoTexto(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// RetirarMedalla saca la medalla del catálogo sin quitársela a quienes ya la tienen
+func (h *MedallasHandler) RetirarMedalla(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.repo.RetirarMedalla(id); err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrMedallaNoEncontrada):
+			utils.RespondWithNotFound(w, "Medalla no encontrada", err.Error())
+		case errors.Is(err, postgres.ErrMedallaRetirada):
+			utils.RespondWithConflict(w, err.Error(), "la medalla ya estaba retirada")
+		default:
+			utils.RespondWithDatabaseError(w, "Error al retirar la medalla", err.Error())
+		}
+		return
+	}
+
+	utils.RespondWithSuccess(w, nil, "Medalla retirada correctamente")
+}
+
+// SubirImagenMedalla sube el arte de la medalla al almacenamiento y reemplaza el anterior
+func (h *MedallasHandler) SubirImagenMedalla(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := r.ParseMultipartForm(5 << 20); err != nil {
+		utils.RespondWithBadRequest(w, "Error al procesar el formulario", err.Error())
+		return
+	}
+
+	file, _, err := r.FormFile("imagen")
+	if err != nil {
+		utils.RespondWithBadRequest(w, "La imagen es obligatoria", err.Error())
+		return
+	}
+	defer file.Close()
+
+	contenido, err := io.ReadAll(file)
+	if err != nil {
+		utils.RespondWithBadRequest(w, "Error al leer la imagen", err.Error())
+		return
+	}
+
+	extension, ok := extensionesImagenMedalla[http.DetectContentType(contenido)]
+	if !ok {
+		utils.RespondWithValidationError(w, "Formato de imagen no válido", "la imagen debe ser PNG, JPEG o WebP")
+		return
+	}
+
+	fileName := fmt.Sprintf("%s-%d.%s", id, time.Now().Unix(), extension)
+	if _, err := h.bunnyClient.Upload(context.Background(), "/medallas", fileName, "", bytes.NewReader(contenido)); err != nil {
+		utils.RespondWithInternalServerError(w, "Error al subir la imagen", err.Error())
+		return
+	}
+	imagen := fmt.Sprintf("https://%s/medallas/%s", h.storageZone, fileName)
+
+	anterior, err := h.repo.CambiarImagenMedalla(id, imagen)
+	if err != nil {
+		h.borrarImagenMedalla(imagen)
+		if errors.Is(err, postgres.ErrMedallaNoEncontrada) {
+			utils.RespondWithNotFound(w, "Medalla no encontrada", err.Error())
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al guardar la imagen de la medalla", err.Error())
+		return
+	}
+
+	if anterior != nil {
+		h.borrarImagenMedalla(*anterior)
+	}
+
+	utils.RespondWithSuccess(w, map[string]string{"imagen": imagen}, "Imagen de la medalla actualizada correctamente")
+}
+
+// borrarImagenMedalla elimina del almacenamiento una imagen de medalla a partir de su
+// URL. Solo registra los errores, porque la medalla ya no la usa
+func (h *MedallasHandler) borrarImagenMedalla(imagen string) {
+	fileName := imagen[strings.LastIndex(imagen, "/")+1:]
+	if _, err := h.bunnyClient.Delete(context.Background(), "/medallas", fileName); err != nil {
+		log.Printf("Error al eliminar la imagen de medalla %s: %v", fileName, err)
+	}
+}
+
+// prepararCriteriosMedalla valida la regla o los criterios enviados y devuelve los
+// criterios con las métricas en su forma canónica. Devuelve un mensaje vacío si son válidos
+func prepararCriteriosMedalla(regla *string, criterios []models.CriterioMedalla) ([]models.CriterioMedalla, string, string) {
+	if regla != nil && strings.TrimSpace(*regla) != "" {
+		if len(criterios) > 0 {
+			return nil, "Regla de medalla no válida", "envía regla o criterios, no ambos"
+		}
+
+		criterios, err := utils.ParsearRegla(*regla)
+		if err != nil {
+			return nil, "Regla de medalla no válida", err.Error()
+		}
+		return criterios, "", ""
+	}
+
+	for i, c := range criterios {
+		metrica, err := utils.ParsearMetrica(c.Metrica)
+		if err != nil {
+			return nil, "Métrica de medalla no válida", err.Error()
+		}
+		if !slices.Contains(models.OperadoresCriterio, c.Operador) {
+			return nil, "Operador de medalla no válido", fmt.Sprintf("operador debe ser uno de: %s", strings.Join(models.OperadoresCriterio, ", "))
+		}
+		if c.Grupo < 0 {
+			return nil, "Grupo de criterios no válido", "grupo no puede ser negativo"
+		}
+		criterios[i].Metrica = metrica.String()
+	}
+
+	return criterios, "", ""
+}
+
+// SimularMedalla muestra qué usuarios ganarían hoy una medalla con la regla o los
+// criterios enviados, sin crearla ni otorgarla
+func (h *MedallasHandler) SimularMedalla(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Regla     *string                  `json:"regla"`
+		Criterios []models.CriterioMedalla `json:"criterios"`
+		Limite    int                      `json:"limite"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	criterios, message, detail := prepararCriteriosMedalla(req.Regla, req.Criterios)
+	if message != "" {
+		utils.RespondWithValidationError(w, message, detail)
+		return
+	}
+	if len(criterios) == 0 {
+		utils.RespondWithValidationError(w, "Regla de medalla requerida", "envía regla o criterios")
+		return
+	}
+
+	if req.Limite <= 0 {
+		req.Limite = 50
+	}
+	if req.Limite > 500 {
+		utils.RespondWithValidationError(w, "Límite no válido", "limite no puede ser mayor a 500")
+		return
+	}
+
+	simulacion, err := h.repo.SimularMedalla(criterios, req.Limite)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al simular la medalla", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, simulacion, "Simulación de medalla realizada correctamente")
+}
+
+// GetBackfillMedalla devuelve el avance del último otorgamiento retroactivo de la medalla
+func (h *MedallasHandler) GetBackfillMedalla(w http.ResponseWriter, r *http.Request) {
+	medallaID := mux.Vars(r)["id"]
+
+	backfill, err := h.repo.GetBackfill(medallaID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrBackfillNoEncontrado) {
+			utils.RespondWithNotFound(w, "Otorgamiento no encontrado", err.Error())
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al obtener el otorgamiento retroactivo", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, backfill, "Otorgamiento retroactivo obtenido correctamente")
+}
+
+// IniciarBackfillMedalla vuelve a evaluar la medalla para todos los usuarios existentes
+func (h *MedallasHandler) IniciarBackfillMedalla(w http.ResponseWriter, r *http.Request) {
+	medallaID := mux.Vars(r)["id"]
+
+	backfill, err := h.repo.IniciarBackfill(medallaID)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrMedallaNoEncontrada):
+			utils.RespondWithNotFound(w, "Medalla no encontrada", err.Error())
+		case errors.Is(err, postgres.ErrMedallaRetirada):
+			utils.RespondWithConflict(w, err.Error(), "una medalla retirada no se puede otorgar")
+		default:
+			utils.RespondWithDatabaseError(w, "Error al iniciar el otorgamiento retroactivo", err.Error())
+		}
+		return
+	}
+
+	utils.RespondWithCreated(w, backfill, "Otorgamiento retroactivo encolado correctamente")
+}
+
+// ProcesarBackfills avanza los otorgamientos retroactivos pendientes; lo llama el cron
+func (h *MedallasHandler) ProcesarBackfills() error {
+	return h.repo.ProcesarBackfills(20)
+}
+
+// GetMedallas devuelve el catálogo con la rareza de cada medalla. Las retiradas solo
+// se incluyen con ?incluir_retiradas=true
 func (h *MedallasHandler) GetMedallas(w http.ResponseWriter, r *http.Request) {
-	medallas, err := h.repo.GetMedallas()
+	incluirRetiradas := r.URL.Query().Get("incluir_retiradas") == "true"
+
+	medallas, err := h.repo.GetMedallas(incluirRetiradas)
 	if err != nil {
 		utils.RespondWithDatabaseError(w, "Error al obtener las medallas", err.Error())
 		return
@@ -69,6 +429,32 @@ func (h *MedallasHandler) GetMedallasUsuario(w http.ResponseWriter, r *http.Requ
 	utils.RespondWithSuccess(w, medallas, "Medallas del usuario obtenidas correctamente")
 }
 
+// GetProgresoMedallas devuelve todas las medallas con el avance del usuario hacia cada una
+func (h *MedallasHandler) GetProgresoMedallas(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+
+	progresos, err := h.repo.GetProgresoMedallas(userID)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener el progreso de las medallas", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, progresos, "Progreso de medallas obtenido correctamente")
+}
+
+// GetMedallasRevocadas devuelve el historial de medallas que perdió el usuario
+func (h *MedallasHandler) GetMedallasRevocadas(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+
+	revocadas, err := h.repo.GetMedallasRevocadas(userID)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener las medallas revocadas", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, revocadas, "Medallas revocadas obtenidas correctamente")
+}
+
 func (h *MedallasHandler) VerifyAndUpdateMedallas(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userID := vars["user_id"]
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/internal/repository/postgres"
+	"backend_proyecto_verde/internal/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/robfig/cron/v3"
+)
+
+type TemporadasHandler struct {
+	repo *postgres.TemporadasRepository
+}
+
+func NewTemporadasHandler(repo *postgres.TemporadasRepository) *TemporadasHandler {
+	return &TemporadasHandler{repo: repo}
+}
+
+// CreateTemporada crea una temporada para agrupar torneos
+func (h *TemporadasHandler) CreateTemporada(w http.ResponseWriter, r *http.Request) {
+	var temporada models.Temporada
+	if err := json.NewDecoder(r.Body).Decode(&temporada); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	if temporada.IDCreator == "" || strings.TrimSpace(temporada.Nombre) == "" {
+		utils.RespondWithValidationError(w, "El organizador y el nombre son requeridos", "id_creator y nombre son requeridos")
+		return
+	}
+
+	if !temporada.FechaFin.After(temporada.FechaInicio) {
+		utils.RespondWithValidationError(w, "La temporada debe terminar después de comenzar", "fecha_fin debe ser posterior a fecha_inicio")
+		return
+	}
+
+	switch temporada.Puntuacion {
+	case "", models.PuntuacionTemporadaPuntos, models.PuntuacionTemporadaPosicion:
+	default:
+		utils.RespondWithValidationError(w, "Forma de puntuación no válida", "puntuacion debe ser puntos o posicion")
+		return
+	}
+
+	for _, puntos := range temporada.PuntosPosicion {
+		if puntos < 0 {
+			utils.RespondWithValidationError(w, "Los puntos por posición no pueden ser negativos", "puntos_posicion contiene valores negativos")
+			return
+		}
+	}
+
+	if err := h.repo.CreateTemporada(&temporada); err != nil {
+		utils.RespondWithDatabaseError(w, "Error al crear la temporada", err.Error())
+		return
+	}
+
+	utils.RespondWithCreated(w, temporada, "Temporada creada correctamente")
+}
+
+func (h *TemporadasHandler) GetTemporada(w http.ResponseWriter, r *http.Request) {
+	temporada, err := h.repo.GetTemporada(mux.Vars(r)["id"])
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondWithNotFound(w, "Temporada no encontrada", "No se encontró la temporada con el ID proporcionado")
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al obtener la temporada", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, temporada, "Temporada obtenida correctamente")
+}
+
+// GetTorneosTemporada lista los torneos que forman parte de una temporada
+func (h *TemporadasHandler) GetTorneosTemporada(w http.ResponseWriter, r *http.Request) {
+	torneos, err := h.repo.GetTorneosTemporada(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener los torneos de la temporada", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, torneos, "Torneos de la temporada obtenidos correctamente")
+}
+
+// GetRankingTemporada devuelve la clasificación acumulada de una temporada.
+// Parámetros: limit (por defecto 10, máximo 100) y offset
+func (h *TemporadasHandler) GetRankingTemporada(w http.ResponseWriter, r *http.Request) {
+	temporadaID := mux.Vars(r)["id"]
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	if _, err := h.repo.GetTemporada(temporadaID); err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondWithNotFound(w, "Temporada no encontrada", "No se encontró la temporada con el ID proporcionado")
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al obtener la temporada", err.Error())
+		return
+	}
+
+	ranking, err := h.repo.GetRankingTemporada(temporadaID, limit, offset)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener la clasificación de la temporada", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, ranking, "Clasificación de la temporada obtenida correctamente")
+}
+
+// CreatePlantilla crea una plantilla que genera un torneo cada vez que se cumple su
+// recurrencia (formato cron de cinco campos, en la hora del servidor)
+func (h *TemporadasHandler) CreatePlantilla(w http.ResponseWriter, r *http.Request) {
+	// Igual que al crear un torneo, los cambios de equipo requieren aprobación por defecto
+	plantilla := models.PlantillaTorneo{Torneo: models.Torneo{CambioRequiereAprobacion: true}}
+	if err := json.NewDecoder(r.Body).Decode(&plantilla); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	if plantilla.IDCreator == "" || strings.TrimSpace(plantilla.Nombre) == "" {
+		utils.RespondWithValidationError(w, "El organizador y el nombre son requeridos", "id_creator y nombre son requeridos")
+		return
+	}
+
+	if plantilla.DuracionMinutos <= 0 {
+		utils.RespondWithValidationError(w, "La duración debe ser mayor que cero", "duracion_minutos debe ser positivo")
+		return
+	}
+
+	recurrencia, err := cron.ParseStandard(plantilla.Recurrencia)
+	if err != nil {
+		utils.RespondWithValidationError(w, "Recurrencia no válida", err.Error())
+		return
+	}
+
+	// Cada torneo debe terminar antes de que la plantilla cree el siguiente: el
+	// organizador solo puede tener un torneo activo
+	intervalo := intervaloMinimo(recurrencia, time.Now())
+	if time.Duration(plantilla.DuracionMinutos)*time.Minute > intervalo {
+		utils.RespondWithValidationError(w, "La duración no puede ser mayor que el intervalo entre torneos",
+			fmt.Sprintf("duracion_minutos (%d) supera el intervalo mínimo de la recurrencia (%.0f minutos)",
+				plantilla.DuracionMinutos, intervalo.Minutes()))
+		return
+	}
+
+	if plantilla.Torneo.Modalidad != "Individual" && plantilla.Torneo.Modalidad != "Versus" {
+		utils.RespondWithValidationError(w, "Modalidad del torneo no válida", "torneo.modalidad debe ser Individual o Versus")
+		return
+	}
+
+	// Las fechas de cada torneo se calculan en cada ejecución
+	plantilla.Torneo.FechaCierreInscripcion = nil
+	if message, detail := validarNuevoTorneo(&plantilla.Torneo); message != "" {
+		utils.RespondWithValidationError(w, message, detail)
+		return
+	}
+
+	plantilla.ProximaEjecucion = recurrencia.Next(time.Now())
+
+	if err := h.repo.CreatePlantilla(&plantilla); err != nil {
+		if errors.Is(err, postgres.ErrTemporadaNoEncontrada) {
+			utils.RespondWithValidationError(w, err.Error(), err.Error())
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al crear la plantilla", err.Error())
+		return
+	}
+
+	utils.RespondWithCreated(w, plantilla, "Plantilla creada correctamente")
+}
+
+// intervaloMinimo devuelve el menor tiempo entre dos ejecuciones consecutivas de la
+// recurrencia, revisando las próximas ejecuciones a partir de desde
+func intervaloMinimo(recurrencia cron.Schedule, desde time.Time) time.Duration {
+	const ejecucionesRevisadas = 100
+
+	anterior := recurrencia.Next(desde)
+	minimo := time.Duration(math.MaxInt64)
+	for i := 0; i < ejecucionesRevisadas; i++ {
+		siguiente := recurrencia.Next(anterior)
+		if siguiente.IsZero() {
+			break
+		}
+		if intervalo := siguiente.Sub(anterior); intervalo < minimo {
+			minimo = intervalo
+		}
+		anterior = siguiente
+	}
+
+	return minimo
+}
+
+// GetPlantillasUsuario lista las plantillas de un organizador
+func (h *TemporadasHandler) GetPlantillasUsuario(w http.ResponseWriter, r *http.Request) {
+	plantillas, err := h.repo.GetPlantillasUsuario(mux.Vars(r)["user_id"])
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener las plantillas", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, plantillas, "Plantillas obtenidas correctamente")
+}
+
+// SetPlantillaActiva pausa o reanuda una plantilla
+func (h *TemporadasHandler) SetPlantillaActiva(w http.ResponseWriter, r *http.Request) {
+	plantillaID := mux.Vars(r)["id"]
+
+	var request struct {
+		IDCreator string `json:"id_creator"`
+		Activa    bool   `json:"activa"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	plantilla, err := h.repo.GetPlantilla(plantillaID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondWithNotFound(w, "Plantilla no encontrada", "No se encontró la plantilla con el ID proporcionado")
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al obtener la plantilla", err.Error())
+		return
+	}
+
+	recurrencia, err := cron.ParseStandard(plantilla.Recurrencia)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "La recurrencia guardada no es válida", err.Error())
+		return
+	}
+
+	if err := h.repo.SetPlantillaActiva(plantillaID, request.IDCreator, request.Activa, recurrencia.Next(time.Now())); err != nil {
+		if errors.Is(err, postgres.ErrPlantillaNoEncontrada) {
+			utils.RespondWithForbidden(w, err.Error(), err.Error())
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al actualizar la plantilla", err.Error())
+		return
+	}
+
+	plantilla, err = h.repo.GetPlantilla(plantillaID)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener la plantilla", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, plantilla, "Plantilla actualizada correctamente")
+}
+
+// DeletePlantilla elimina una plantilla sin afectar a los torneos que ya creó
+func (h *TemporadasHandler) DeletePlantilla(w http.ResponseWriter, r *http.Request) {
+	plantillaID := mux.Vars(r)["id"]
+	idCreator := r.URL.Query().Get("id_creator")
+
+	if err := h.repo.DeletePlantilla(plantillaID, idCreator); err != nil {
+		if errors.Is(err, postgres.ErrPlantillaNoEncontrada) {
+			utils.RespondWithNotFound(w, err.Error(), err.Error())
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al eliminar la plantilla", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, nil, "Plantilla eliminada correctamente")
+}
+
+// GenerarTorneosProgramados crea los torneos de las plantillas cuya ejecución ya llegó.
+// Las plantillas cuyo organizador aún tiene un torneo activo se reintentan en la siguiente pasada
+func (h *TemporadasHandler) GenerarTorneosProgramados() error {
+	plantillas, err := h.repo.GetPlantillasPendientes()
+	if err != nil {
+		return fmt.Errorf("error al buscar plantillas pendientes: %w", err)
+	}
+
+	var finalErrors []string
+	for i := range plantillas {
+		plantilla := &plantillas[i]
+
+		recurrencia, err := cron.ParseStandard(plantilla.Recurrencia)
+		if err != nil {
+			finalErrors = append(finalErrors, fmt.Sprintf("Recurrencia no válida en la plantilla %s: %v", plantilla.ID, err))
+			continue
+		}
+
+		torneo, err := h.repo.CrearTorneoDesdePlantilla(plantilla, recurrencia.Next(time.Now()))
+		if err != nil {
+			if errors.Is(err, postgres.ErrCreadorConTorneoActivo) {
+				log.Printf("Plantilla %s pospuesta: %v", plantilla.ID, err)
+				continue
+			}
+			finalErrors = append(finalErrors, fmt.Sprintf("Error al crear torneo de la plantilla %s: %v", plantilla.ID, err))
+			continue
+		}
+
+		if torneo != nil {
+			log.Printf("Torneo %s creado desde la plantilla %s", torneo.ID, plantilla.ID)
+		}
+	}
+
+	if len(finalErrors) > 0 {
+		return fmt.Errorf("errores al generar torneos programados: %s", strings.Join(finalErrors, "; "))
+	}
+
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"backend_proyecto_verde/internal/utils"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -15,20 +16,27 @@ import (
 )
 
 type TorneoHandler struct {
-	repo *postgres.TorneoRepository
+	repo     *postgres.TorneoRepository
+	marcador *MarcadorHandler
 }
 
-func NewTorneoHandler(repo *postgres.TorneoRepository) *TorneoHandler {
-	return &TorneoHandler{repo: repo}
+func NewTorneoHandler(repo *postgres.TorneoRepository, marcador *MarcadorHandler) *TorneoHandler {
+	return &TorneoHandler{repo: repo, marcador: marcador}
 }
 
 func (h *TorneoHandler) CreateTorneo(w http.ResponseWriter, r *http.Request) {
-	var torneo models.Torneo
+	// Los cambios de equipo requieren aprobación salvo que el cliente indique lo contrario
+	torneo := models.Torneo{CambioRequiereAprobacion: true}
 	if err := json.NewDecoder(r.Body).Decode(&torneo); err != nil {
 		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
 		return
 	}
 
+	if message, detail := validarNuevoTorneo(&torneo); message != "" {
+		utils.RespondWithValidationError(w, message, detail)
+		return
+	}
+
 	// Verificar si el usuario puede crear un torneo
 	_, message, err := h.repo.GetIfTournamentOwner(torneo.IDCreator)
 	if err != nil {
@@ -37,6 +45,10 @@ func (h *TorneoHandler) CreateTorneo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.repo.CreateTorneo(&torneo); err != nil {
+		if errors.Is(err, postgres.ErrTemporadaNoEncontrada) || errors.Is(err, postgres.ErrFueraDeTemporada) {
+			utils.RespondWithValidationError(w, err.Error(), err.Error())
+			return
+		}
 		utils.RespondWithDatabaseError(w, "Error al crear el torneo", err.Error())
 		return
 	}
@@ -44,6 +56,117 @@ func (h *TorneoHandler) CreateTorneo(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithCreated(w, torneo, "Torneo creado correctamente")
 }
 
+// validarNuevoTorneo valida la configuración de un torneo antes de crearlo (también la
+// que se guarda en una plantilla). Devuelve un mensaje vacío si es válida
+func validarNuevoTorneo(torneo *models.Torneo) (string, string) {
+	// Validar los equipos en modalidad Versus (si no se envían se crean dos por defecto)
+	if torneo.Modalidad == "Versus" && len(torneo.Equipos) > 0 {
+		if len(torneo.Equipos) < 2 {
+			return "Un torneo Versus necesita al menos dos equipos", "equipos debe contener al menos dos elementos"
+		}
+		for _, equipo := range torneo.Equipos {
+			if strings.TrimSpace(equipo.Nombre) == "" {
+				return "Todos los equipos deben tener nombre", "nombre es requerido en cada equipo"
+			}
+		}
+	} else if torneo.Modalidad != "Versus" {
+		torneo.Equipos = nil
+	}
+
+	switch torneo.AsignacionEquipos {
+	case "", models.AsignacionEquiposLibre, models.AsignacionEquiposCantidad, models.AsignacionEquiposPuntos, models.AsignacionEquiposRating:
+	default:
+		return "Modo de asignación de equipos no válido", "asignacion_equipos debe ser libre, cantidad, puntos o rating"
+	}
+
+	if message, detail := validarCapacidadTorneo(torneo); message != "" {
+		return message, detail
+	}
+
+	if message, detail := validarGeocercaTorneo(torneo); message != "" {
+		return message, detail
+	}
+
+	switch torneo.Visibilidad {
+	case "", models.VisibilidadPublica, models.VisibilidadNoListada, models.VisibilidadPrivada:
+	default:
+		return "Visibilidad del torneo no válida", "visibilidad debe ser publica, no_listada o privada"
+	}
+
+	return validarFormatoTorneo(torneo)
+}
+
+// validarFormatoTorneo valida el formato de competición y el periodo de verificación.
+// Devuelve un mensaje vacío si son válidos
+func validarFormatoTorneo(torneo *models.Torneo) (string, string) {
+	switch torneo.Formato {
+	case "", models.FormatoClasico:
+		torneo.DuracionRondaMinutos = nil
+	case models.FormatoEliminatoria:
+		if torneo.DuracionRondaMinutos == nil || *torneo.DuracionRondaMinutos <= 0 {
+			return "Un torneo eliminatoria necesita la duración de cada ronda", "duracion_ronda_minutos debe ser positivo"
+		}
+	default:
+		return "Formato del torneo no válido", "formato debe ser clasico o eliminatoria"
+	}
+
+	if torneo.MinutosVerificacion != nil && *torneo.MinutosVerificacion <= 0 {
+		return "El periodo de verificación debe ser mayor que cero", "minutos_verificacion debe ser positivo"
+	}
+
+	return "", ""
+}
+
+// validarCapacidadTorneo valida los límites de participantes y el cierre de inscripciones.
+// Devuelve un mensaje vacío si son válidos
+func validarCapacidadTorneo(torneo *models.Torneo) (string, string) {
+	if torneo.MaxParticipantes != nil && *torneo.MaxParticipantes <= 0 {
+		return "El máximo de participantes debe ser mayor que cero", "max_participantes debe ser positivo"
+	}
+
+	if torneo.MaxPorEquipo != nil {
+		if torneo.Modalidad != "Versus" {
+			return "El máximo por equipo solo aplica a torneos Versus", "max_por_equipo requiere modalidad Versus"
+		}
+		if *torneo.MaxPorEquipo <= 0 {
+			return "El máximo por equipo debe ser mayor que cero", "max_por_equipo debe ser positivo"
+		}
+	}
+
+	if torneo.FechaCierreInscripcion != nil && torneo.FechaCierreInscripcion.After(torneo.FechaFin) {
+		return "El cierre de inscripciones debe ser anterior al fin del torneo", "fecha_cierre_inscripcion es posterior a fecha_fin"
+	}
+
+	return "", ""
+}
+
+// validarGeocercaTorneo valida el área del torneo. Devuelve un mensaje vacío si es válida
+func validarGeocercaTorneo(torneo *models.Torneo) (string, string) {
+	switch torneo.GeocercaTipo {
+	case "", models.GeocercaNinguna:
+	case models.GeocercaRadio:
+		if torneo.GeocercaRadioMetros == nil && torneo.MetrosAprox == nil {
+			return "Debes indicar el radio del área del torneo", "geocerca_radio_metros es requerido"
+		}
+		if torneo.GeocercaRadioMetros != nil && *torneo.GeocercaRadioMetros <= 0 {
+			return "El radio del área del torneo debe ser mayor que cero", "geocerca_radio_metros debe ser positivo"
+		}
+	case models.GeocercaPoligono:
+		if len(torneo.GeocercaPoligono) < 3 {
+			return "El área del torneo necesita al menos tres puntos", "geocerca_poligono debe contener al menos tres puntos"
+		}
+		for _, punto := range torneo.GeocercaPoligono {
+			if punto.Latitud < -90 || punto.Latitud > 90 || punto.Longitud < -180 || punto.Longitud > 180 {
+				return "El área del torneo contiene coordenadas inválidas", "latitud o longitud fuera de rango en geocerca_poligono"
+			}
+		}
+	default:
+		return "Tipo de área del torneo no válido", "geocerca_tipo debe ser ninguna, radio o poligono"
+	}
+
+	return "", ""
+}
+
 func (h *TorneoHandler) GetTorneo(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -70,11 +193,35 @@ func (h *TorneoHandler) GetTorneoByCodeID(w http.ResponseWriter, r *http.Request
 	utils.RespondWithSuccess(w, torneo, "Torneo obtenido correctamente")
 }
 
+// GetTorneoAdmin obtiene un torneo con los datos que solo ven sus organizadores.
+// Parámetro: organizador_id
 func (h *TorneoHandler) GetTorneoAdmin(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	torneoID := mux.Vars(r)["id"]
+
+	if !h.verificarOrganizador(w, torneoID, r.URL.Query().Get("organizador_id")) {
+		return
+	}
 
-	torneo, err := h.repo.GetTorneoByAdminID(id)
+	h.responderTorneoAdmin(w, torneoID)
+}
+
+// GetTorneoAdminCreador es la ruta anterior de administración, que recibe el ID del
+// dueño y resuelve su torneo activo. Parámetro: organizador_id
+func (h *TorneoHandler) GetTorneoAdminCreador(w http.ResponseWriter, r *http.Request) {
+	torneoID, ok := h.torneoActivoCreador(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	if !h.verificarOrganizador(w, torneoID, r.URL.Query().Get("organizador_id")) {
+		return
+	}
+
+	h.responderTorneoAdmin(w, torneoID)
+}
+
+func (h *TorneoHandler) responderTorneoAdmin(w http.ResponseWriter, torneoID string) {
+	torneo, err := h.repo.GetTorneoAdmin(torneoID)
 	if err != nil {
 		utils.RespondWithNotFound(w, "Torneo no encontrado", "No se encontró el torneo con el ID proporcionado")
 		return
@@ -83,19 +230,99 @@ func (h *TorneoHandler) GetTorneoAdmin(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithSuccess(w, torneo, "Torneo obtenido correctamente")
 }
 
+// torneoActivoCreador resuelve el torneo activo de un dueño para las rutas anteriores
+// de administración. Responde con un error y devuelve false si no tiene uno
+func (h *TorneoHandler) torneoActivoCreador(w http.ResponseWriter, idCreator string) (string, bool) {
+	torneoID, err := h.repo.GetTorneoActivoCreador(idCreator)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondWithNotFound(w, "Torneo no encontrado", "No se encontró un torneo activo para este usuario")
+			return "", false
+		}
+		utils.RespondWithDatabaseError(w, "Error al buscar el torneo", err.Error())
+		return "", false
+	}
+
+	return torneoID, true
+}
+
+// UpdateTorneo actualiza un torneo. Es una actualización parcial: los campos que no se
+// envían conservan su valor. La modalidad y el formato solo se pueden cambiar mientras
+// el torneo no tenga participantes. Requiere organizador_id en el cuerpo
 func (h *TorneoHandler) UpdateTorneo(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	var torneo models.Torneo
-	if err := json.NewDecoder(r.Body).Decode(&torneo); err != nil {
+	actual, err := h.repo.GetTorneoAdmin(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondWithNotFound(w, "Torneo no encontrado", "No se encontró el torneo con el ID proporcionado")
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al obtener el torneo", err.Error())
+		return
+	}
+
+	// Los campos del cuerpo se decodifican sobre el torneo actual
+	body := struct {
+		models.Torneo
+		OrganizadorID string `json:"organizador_id"`
+	}{Torneo: *actual}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
 		return
 	}
 
+	if !h.verificarOrganizador(w, id, body.OrganizadorID) {
+		return
+	}
+
+	torneo := body.Torneo
+	if torneo.Modalidad != "Individual" && torneo.Modalidad != "Versus" {
+		utils.RespondWithValidationError(w, "Modalidad del torneo no válida", "modalidad debe ser Individual o Versus")
+		return
+	}
+
+	if message, detail := validarCapacidadTorneo(&torneo); message != "" {
+		utils.RespondWithValidationError(w, message, detail)
+		return
+	}
+
+	if message, detail := validarGeocercaTorneo(&torneo); message != "" {
+		utils.RespondWithValidationError(w, message, detail)
+		return
+	}
+
+	if message, detail := validarFormatoTorneo(&torneo); message != "" {
+		utils.RespondWithValidationError(w, message, detail)
+		return
+	}
+
+	switch torneo.Visibilidad {
+	case "", models.VisibilidadPublica, models.VisibilidadNoListada, models.VisibilidadPrivada:
+	default:
+		utils.RespondWithValidationError(w, "Visibilidad del torneo no válida", "visibilidad debe ser publica, no_listada o privada")
+		return
+	}
+
+	// Los datos que no se editan en esta ruta se conservan
 	torneo.ID = id
+	torneo.IDCreator = actual.IDCreator
+	torneo.CodeID = actual.CodeID
+	torneo.Finalizado = actual.Finalizado
+	torneo.Equipos = nil
+
 	if err := h.repo.UpdateTorneo(&torneo); err != nil {
-		utils.RespondWithDatabaseError(w, "Error al actualizar el torneo", err.Error())
+		switch {
+		case errors.Is(err, postgres.ErrTorneoConParticipantes):
+			utils.RespondWithConflict(w, err.Error(), err.Error())
+		case errors.Is(err, postgres.ErrTorneoFinalizado):
+			utils.RespondWithConflict(w, err.Error(), err.Error())
+		case errors.Is(err, sql.ErrNoRows):
+			utils.RespondWithNotFound(w, "Torneo no encontrado", "No se encontró el torneo con el ID proporcionado")
+		default:
+			utils.RespondWithDatabaseError(w, "Error al actualizar el torneo", err.Error())
+		}
 		return
 	}
 
@@ -119,29 +346,143 @@ func (h *TorneoHandler) ListTorneos(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithSuccess(w, torneos, "Lista de torneos obtenida correctamente")
 }
 
+// ListTorneosCercanos lista los torneos públicos próximos o en curso cerca de una coordenada.
+// Parámetros: lat, lng, radio_km (por defecto 10, máximo 100), limit y offset
+func (h *TorneoHandler) ListTorneosCercanos(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	latitud, err := strconv.ParseFloat(query.Get("lat"), 64)
+	if err != nil || latitud < -90 || latitud > 90 {
+		utils.RespondWithBadRequest(w, "Latitud inválida", "lat debe ser un número entre -90 y 90")
+		return
+	}
+
+	longitud, err := strconv.ParseFloat(query.Get("lng"), 64)
+	if err != nil || longitud < -180 || longitud > 180 {
+		utils.RespondWithBadRequest(w, "Longitud inválida", "lng debe ser un número entre -180 y 180")
+		return
+	}
+
+	radioKm := 10.0
+	if valor := query.Get("radio_km"); valor != "" {
+		radioKm, err = strconv.ParseFloat(valor, 64)
+		if err != nil || radioKm <= 0 || radioKm > 100 {
+			utils.RespondWithBadRequest(w, "Radio inválido", "radio_km debe ser mayor que 0 y como máximo 100")
+			return
+		}
+	}
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	if limit <= 0 || limit > 50 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	torneos, err := h.repo.ListTorneosCercanos(latitud, longitud, radioKm, limit, offset)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al buscar torneos cercanos", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, torneos, "Torneos cercanos obtenidos correctamente")
+}
+
 func (h *TorneoHandler) GetTorneoStats(w http.ResponseWriter, r *http.Request) {
 	// Esta función aún no está implementada en el repositorio
 	// Devolvemos una respuesta vacía por ahora
 	utils.RespondWithSuccess(w, []models.TorneoEstadisticas{}, "No hay estadísticas disponibles")
 }
 
+// TerminarTorneo finaliza un torneo antes de su fecha de fin o, si está pendiente de
+// verificación, confirma sus resultados sin esperar a que cierre el periodo. Puede
+// hacerlo cualquier organizador
 func (h *TorneoHandler) TerminarTorneo(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	torneoID := mux.Vars(r)["id"]
+
+	var body struct {
+		OrganizadorID string `json:"organizador_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	if !h.verificarOrganizador(w, torneoID, body.OrganizadorID) {
+		return
+	}
 
-	if err := h.repo.TerminarTorneo(id); err != nil {
+	h.terminarTorneo(w, torneoID)
+}
+
+// TerminarTorneoCreador es la ruta anterior para finalizar el torneo activo de un dueño.
+// Parámetro: organizador_id
+func (h *TorneoHandler) TerminarTorneoCreador(w http.ResponseWriter, r *http.Request) {
+	torneoID, ok := h.torneoActivoCreador(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	if !h.verificarOrganizador(w, torneoID, r.URL.Query().Get("organizador_id")) {
+		return
+	}
+
+	h.terminarTorneo(w, torneoID)
+}
+
+func (h *TorneoHandler) terminarTorneo(w http.ResponseWriter, torneoID string) {
+	if err := h.repo.TerminarTorneo(torneoID); err != nil {
+		if errors.Is(err, postgres.ErrTorneoFinalizado) {
+			utils.RespondWithConflict(w, err.Error(), err.Error())
+			return
+		}
 		utils.RespondWithDatabaseError(w, "Error al finalizar el torneo", err.Error())
 		return
 	}
+	h.marcador.PublicarFin(torneoID, models.EventoFinalizado)
 
 	utils.RespondWithSuccess(w, nil, "Torneo finalizado correctamente")
 }
 
+// BorrarTorneo elimina un torneo sin participantes. Solo puede hacerlo su dueño
 func (h *TorneoHandler) BorrarTorneo(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	torneoID := mux.Vars(r)["id"]
+
+	var body struct {
+		DuenoID string `json:"dueno_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	if !h.verificarDueno(w, torneoID, body.DuenoID) {
+		return
+	}
+
+	h.borrarTorneo(w, torneoID)
+}
+
+// BorrarTorneoCreador es la ruta anterior para eliminar el torneo activo de un dueño.
+// Parámetro: dueno_id
+func (h *TorneoHandler) BorrarTorneoCreador(w http.ResponseWriter, r *http.Request) {
+	torneoID, ok := h.torneoActivoCreador(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	if !h.verificarDueno(w, torneoID, r.URL.Query().Get("dueno_id")) {
+		return
+	}
+
+	h.borrarTorneo(w, torneoID)
+}
 
-	message, err := h.repo.BorrarTorneoEstadisticas(id)
+func (h *TorneoHandler) borrarTorneo(w http.ResponseWriter, torneoID string) {
+	message, err := h.repo.BorrarTorneoEstadisticas(torneoID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			utils.RespondWithNotFound(w, "Torneo no encontrado", "No se encontró el torneo con el ID proporcionado")
@@ -155,7 +496,7 @@ func (h *TorneoHandler) BorrarTorneo(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetTorneosUsuario obtiene todos los torneos relacionados con un usuario específico,
-// tanto los que ha creado como en los que ha participado
+// tanto los que administra como en los que ha participado
 func (h *TorneoHandler) GetTorneosUsuario(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userID := vars["user_id"]
@@ -174,8 +515,9 @@ func (h *TorneoHandler) InscribirUsuario(w http.ResponseWriter, r *http.Request)
 	codeID := vars["code_id"]
 
 	var body struct {
-		UserID string `json:"user_id"`
-		Team   bool   `json:"team"`
+		UserID   string  `json:"user_id"`
+		EquipoID *string `json:"equipo_id"`
+		Team     *bool   `json:"team"` // Compatibilidad con clientes que envían el equipo como booleano
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
@@ -189,12 +531,37 @@ func (h *TorneoHandler) InscribirUsuario(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.repo.InscribirUsuario(codeID, body.UserID, body.Team); err != nil {
-		utils.RespondWithDatabaseError(w, "Error al inscribir el usuario", err.Error())
+	inscripcion, err := h.repo.InscribirUsuario(codeID, body.UserID, body.EquipoID, body.Team)
+	if err != nil {
+		respondWithErrorInscripcion(w, err, "Error al inscribir el usuario")
+		return
+	}
+
+	if inscripcion.Estado == models.InscripcionListaEspera {
+		utils.RespondWithCreated(w, inscripcion, "El torneo está lleno, quedaste en la lista de espera")
 		return
 	}
 
-	utils.RespondWithCreated(w, nil, "Usuario inscrito correctamente")
+	utils.RespondWithCreated(w, inscripcion, "Usuario inscrito correctamente")
+}
+
+// respondWithErrorInscripcion traduce los errores de las reglas de inscripción a respuestas HTTP
+func respondWithErrorInscripcion(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		utils.RespondWithNotFound(w, "Torneo no encontrado", "No se encontró el torneo indicado")
+	case errors.Is(err, postgres.ErrEquipoRequerido), errors.Is(err, postgres.ErrEquipoNoEncontrado):
+		utils.RespondWithValidationError(w, err.Error(), err.Error())
+	case errors.Is(err, postgres.ErrDuenoTorneoActivo), errors.Is(err, postgres.ErrEnTorneoActivo):
+		utils.RespondWithBadRequest(w, err.Error(), err.Error())
+	case errors.Is(err, postgres.ErrUsuarioBaneado), errors.Is(err, postgres.ErrTorneoPrivado):
+		utils.RespondWithForbidden(w, err.Error(), err.Error())
+	case errors.Is(err, postgres.ErrInscripcionCerrada), errors.Is(err, postgres.ErrYaEnListaEspera),
+		errors.Is(err, postgres.ErrOrganizadorNoParticipa), errors.Is(err, postgres.ErrTorneoFinalizado):
+		utils.RespondWithConflict(w, err.Error(), err.Error())
+	default:
+		utils.RespondWithDatabaseError(w, message, err.Error())
+	}
 }
 
 // SalirTorneo maneja la solicitud para que un usuario abandone un torneo
@@ -224,33 +591,79 @@ func (h *TorneoHandler) GetEquipoUsuarioTorneo(w http.ResponseWriter, r *http.Re
 	}
 
 	response := struct {
-		Equipo bool `json:"equipo"`
+		Equipo *models.TorneoEquipo `json:"equipo"`
 	}{
-		Equipo: *equipo,
+		Equipo: equipo,
 	}
 
 	utils.RespondWithSuccess(w, response, "Equipo del usuario obtenido correctamente")
 }
 
+// AvanzarEliminatorias arma las llaves de los torneos eliminatoria que comenzaron y
+// cierra las rondas vencidas. Se ejecuta antes de FinalizeTournaments para que la final
+// tenga ganador cuando el torneo llega a su fecha de fin
+func (h *TorneoHandler) AvanzarEliminatorias() error {
+	torneoIDs, err := h.repo.FindEliminatoriasEnCurso()
+	if err != nil {
+		return fmt.Errorf("error al buscar torneos eliminatoria: %w", err)
+	}
+
+	var finalErrors []string
+	for _, torneoID := range torneoIDs {
+		if err := h.repo.AvanzarEliminatoria(torneoID); err != nil {
+			finalErrors = append(finalErrors, fmt.Sprintf("Error al avanzar la llave del torneo %s: %v", torneoID, err))
+		}
+	}
+
+	if len(finalErrors) > 0 {
+		return fmt.Errorf("errores al avanzar torneos eliminatoria: %s", strings.Join(finalErrors, "; "))
+	}
+
+	return nil
+}
+
+// GetLlave devuelve la llave de un torneo eliminatoria con los puntos de cada enfrentamiento
+func (h *TorneoHandler) GetLlave(w http.ResponseWriter, r *http.Request) {
+	torneoID := mux.Vars(r)["id"]
+
+	llave, err := h.repo.GetLlave(torneoID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondWithNotFound(w, "Torneo no encontrado", "No se encontró el torneo con el ID proporcionado")
+			return
+		}
+		if errors.Is(err, postgres.ErrNoEsEliminatoria) {
+			utils.RespondWithBadRequest(w, err.Error(), err.Error())
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al obtener la llave del torneo", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, llave, "Llave del torneo obtenida correctamente")
+}
+
 // FinalizeTournaments finaliza automáticamente todos los torneos cuya fecha de fin ya ha pasado
 func (h *TorneoHandler) FinalizeTournaments() error {
 	// Buscar todos los torneos vencidos
-	creatorIDs, err := h.repo.FindExpiredTournaments()
+	torneoIDs, err := h.repo.FindExpiredTournaments()
 	if err != nil {
 		return fmt.Errorf("error al buscar torneos vencidos: %w", err)
 	}
 
 	// Si no hay torneos vencidos, retornar sin errores
-	if len(creatorIDs) == 0 {
+	if len(torneoIDs) == 0 {
 		return nil
 	}
 
 	// Finalizar cada torneo vencido
 	var finalErrors []string
-	for _, creatorID := range creatorIDs {
-		if err := h.repo.TerminarTorneo(creatorID); err != nil {
-			finalErrors = append(finalErrors, fmt.Sprintf("Error al finalizar torneo del creador %s: %v", creatorID, err))
+	for _, torneoID := range torneoIDs {
+		if err := h.repo.TerminarTorneo(torneoID); err != nil {
+			finalErrors = append(finalErrors, fmt.Sprintf("Error al finalizar el torneo %s: %v", torneoID, err))
+			continue
 		}
+		h.marcador.PublicarFin(torneoID, models.EventoFinalizado)
 	}
 
 	// Si hubo errores, reportarlos todos juntos
@@ -267,7 +680,8 @@ func (h *TorneoHandler) UpdateTorneoFechaFin(w http.ResponseWriter, r *http.Requ
 	id := vars["id"]
 
 	var body struct {
-		FechaFin string `json:"fecha_fin"`
+		OrganizadorID string `json:"organizador_id"`
+		FechaFin      string `json:"fecha_fin"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
@@ -279,10 +693,930 @@ func (h *TorneoHandler) UpdateTorneoFechaFin(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if !h.verificarOrganizador(w, id, body.OrganizadorID) {
+		return
+	}
+
 	if err := h.repo.UpdateTorneoFechaFin(id, body.FechaFin); err != nil {
+		if errors.Is(err, postgres.ErrTorneoFinalizado) {
+			utils.RespondWithConflict(w, err.Error(), err.Error())
+			return
+		}
 		utils.RespondWithDatabaseError(w, "Error al actualizar la fecha de fin del torneo", err.Error())
 		return
 	}
 
 	utils.RespondWithSuccess(w, map[string]string{"id": id, "fecha_fin": body.FechaFin}, "Fecha de fin del torneo actualizada correctamente")
 }
+
+// GetEquiposTorneo obtiene los equipos de un torneo con sus integrantes y puntos
+func (h *TorneoHandler) GetEquiposTorneo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["id"]
+
+	equipos, err := h.repo.GetEquiposTorneo(torneoID)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener los equipos del torneo", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, equipos, "Equipos del torneo obtenidos correctamente")
+}
+
+// GetRankingEquipos obtiene el ranking de equipos de un torneo según sus puntos
+func (h *TorneoHandler) GetRankingEquipos(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["torneo_id"]
+
+	equipos, err := h.repo.GetRankingEquipos(torneoID)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener el ranking de equipos", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, equipos, "Ranking de equipos obtenido correctamente")
+}
+
+// CreateEquipo agrega un equipo a un torneo en modalidad Versus
+func (h *TorneoHandler) CreateEquipo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["id"]
+
+	var body struct {
+		models.TorneoEquipo
+		OrganizadorID string `json:"organizador_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+	equipo := body.TorneoEquipo
+
+	if strings.TrimSpace(equipo.Nombre) == "" {
+		utils.RespondWithValidationError(w, "El nombre del equipo no puede estar vacío", "nombre es requerido")
+		return
+	}
+
+	if !h.verificarOrganizador(w, torneoID, body.OrganizadorID) {
+		return
+	}
+
+	equipo.IDTorneo = torneoID
+	if err := h.repo.CreateEquipo(&equipo); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			utils.RespondWithNotFound(w, "Torneo no encontrado", "No se encontró el torneo con el ID proporcionado")
+		case errors.Is(err, postgres.ErrNoEsVersus), errors.Is(err, postgres.ErrCapitanNoIntegrante):
+			utils.RespondWithValidationError(w, err.Error(), err.Error())
+		default:
+			utils.RespondWithDatabaseError(w, "Error al crear el equipo", err.Error())
+		}
+		return
+	}
+
+	utils.RespondWithCreated(w, equipo, "Equipo creado correctamente")
+}
+
+// UpdateEquipo actualiza el nombre, color o capitán de un equipo
+func (h *TorneoHandler) UpdateEquipo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["id"]
+	equipoID := vars["equipo_id"]
+
+	var body struct {
+		models.TorneoEquipo
+		OrganizadorID string `json:"organizador_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+	equipo := body.TorneoEquipo
+
+	if strings.TrimSpace(equipo.Nombre) == "" || equipo.Color == "" {
+		utils.RespondWithValidationError(w, "El nombre y el color del equipo son requeridos", "nombre y color son requeridos")
+		return
+	}
+
+	if !h.verificarOrganizador(w, torneoID, body.OrganizadorID) {
+		return
+	}
+
+	equipo.ID = equipoID
+	equipo.IDTorneo = torneoID
+	if err := h.repo.UpdateEquipo(&equipo); err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrEquipoNoEncontrado):
+			utils.RespondWithNotFound(w, "Equipo no encontrado", err.Error())
+		case errors.Is(err, postgres.ErrCapitanNoIntegrante):
+			utils.RespondWithValidationError(w, err.Error(), err.Error())
+		default:
+			utils.RespondWithDatabaseError(w, "Error al actualizar el equipo", err.Error())
+		}
+		return
+	}
+
+	utils.RespondWithSuccess(w, equipo, "Equipo actualizado correctamente")
+}
+
+// DeleteEquipo elimina un equipo que aún no tiene integrantes. Parámetro: organizador_id
+func (h *TorneoHandler) DeleteEquipo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["id"]
+	equipoID := vars["equipo_id"]
+
+	if !h.verificarOrganizador(w, torneoID, r.URL.Query().Get("organizador_id")) {
+		return
+	}
+
+	if err := h.repo.DeleteEquipo(torneoID, equipoID); err != nil {
+		if errors.Is(err, postgres.ErrEquipoNoEncontrado) {
+			utils.RespondWithNotFound(w, "Equipo no encontrado", err.Error())
+			return
+		}
+		if errors.Is(err, postgres.ErrEquipoConIntegrantes) {
+			utils.RespondWithConflict(w, err.Error(), err.Error())
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al eliminar el equipo", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, nil, "Equipo eliminado correctamente")
+}
+
+// verificarOrganizador responde con un error y devuelve false si el usuario no administra el torneo
+func (h *TorneoHandler) verificarOrganizador(w http.ResponseWriter, torneoID string, userID string) bool {
+	if userID == "" {
+		utils.RespondWithBadRequest(w, "El ID del organizador es requerido", "organizador_id es requerido")
+		return false
+	}
+
+	esOrganizador, err := h.repo.EsOrganizador(torneoID, userID)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al verificar el organizador del torneo", err.Error())
+		return false
+	}
+
+	if !esOrganizador {
+		utils.RespondWithForbidden(w, postgres.ErrNoEsOrganizador.Error(), "El usuario no administra este torneo")
+		return false
+	}
+
+	return true
+}
+
+// verificarDueno responde con un error y devuelve false si el usuario no es el dueño del torneo
+func (h *TorneoHandler) verificarDueno(w http.ResponseWriter, torneoID string, userID string) bool {
+	if userID == "" {
+		utils.RespondWithBadRequest(w, "El ID del dueño es requerido", "dueno_id es requerido")
+		return false
+	}
+
+	esDueno, err := h.repo.EsDueno(torneoID, userID)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al verificar el dueño del torneo", err.Error())
+		return false
+	}
+
+	if !esDueno {
+		utils.RespondWithForbidden(w, postgres.ErrNoEsDueno.Error(), "El usuario no es el dueño de este torneo")
+		return false
+	}
+
+	return true
+}
+
+// respondWithErrorCambioEquipo traduce los errores de reglas de equipos a respuestas HTTP
+func respondWithErrorCambioEquipo(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, postgres.ErrUsuarioNoInscrito),
+		errors.Is(err, postgres.ErrEquipoNoEncontrado),
+		errors.Is(err, postgres.ErrCambioNoEncontrado),
+		errors.Is(err, sql.ErrNoRows):
+		utils.RespondWithNotFound(w, message, err.Error())
+	case errors.Is(err, postgres.ErrEquiposBloqueados),
+		errors.Is(err, postgres.ErrCambioEquipoDeshabilitado),
+		errors.Is(err, postgres.ErrTorneoIniciado),
+		errors.Is(err, postgres.ErrMismoEquipo),
+		errors.Is(err, postgres.ErrEquipoLleno):
+		utils.RespondWithConflict(w, err.Error(), err.Error())
+	case errors.Is(err, postgres.ErrNoEsVersus):
+		utils.RespondWithValidationError(w, err.Error(), err.Error())
+	default:
+		utils.RespondWithDatabaseError(w, message, err.Error())
+	}
+}
+
+// UpdateConfiguracionEquipos permite al organizador cambiar el modo de asignación de
+// equipos, bloquearlos y decidir si se permiten cambios de equipo antes del inicio
+func (h *TorneoHandler) UpdateConfiguracionEquipos(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["id"]
+
+	var body struct {
+		OrganizadorID            string `json:"organizador_id"`
+		AsignacionEquipos        string `json:"asignacion_equipos"`
+		EquiposBloqueados        bool   `json:"equipos_bloqueados"`
+		PermitirCambioEquipo     bool   `json:"permitir_cambio_equipo"`
+		CambioRequiereAprobacion bool   `json:"cambio_requiere_aprobacion"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	switch body.AsignacionEquipos {
+	case models.AsignacionEquiposLibre, models.AsignacionEquiposCantidad, models.AsignacionEquiposPuntos, models.AsignacionEquiposRating:
+	default:
+		utils.RespondWithValidationError(w, "Modo de asignación de equipos no válido", "asignacion_equipos debe ser libre, cantidad, puntos o rating")
+		return
+	}
+
+	if !h.verificarOrganizador(w, torneoID, body.OrganizadorID) {
+		return
+	}
+
+	torneo := models.Torneo{
+		ID:                       torneoID,
+		AsignacionEquipos:        body.AsignacionEquipos,
+		EquiposBloqueados:        body.EquiposBloqueados,
+		PermitirCambioEquipo:     body.PermitirCambioEquipo,
+		CambioRequiereAprobacion: body.CambioRequiereAprobacion,
+	}
+	if err := h.repo.UpdateConfiguracionEquipos(&torneo); err != nil {
+		respondWithErrorCambioEquipo(w, err, "Error al actualizar la configuración de equipos")
+		return
+	}
+
+	utils.RespondWithSuccess(w, body, "Configuración de equipos actualizada correctamente")
+}
+
+// SolicitarCambioEquipo registra la solicitud de un jugador para cambiarse de equipo
+func (h *TorneoHandler) SolicitarCambioEquipo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["torneo_id"]
+	userID := vars["user_id"]
+
+	var body struct {
+		EquipoID string `json:"equipo_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	if body.EquipoID == "" {
+		utils.RespondWithBadRequest(w, "El equipo de destino es requerido", "equipo_id es requerido")
+		return
+	}
+
+	cambio, err := h.repo.SolicitarCambioEquipo(torneoID, userID, body.EquipoID)
+	if err != nil {
+		respondWithErrorCambioEquipo(w, err, "Error al solicitar el cambio de equipo")
+		return
+	}
+
+	if cambio.Estado == "pendiente" {
+		utils.RespondWithCreated(w, cambio, "Solicitud de cambio de equipo enviada al organizador")
+		return
+	}
+
+	utils.RespondWithSuccess(w, cambio, "Cambio de equipo realizado correctamente")
+}
+
+// GetCambiosEquipo obtiene las solicitudes de cambio de equipo de un torneo para su organizador
+func (h *TorneoHandler) GetCambiosEquipo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["id"]
+
+	if !h.verificarOrganizador(w, torneoID, r.URL.Query().Get("organizador_id")) {
+		return
+	}
+
+	cambios, err := h.repo.GetCambiosEquipo(torneoID, r.URL.Query().Get("estado"))
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener las solicitudes de cambio de equipo", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, cambios, "Solicitudes de cambio de equipo obtenidas correctamente")
+}
+
+// ResolverCambioEquipo permite al organizador aprobar o rechazar una solicitud de cambio de equipo
+func (h *TorneoHandler) ResolverCambioEquipo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["id"]
+	cambioID := vars["cambio_id"]
+
+	var body struct {
+		OrganizadorID string `json:"organizador_id"`
+		Aprobar       bool   `json:"aprobar"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	if !h.verificarOrganizador(w, torneoID, body.OrganizadorID) {
+		return
+	}
+
+	cambio, err := h.repo.ResolverCambioEquipo(torneoID, cambioID, body.Aprobar)
+	if err != nil {
+		respondWithErrorCambioEquipo(w, err, "Error al resolver la solicitud de cambio de equipo")
+		return
+	}
+
+	utils.RespondWithSuccess(w, cambio, "Solicitud de cambio de equipo resuelta correctamente")
+}
+
+// respondWithErrorParticipante traduce los errores de gestión de participantes a respuestas HTTP
+func respondWithErrorParticipante(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, postgres.ErrUsuarioNoInscrito),
+		errors.Is(err, sql.ErrNoRows):
+		utils.RespondWithNotFound(w, message, err.Error())
+	case errors.Is(err, postgres.ErrTorneoFinalizado):
+		utils.RespondWithConflict(w, err.Error(), err.Error())
+	default:
+		utils.RespondWithDatabaseError(w, message, err.Error())
+	}
+}
+
+// SetHabilitadoParticipante permite al organizador deshabilitar o rehabilitar la
+// puntuación de un participante. Al deshabilitar se debe indicar un motivo
+func (h *TorneoHandler) SetHabilitadoParticipante(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["torneo_id"]
+	userID := vars["user_id"]
+
+	var body struct {
+		OrganizadorID string `json:"organizador_id"`
+		Habilitado    bool   `json:"habilitado"`
+		Motivo        string `json:"motivo"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	body.Motivo = strings.TrimSpace(body.Motivo)
+	if !body.Habilitado && body.Motivo == "" {
+		utils.RespondWithValidationError(w, "Debes indicar el motivo para deshabilitar al participante", "motivo es requerido")
+		return
+	}
+
+	if !h.verificarOrganizador(w, torneoID, body.OrganizadorID) {
+		return
+	}
+
+	if err := h.repo.SetHabilitadoParticipante(torneoID, userID, body.OrganizadorID, body.Habilitado, body.Motivo); err != nil {
+		respondWithErrorParticipante(w, err, "Error al actualizar al participante")
+		return
+	}
+	h.marcador.PublicarMarcador(torneoID)
+
+	message := "Participante habilitado correctamente"
+	if !body.Habilitado {
+		message = "Participante deshabilitado correctamente"
+	}
+
+	utils.RespondWithSuccess(w, nil, message)
+}
+
+// ExpulsarParticipante permite al organizador sacar a un participante del torneo y,
+// opcionalmente, impedir que vuelva a inscribirse con el código
+func (h *TorneoHandler) ExpulsarParticipante(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["torneo_id"]
+	userID := vars["user_id"]
+
+	var body struct {
+		OrganizadorID string `json:"organizador_id"`
+		Motivo        string `json:"motivo"`
+		Banear        bool   `json:"banear"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	body.Motivo = strings.TrimSpace(body.Motivo)
+	if body.Motivo == "" {
+		utils.RespondWithValidationError(w, "Debes indicar el motivo de la expulsión", "motivo es requerido")
+		return
+	}
+
+	if !h.verificarOrganizador(w, torneoID, body.OrganizadorID) {
+		return
+	}
+
+	if body.OrganizadorID == userID {
+		utils.RespondWithBadRequest(w, "No puedes expulsarte de tu propio torneo", "organizador_id y user_id son iguales")
+		return
+	}
+
+	if err := h.repo.ExpulsarParticipante(torneoID, userID, body.OrganizadorID, body.Motivo, body.Banear); err != nil {
+		respondWithErrorParticipante(w, err, "Error al expulsar al participante")
+		return
+	}
+	h.marcador.PublicarMarcador(torneoID)
+
+	message := "Participante expulsado correctamente"
+	if body.Banear {
+		message = "Participante expulsado y baneado correctamente"
+	}
+
+	utils.RespondWithSuccess(w, nil, message)
+}
+
+// LevantarBaneo permite al organizador quitar el baneo de un jugador
+func (h *TorneoHandler) LevantarBaneo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["torneo_id"]
+	userID := vars["user_id"]
+
+	if !h.verificarOrganizador(w, torneoID, r.URL.Query().Get("organizador_id")) {
+		return
+	}
+
+	if err := h.repo.LevantarBaneo(torneoID, userID); err != nil {
+		respondWithErrorParticipante(w, err, "El usuario no tiene un baneo vigente en este torneo")
+		return
+	}
+
+	utils.RespondWithSuccess(w, nil, "Baneo levantado correctamente")
+}
+
+// GetAccionesTorneo devuelve a los organizadores las acciones que puntuaron en el torneo,
+// incluidas las anuladas, para revisarlas. Parámetro: organizador_id
+func (h *TorneoHandler) GetAccionesTorneo(w http.ResponseWriter, r *http.Request) {
+	torneoID := mux.Vars(r)["id"]
+
+	if !h.verificarOrganizador(w, torneoID, r.URL.Query().Get("organizador_id")) {
+		return
+	}
+
+	acciones, err := h.repo.GetAccionesTorneo(torneoID)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener las acciones del torneo", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, acciones, "Acciones del torneo obtenidas correctamente")
+}
+
+// AnularAccion permite al organizador anular una acción mientras el torneo no haya
+// finalizado, también durante el periodo de verificación. Se debe indicar un motivo
+func (h *TorneoHandler) AnularAccion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["id"]
+	actionID := vars["action_id"]
+
+	var body struct {
+		OrganizadorID string `json:"organizador_id"`
+		Motivo        string `json:"motivo"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	body.Motivo = strings.TrimSpace(body.Motivo)
+	if body.Motivo == "" {
+		utils.RespondWithValidationError(w, "Debes indicar el motivo para anular la acción", "motivo es requerido")
+		return
+	}
+
+	if !h.verificarOrganizador(w, torneoID, body.OrganizadorID) {
+		return
+	}
+
+	if err := h.repo.AnularAccion(torneoID, actionID, body.OrganizadorID, body.Motivo); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			utils.RespondWithNotFound(w, "Torneo no encontrado", "No se encontró el torneo con el ID proporcionado")
+		case errors.Is(err, postgres.ErrAccionNoEncontrada):
+			utils.RespondWithNotFound(w, err.Error(), err.Error())
+		case errors.Is(err, postgres.ErrAccionYaAnulada),
+			errors.Is(err, postgres.ErrTorneoFinalizado):
+			utils.RespondWithConflict(w, err.Error(), err.Error())
+		default:
+			utils.RespondWithDatabaseError(w, "Error al anular la acción", err.Error())
+		}
+		return
+	}
+	h.marcador.PublicarMarcador(torneoID)
+
+	utils.RespondWithSuccess(w, nil, "Acción anulada correctamente")
+}
+
+// GetEstadoParticipante devuelve al jugador su estado en el torneo y los motivos
+// de las sanciones que le haya aplicado el organizador
+func (h *TorneoHandler) GetEstadoParticipante(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["torneo_id"]
+	userID := vars["user_id"]
+
+	estado, err := h.repo.GetEstadoParticipante(torneoID, userID)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener el estado del participante", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, estado, "Estado del participante obtenido correctamente")
+}
+
+// GetListaEspera devuelve la lista de espera de un torneo en orden de llegada
+func (h *TorneoHandler) GetListaEspera(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["id"]
+
+	lista, err := h.repo.GetListaEspera(torneoID)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener la lista de espera", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, lista, "Lista de espera obtenida correctamente")
+}
+
+// GetReglasPuntuacion devuelve las reglas con las que el torneo puntúa las acciones
+func (h *TorneoHandler) GetReglasPuntuacion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["id"]
+
+	reglas, err := h.repo.GetReglasPuntuacion(torneoID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.RespondWithNotFound(w, "Torneo no encontrado", err.Error())
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al obtener las reglas del torneo", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, reglas, "Reglas del torneo obtenidas correctamente")
+}
+
+// UpdateReglasPuntuacion permite al organizador definir, antes del inicio, los puntos
+// por tipo de acción, deshabilitar tipos, limitar los puntos diarios y dar un bono
+// por la primera acción en un lugar nuevo
+func (h *TorneoHandler) UpdateReglasPuntuacion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["id"]
+
+	var body struct {
+		OrganizadorID string `json:"organizador_id"`
+		models.ReglasPuntuacion
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	reglas := body.ReglasPuntuacion
+	reglas.IDTorneo = torneoID
+
+	vistos := map[string]bool{}
+	for _, regla := range reglas.Acciones {
+		valido := false
+		for _, tipo := range models.TiposAccion {
+			valido = valido || regla.TipoAccion == tipo
+		}
+		if !valido {
+			utils.RespondWithValidationError(w, "Tipo de acción no válido: "+regla.TipoAccion, "tipo_accion debe ser ayuda, alerta o descubrimiento")
+			return
+		}
+		if vistos[regla.TipoAccion] {
+			utils.RespondWithValidationError(w, "Tipo de acción repetido: "+regla.TipoAccion, "cada tipo_accion solo puede aparecer una vez")
+			return
+		}
+		if regla.Puntos < 0 {
+			utils.RespondWithValidationError(w, "Los puntos no pueden ser negativos", "puntos debe ser mayor o igual a cero")
+			return
+		}
+		vistos[regla.TipoAccion] = true
+	}
+
+	if reglas.MaxPuntosDiarios != nil && *reglas.MaxPuntosDiarios <= 0 {
+		utils.RespondWithValidationError(w, "El máximo de puntos diarios debe ser mayor que cero", "max_puntos_diarios debe ser positivo")
+		return
+	}
+
+	if reglas.BonoLugarNuevo < 0 {
+		utils.RespondWithValidationError(w, "El bono por lugar nuevo no puede ser negativo", "bono_lugar_nuevo debe ser mayor o igual a cero")
+		return
+	}
+
+	if !h.verificarOrganizador(w, torneoID, body.OrganizadorID) {
+		return
+	}
+
+	if err := h.repo.UpdateReglasPuntuacion(&reglas); err != nil {
+		if errors.Is(err, postgres.ErrTorneoIniciado) {
+			utils.RespondWithConflict(w, "Las reglas solo se pueden cambiar antes del inicio del torneo", err.Error())
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al actualizar las reglas del torneo", err.Error())
+		return
+	}
+
+	actualizadas, err := h.repo.GetReglasPuntuacion(torneoID)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener las reglas del torneo", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, actualizadas, "Reglas del torneo actualizadas correctamente")
+}
+
+// InvitarAmigo permite a un organizador o participante invitar a un amigo al torneo,
+// opcionalmente con un equipo preseleccionado
+func (h *TorneoHandler) InvitarAmigo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["id"]
+
+	var body struct {
+		InvitadorID string  `json:"invitador_id"`
+		InvitadoID  string  `json:"invitado_id"`
+		EquipoID    *string `json:"equipo_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	if body.InvitadorID == "" || body.InvitadoID == "" {
+		utils.RespondWithValidationError(w, "El invitador y el invitado son requeridos", "invitador_id e invitado_id son requeridos")
+		return
+	}
+
+	if body.InvitadorID == body.InvitadoID {
+		utils.RespondWithBadRequest(w, "No puedes invitarte a ti mismo", "invitador_id e invitado_id son iguales")
+		return
+	}
+
+	invitacion, err := h.repo.InvitarAmigo(torneoID, body.InvitadorID, body.InvitadoID, body.EquipoID)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrNoPuedeInvitar), errors.Is(err, postgres.ErrNoSonAmigos),
+			errors.Is(err, postgres.ErrUsuarioBaneado):
+			utils.RespondWithForbidden(w, err.Error(), err.Error())
+		case errors.Is(err, postgres.ErrInvitacionExistente), errors.Is(err, postgres.ErrTorneoFinalizado):
+			utils.RespondWithConflict(w, err.Error(), err.Error())
+		case errors.Is(err, postgres.ErrEquipoNoEncontrado), errors.Is(err, postgres.ErrEquipoNoSeleccionable):
+			utils.RespondWithValidationError(w, err.Error(), err.Error())
+		default:
+			utils.RespondWithDatabaseError(w, "Error al invitar al usuario", err.Error())
+		}
+		return
+	}
+
+	utils.RespondWithCreated(w, invitacion, "Invitación enviada correctamente")
+}
+
+// GetInvitacionesUsuario devuelve las invitaciones a torneos recibidas por un usuario.
+// Se pueden filtrar con el parámetro estado
+func (h *TorneoHandler) GetInvitacionesUsuario(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["user_id"]
+
+	invitaciones, err := h.repo.GetInvitacionesUsuario(userID, r.URL.Query().Get("estado"))
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener las invitaciones", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, invitaciones, "Invitaciones obtenidas correctamente")
+}
+
+// ResponderInvitacion permite al invitado aceptar o rechazar una invitación
+func (h *TorneoHandler) ResponderInvitacion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["user_id"]
+	invitacionID := vars["invitacion_id"]
+
+	var body struct {
+		Aceptar bool `json:"aceptar"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	// Aceptar la invitación tiene las mismas restricciones que inscribirse con el código;
+	// el repositorio las comprueba dentro de la misma transacción
+	invitacion, inscripcion, err := h.repo.ResponderInvitacion(invitacionID, userID, body.Aceptar)
+	if err != nil {
+		if errors.Is(err, postgres.ErrInvitacionNoEncontrada) {
+			utils.RespondWithNotFound(w, err.Error(), err.Error())
+			return
+		}
+		respondWithErrorInscripcion(w, err, "Error al responder la invitación")
+		return
+	}
+
+	if !body.Aceptar {
+		utils.RespondWithSuccess(w, map[string]interface{}{"invitacion": invitacion}, "Invitación rechazada")
+		return
+	}
+
+	message := "Invitación aceptada, quedaste inscrito en el torneo"
+	if inscripcion.Estado == models.InscripcionListaEspera {
+		message = "Invitación aceptada, el torneo está lleno y quedaste en la lista de espera"
+	}
+
+	utils.RespondWithSuccess(w, map[string]interface{}{
+		"invitacion":  invitacion,
+		"inscripcion": inscripcion,
+	}, message)
+}
+
+// CancelarInvitacion permite al invitador retirar una invitación pendiente
+func (h *TorneoHandler) CancelarInvitacion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["id"]
+	invitacionID := vars["invitacion_id"]
+
+	invitadorID := r.URL.Query().Get("invitador_id")
+	if invitadorID == "" {
+		utils.RespondWithBadRequest(w, "El ID del invitador es requerido", "invitador_id es requerido")
+		return
+	}
+
+	if err := h.repo.CancelarInvitacion(torneoID, invitacionID, invitadorID); err != nil {
+		if errors.Is(err, postgres.ErrInvitacionNoEncontrada) {
+			utils.RespondWithNotFound(w, err.Error(), err.Error())
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al cancelar la invitación", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, nil, "Invitación cancelada correctamente")
+}
+
+// respondWithErrorStaff traduce los errores de gestión de organizadores a respuestas HTTP
+func respondWithErrorStaff(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, postgres.ErrOrganizadorNoEncontrado):
+		utils.RespondWithNotFound(w, err.Error(), err.Error())
+	case errors.Is(err, postgres.ErrYaEsOrganizador), errors.Is(err, postgres.ErrOrganizadorNoParticipa),
+		errors.Is(err, postgres.ErrNuevoDuenoOcupado), errors.Is(err, postgres.ErrTorneoFinalizado):
+		utils.RespondWithConflict(w, err.Error(), err.Error())
+	default:
+		utils.RespondWithDatabaseError(w, message, err.Error())
+	}
+}
+
+// GetStaff lista el dueño y los coorganizadores de un torneo
+func (h *TorneoHandler) GetStaff(w http.ResponseWriter, r *http.Request) {
+	staff, err := h.repo.GetStaff(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener los organizadores", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, staff, "Organizadores obtenidos correctamente")
+}
+
+// AgregarCoorganizador permite al dueño sumar un coorganizador al torneo
+func (h *TorneoHandler) AgregarCoorganizador(w http.ResponseWriter, r *http.Request) {
+	torneoID := mux.Vars(r)["id"]
+
+	var body struct {
+		DuenoID string `json:"dueno_id"`
+		UserID  string `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	if body.UserID == "" {
+		utils.RespondWithBadRequest(w, "El ID del usuario es requerido", "user_id es requerido")
+		return
+	}
+
+	if !h.verificarDueno(w, torneoID, body.DuenoID) {
+		return
+	}
+
+	if err := h.repo.AgregarCoorganizador(torneoID, body.UserID); err != nil {
+		respondWithErrorStaff(w, err, "Error al agregar el coorganizador")
+		return
+	}
+
+	staff, err := h.repo.GetStaff(torneoID)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener los organizadores", err.Error())
+		return
+	}
+
+	utils.RespondWithCreated(w, staff, "Coorganizador agregado correctamente")
+}
+
+// QuitarCoorganizador quita a un coorganizador. Lo puede hacer el dueño, o el propio
+// coorganizador para dejar el torneo. Parámetro: dueno_id (el dueño o el mismo coorganizador)
+func (h *TorneoHandler) QuitarCoorganizador(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	torneoID := vars["id"]
+	userID := vars["user_id"]
+	solicitanteID := r.URL.Query().Get("dueno_id")
+
+	if solicitanteID != userID && !h.verificarDueno(w, torneoID, solicitanteID) {
+		return
+	}
+
+	if err := h.repo.QuitarCoorganizador(torneoID, userID); err != nil {
+		respondWithErrorStaff(w, err, "Error al quitar el coorganizador")
+		return
+	}
+
+	utils.RespondWithSuccess(w, nil, "Coorganizador quitado correctamente")
+}
+
+// TransferirPropiedad cede el torneo a otro usuario. Solo puede hacerlo el dueño actual
+func (h *TorneoHandler) TransferirPropiedad(w http.ResponseWriter, r *http.Request) {
+	torneoID := mux.Vars(r)["id"]
+
+	var body struct {
+		DuenoID                 string `json:"dueno_id"`
+		NuevoDuenoID            string `json:"nuevo_dueno_id"`
+		SeguirComoCoorganizador bool   `json:"seguir_como_coorganizador"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	if body.NuevoDuenoID == "" {
+		utils.RespondWithBadRequest(w, "El ID del nuevo dueño es requerido", "nuevo_dueno_id es requerido")
+		return
+	}
+
+	if body.NuevoDuenoID == body.DuenoID {
+		utils.RespondWithValidationError(w, "El usuario ya es el dueño del torneo", "nuevo_dueno_id debe ser distinto de dueno_id")
+		return
+	}
+
+	if !h.verificarDueno(w, torneoID, body.DuenoID) {
+		return
+	}
+
+	err := h.repo.TransferirPropiedad(torneoID, body.DuenoID, body.NuevoDuenoID, body.SeguirComoCoorganizador)
+	if err != nil {
+		respondWithErrorStaff(w, err, "Error al transferir el torneo")
+		return
+	}
+
+	staff, err := h.repo.GetStaff(torneoID)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener los organizadores", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, staff, "Torneo transferido correctamente")
+}
+
+// CancelarTorneo cancela un torneo activo conservándolo en el historial y revierte la
+// participación de sus jugadores. Solo puede hacerlo el dueño
+func (h *TorneoHandler) CancelarTorneo(w http.ResponseWriter, r *http.Request) {
+	torneoID := mux.Vars(r)["id"]
+
+	var body struct {
+		DuenoID string  `json:"dueno_id"`
+		Motivo  *string `json:"motivo"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	if !h.verificarDueno(w, torneoID, body.DuenoID) {
+		return
+	}
+
+	if err := h.repo.CancelarTorneo(torneoID, body.Motivo); err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			utils.RespondWithNotFound(w, "Torneo no encontrado", "No se encontró el torneo con el ID proporcionado")
+		case errors.Is(err, postgres.ErrTorneoFinalizado):
+			utils.RespondWithConflict(w, err.Error(), err.Error())
+		default:
+			utils.RespondWithDatabaseError(w, "Error al cancelar el torneo", err.Error())
+		}
+		return
+	}
+	h.marcador.PublicarFin(torneoID, models.EventoCancelado)
+
+	torneo, err := h.repo.GetTorneoByID(torneoID)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener el torneo", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, torneo, "Torneo cancelado correctamente")
+}
@@ -22,14 +22,16 @@ import (
 type UserActionsHandler struct {
 	repo         *postgres.UserActionsRepository
 	medallasRepo *postgres.MedallasRepository
+	marcador     *MarcadorHandler
 	bunnyClient  *bunnystorage.Client
 	storageZone  string
 }
 
-func NewUserActionsHandler(repo *postgres.UserActionsRepository, medallasRepo *postgres.MedallasRepository, bunnyClient *bunnystorage.Client, storageZone string) *UserActionsHandler {
+func NewUserActionsHandler(repo *postgres.UserActionsRepository, medallasRepo *postgres.MedallasRepository, marcador *MarcadorHandler, bunnyClient *bunnystorage.Client, storageZone string) *UserActionsHandler {
 	return &UserActionsHandler{
 		repo:         repo,
 		medallasRepo: medallasRepo,
+		marcador:     marcador,
 		bunnyClient:  bunnyClient,
 		storageZone:  storageZone,
 	}
@@ -101,28 +103,41 @@ func (h *UserActionsHandler) CreateAction(w http.ResponseWriter, r *http.Request
 		EsParaTorneo:   false,
 	}
 
-	err = h.repo.CreateAction(&action)
-	if err != nil {
-		utils.RespondWithDatabaseError(w, "Error al crear la acción", err.Error())
-		return
-	}
-
 	if isTournamentValid {
 		torneoID, err := h.repo.GetTorneoID(userID)
 		if err != nil {
 			utils.RespondWithDatabaseError(w, "Error al obtener el ID del torneo", err.Error())
 			return
 		}
-		_, err = h.repo.TorneoPoints(userID, action.TipoAccion, torneoID)
+
+		geocerca, err := h.repo.GetGeocercaTorneo(torneoID)
 		if err != nil {
-			utils.RespondWithDatabaseError(w, "Error al actualizar los puntos del torneo", err.Error())
+			utils.RespondWithDatabaseError(w, "Error al obtener el área del torneo", err.Error())
 			return
 		}
+
+		// Solo puntúan las acciones hechas dentro del área del torneo
+		dentro, motivo := utils.EvaluarGeocerca(geocerca, action.Latitud, action.Longitud)
+		if dentro {
+			action.EsParaTorneo = true
+			action.IDTorneo = &torneoID
+		} else {
+			action.ResultadoTorneo = &models.ResultadoTorneo{IDTorneo: torneoID, Motivo: motivo}
+		}
+	}
+
+	err = h.repo.CreateAction(&action)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al crear la acción", err.Error())
+		return
 	}
 
 	// Verificar si el usuario ha ganado medallas
 	h.checkMedallas(userID)
 
+	// Actualizar el marcador en vivo del torneo
+	h.marcador.PublicarAccion(&action)
+
 	utils.RespondWithCreated(w, action, "Acción creada correctamente")
 }
 
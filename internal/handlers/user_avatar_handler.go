@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/internal/repository/postgres"
+	"backend_proyecto_verde/internal/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// GetAvatarItems devuelve el catálogo de artículos de avatar con sus condiciones de
+// desbloqueo. Acepta ?categoria= para ver una sola categoría
+func (h *UserHandler) GetAvatarItems(w http.ResponseWriter, r *http.Request) {
+	categoria := r.URL.Query().Get("categoria")
+	if categoria != "" && !slices.Contains(models.CategoriasAvatar, categoria) {
+		utils.RespondWithValidationError(w, "Categoría no válida", "categoria debe ser una de: "+strings.Join(models.CategoriasAvatar, ", "))
+		return
+	}
+
+	items, err := h.repo.GetAvatarItems(categoria)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener los artículos de avatar", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, items, "Artículos de avatar obtenidos correctamente")
+}
+
+func (h *UserHandler) CreateAvatarItem(w http.ResponseWriter, r *http.Request) {
+	var item models.AvatarItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		utils.RespondWithBadRequest(w, "Error al decodificar el cuerpo de la solicitud", err.Error())
+		return
+	}
+
+	if item.TipoDesbloqueo == "" {
+		item.TipoDesbloqueo = models.DesbloqueoLibre
+	}
+	if message, detail := validarAvatarItem(&item); message != "" {
+		utils.RespondWithValidationError(w, message, detail)
+		return
+	}
+
+	if err := h.repo.CreateAvatarItem(&item); err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrMedallaNoEncontrada):
+			utils.RespondWithNotFound(w, "Medalla no encontrada", err.Error())
+		case errors.Is(err, postgres.ErrItemAvatarDuplicado):
+			utils.RespondWithConflict(w, "Artículo de avatar duplicado", err.Error())
+		default:
+			utils.RespondWithDatabaseError(w, "Error al crear el artículo de avatar", err.Error())
+		}
+		return
+	}
+
+	utils.RespondWithCreated(w, item, "Artículo de avatar creado correctamente")
+}
+
+// validarAvatarItem revisa que el artículo tenga la condición que pide su tipo de
+// desbloqueo y nada más
+func validarAvatarItem(item *models.AvatarItem) (string, string) {
+	item.Valor = strings.TrimSpace(item.Valor)
+
+	if !slices.Contains(models.CategoriasAvatar, item.Categoria) {
+		return "Categoría no válida", "categoria debe ser una de: " + strings.Join(models.CategoriasAvatar, ", ")
+	}
+	if item.Valor == "" || len(item.Valor) > 100 {
+		return "Valor no válido", "valor debe tener entre 1 y 100 caracteres"
+	}
+	if item.Nombre != nil && len(*item.Nombre) > 255 {
+		return "Nombre no válido", "nombre no puede tener más de 255 caracteres"
+	}
+	if !slices.Contains(models.TiposDesbloqueoAvatar, item.TipoDesbloqueo) {
+		return "Tipo de desbloqueo no válido", "tipo_desbloqueo debe ser uno de: " + strings.Join(models.TiposDesbloqueoAvatar, ", ")
+	}
+
+	conMedalla := item.TipoDesbloqueo == models.DesbloqueoMedalla
+	if conMedalla != (item.IDMedalla != nil) {
+		return "Medalla no válida", "id_medalla solo se indica, y es obligatorio, con tipo_desbloqueo 'medalla'"
+	}
+
+	conUmbral := item.TipoDesbloqueo != models.DesbloqueoLibre && !conMedalla
+	if conUmbral && (item.Umbral == nil || *item.Umbral <= 0) {
+		return "Umbral no válido", "umbral debe ser mayor a 0 con tipo_desbloqueo '" + item.TipoDesbloqueo + "'"
+	}
+	if !conUmbral && item.Umbral != nil {
+		return "Umbral no válido", "umbral solo se indica con tipo_desbloqueo 'puntos', 'nivel' o 'victoria_torneo'"
+	}
+
+	return "", ""
+}
+
+// GetInventarioAvatar devuelve el catálogo de avatar indicando qué artículos tiene
+// desbloqueados el usuario, junto con su nivel
+func (h *UserHandler) GetInventarioAvatar(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	inventario, err := h.repo.GetInventarioAvatar(id)
+	if err != nil {
+		if errors.Is(err, postgres.ErrUsuarioNoEncontrado) {
+			utils.RespondWithNotFound(w, "Usuario no encontrado", err.Error())
+			return
+		}
+		utils.RespondWithDatabaseError(w, "Error al obtener el inventario de avatar", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, inventario, "Inventario de avatar obtenido correctamente")
+}
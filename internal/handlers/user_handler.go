@@ -5,6 +5,7 @@ import (
 	"backend_proyecto_verde/internal/repository/postgres"
 	"backend_proyecto_verde/internal/utils"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -215,7 +216,16 @@ func (h *UserHandler) UpdateUserProfileEdit(w http.ResponseWriter, r *http.Reque
 
 	profile.UserID = id
 	if err := h.repo.EditUserProfile(&profile); err != nil {
-		utils.RespondWithDatabaseError(w, "Error al actualizar el perfil del usuario", err.Error())
+		switch {
+		case errors.Is(err, postgres.ErrPerfilNoEncontrado):
+			utils.RespondWithNotFound(w, "Perfil de usuario no encontrado", err.Error())
+		case errors.Is(err, postgres.ErrItemAvatarBloqueado):
+			utils.RespondWithForbidden(w, "Artículo de avatar bloqueado", err.Error())
+		case errors.Is(err, postgres.ErrItemAvatarNoEncontrado):
+			utils.RespondWithValidationError(w, "Artículo de avatar no válido", err.Error())
+		default:
+			utils.RespondWithDatabaseError(w, "Error al actualizar el perfil del usuario", err.Error())
+		}
 		return
 	}
 
@@ -264,6 +274,47 @@ func (h *UserHandler) GetRanking(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithSuccess(w, ranking, "Ranking obtenido correctamente")
 }
 
+// GetRankingRating obtiene la clasificación de jugadores por rating de habilidad.
+// Parámetros: limit (por defecto 20, máximo 100) y offset
+func (h *UserHandler) GetRankingRating(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	ranking, err := h.repo.GetRankingRating(limit, offset)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener el ranking por rating", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, ranking, "Ranking por rating obtenido correctamente")
+}
+
+// GetRatingUsuario obtiene el rating de habilidad de un usuario y su historial reciente
+func (h *UserHandler) GetRatingUsuario(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	rating, historial, err := h.repo.GetRatingUsuario(userID, 20)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener el rating del usuario", err.Error())
+		return
+	}
+
+	response := struct {
+		*models.RatingJugador
+		Historial []models.HistorialRating `json:"historial"`
+	}{rating, historial}
+
+	utils.RespondWithSuccess(w, response, "Rating del usuario obtenido correctamente")
+}
+
 // GetRankingTorneo obtiene el ranking de usuarios para un torneo específico
 // según los puntos obtenidos en ese torneo
 func (h *UserHandler) GetRankingTorneo(w http.ResponseWriter, r *http.Request) {
@@ -278,3 +329,33 @@ func (h *UserHandler) GetRankingTorneo(w http.ResponseWriter, r *http.Request) {
 
 	utils.RespondWithSuccess(w, ranking, "Ranking del torneo obtenido correctamente")
 }
+
+// GetNotificaciones obtiene los avisos más recientes de un usuario.
+// Parámetros: no_leidas (true para ver solo los pendientes) y limit (por defecto 20, máximo 100)
+func (h *UserHandler) GetNotificaciones(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+	soloNoLeidas := r.URL.Query().Get("no_leidas") == "true"
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	notificaciones, err := h.repo.GetNotificaciones(userID, soloNoLeidas, limit)
+	if err != nil {
+		utils.RespondWithDatabaseError(w, "Error al obtener las notificaciones", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, notificaciones, "Notificaciones obtenidas correctamente")
+}
+
+// MarcarNotificacionesLeidas marca como leídos todos los avisos de un usuario
+func (h *UserHandler) MarcarNotificacionesLeidas(w http.ResponseWriter, r *http.Request) {
+	if err := h.repo.MarcarNotificacionesLeidas(mux.Vars(r)["id"]); err != nil {
+		utils.RespondWithDatabaseError(w, "Error al actualizar las notificaciones", err.Error())
+		return
+	}
+
+	utils.RespondWithSuccess(w, nil, "Notificaciones marcadas como leídas")
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// AvatarItem es un artículo del catálogo de avatar. Valor es lo que se guarda en la
+// columna de user_profile que indica Categoria
+type AvatarItem struct {
+	ID             string    `json:"id"`
+	Categoria      string    `json:"categoria"`
+	Valor          string    `json:"valor"`
+	Nombre         *string   `json:"nombre,omitempty"`
+	TipoDesbloqueo string    `json:"tipo_desbloqueo"`
+	Umbral         *int      `json:"umbral,omitempty"`     // Puntos, nivel o torneos ganados
+	IDMedalla      *string   `json:"id_medalla,omitempty"` // Solo con tipo_desbloqueo medalla
+	Capa           *string   `json:"capa,omitempty"`       // URL del PNG con el que se dibuja
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Categorías de artículos de avatar, una por columna de user_profile
+const (
+	CategoriaCabello          = "cabello"
+	CategoriaVestimenta       = "vestimenta"
+	CategoriaBarba            = "barba"
+	CategoriaDetalleFacial    = "detalle_facial"
+	CategoriaDetalleAdicional = "detalle_adicional"
+)
+
+var CategoriasAvatar = []string{CategoriaCabello, CategoriaVestimenta, CategoriaBarba,
+	CategoriaDetalleFacial, CategoriaDetalleAdicional}
+
+// OrdenCapasAvatar es el orden en que se dibujan las capas del avatar, de abajo hacia arriba
+var OrdenCapasAvatar = []string{CategoriaVestimenta, CategoriaDetalleFacial, CategoriaBarba,
+	CategoriaCabello, CategoriaDetalleAdicional}
+
+// Formas de desbloquear un artículo de avatar
+const (
+	DesbloqueoLibre          = "libre"           // Disponible para todos
+	DesbloqueoMedalla        = "medalla"         // Al ganar la medalla indicada
+	DesbloqueoPuntos         = "puntos"          // Al llegar a umbral puntos
+	DesbloqueoNivel          = "nivel"           // Al llegar al nivel umbral
+	DesbloqueoVictoriaTorneo = "victoria_torneo" // Al ganar umbral torneos
+)
+
+var TiposDesbloqueoAvatar = []string{DesbloqueoLibre, DesbloqueoMedalla, DesbloqueoPuntos,
+	DesbloqueoNivel, DesbloqueoVictoriaTorneo}
+
+// LogrosAvatar son los logros de un usuario que desbloquean artículos de avatar
+type LogrosAvatar struct {
+	Puntos         int
+	TorneosGanados int
+	Medallas       []string // IDs de las medallas ganadas
+}
+
+// InventarioAvatar es el catálogo de avatar visto por un usuario
+type InventarioAvatar struct {
+	Nivel  int                    `json:"nivel"`
+	Puntos int                    `json:"puntos"`
+	Items  []ItemInventarioAvatar `json:"items"`
+}
+
+type ItemInventarioAvatar struct {
+	AvatarItem
+	Desbloqueado   bool       `json:"desbloqueado"`
+	DesbloqueadoAt *time.Time `json:"desbloqueado_at,omitempty"` // Vacío en los artículos libres
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Tipos de geocerca de un torneo
+const (
+	GeocercaNinguna  = "ninguna"  // Se aceptan acciones desde cualquier lugar
+	GeocercaRadio    = "radio"    // Radio alrededor de la ubicación A (y B si existe)
+	GeocercaPoligono = "poligono" // Polígono definido por el organizador
+)
+
+// PuntoGeo es un vértice del polígono de una geocerca
+type PuntoGeo struct {
+	Latitud  float64 `json:"latitud"`
+	Longitud float64 `json:"longitud"`
+}
+
+// PoligonoGeo es la lista de vértices de una geocerca. Se guarda como JSONB
+type PoligonoGeo []PuntoGeo
+
+// Value implementa driver.Valuer para guardar el polígono como JSON
+func (p PoligonoGeo) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// Scan implementa sql.Scanner para leer el polígono desde JSONB
+func (p *PoligonoGeo) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("tipo no soportado para PoligonoGeo: %T", src)
+	}
+}
+
+// Geocerca es el área en la que las acciones puntúan para un torneo
+type Geocerca struct {
+	Tipo        string      `json:"tipo"`
+	RadioMetros *int        `json:"radio_metros,omitempty"`
+	PuntoA      PuntoGeo    `json:"punto_a"`
+	PuntoB      *PuntoGeo   `json:"punto_b,omitempty"`
+	Poligono    PoligonoGeo `json:"poligono,omitempty"`
+}
+
+// ResultadoTorneo explica si una acción puntuó para el torneo del usuario y por qué
+type ResultadoTorneo struct {
+	IDTorneo       string `json:"id_torneo"`
+	Puntua         bool   `json:"puntua"`
+	Puntos         int    `json:"puntos"`
+	BonoLugarNuevo int    `json:"bono_lugar_nuevo,omitempty"`
+	Motivo         string `json:"motivo,omitempty"`
+}
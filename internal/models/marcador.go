@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Tipos de evento del marcador en vivo de un torneo
+const (
+	EventoMarcador   = "marcador"   // Estado completo: clasificación de jugadores y totales por equipo
+	EventoAccion     = "accion"     // Un participante registró una acción para el torneo
+	EventoFinalizado = "finalizado" // El torneo terminó; el servidor cierra la conexión
+	EventoCancelado  = "cancelado"  // El torneo fue cancelado; el servidor cierra la conexión
+)
+
+// MarcadorTorneo es el estado del marcador en vivo de un torneo
+type MarcadorTorneo struct {
+	IDTorneo      string         `json:"id_torneo"`
+	Finalizado    bool           `json:"finalizado"`
+	Jugadores     []UserRanking  `json:"jugadores"`
+	Equipos       []TorneoEquipo `json:"equipos,omitempty"`
+	ActualizadoAt time.Time      `json:"actualizado_at"`
+}
+
+// AccionMarcador es una acción de un participante tal como se publica en el marcador
+type AccionMarcador struct {
+	IDAccion   string    `json:"id_accion"`
+	UserID     string    `json:"user_id"`
+	TipoAccion string    `json:"tipo_accion"`
+	Lugar      string    `json:"lugar"`
+	Puntua     bool      `json:"puntua"`
+	Puntos     int       `json:"puntos"`
+	Motivo     string    `json:"motivo,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
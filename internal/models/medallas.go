@@ -3,16 +3,146 @@ package models
 import "time"
 
 type Medalla struct {
-	ID                    string `json:"id"`
-	Nombre                string `json:"nombre"`
-	Descripcion           string `json:"descripcion"`
-	Dificultad            int    `json:"dificultad"`
-	RequiereAmistades     bool   `json:"requiere_amistades"`
-	RequierePuntos        bool   `json:"requiere_puntos"`
-	RequiereAcciones      bool   `json:"requiere_acciones"`
-	RequiereTorneos       bool   `json:"requiere_torneos"`
-	RequiereVictoriaTorneos bool `json:"requiere_victoria_torneos"`
-	NumeroRequerido       *int   `json:"numero_requerido,omitempty"`
+	ID                      string `json:"id"`
+	Nombre                  string `json:"nombre"`
+	Descripcion             string `json:"descripcion"`
+	Dificultad              int    `json:"dificultad"`
+	RequiereAmistades       bool   `json:"requiere_amistades"`
+	RequierePuntos          bool   `json:"requiere_puntos"`
+	RequiereAcciones        bool   `json:"requiere_acciones"`
+	RequiereTorneos         bool   `json:"requiere_torneos"`
+	RequiereVictoriaTorneos bool   `json:"requiere_victoria_torneos"`
+	NumeroRequerido         *int   `json:"numero_requerido,omitempty"`
+
+	// PoliticaRevocacion indica si la medalla se pierde al dejar de cumplir sus criterios
+	PoliticaRevocacion string `json:"politica_revocacion"`
+
+	Categoria  *string    `json:"categoria,omitempty"`
+	Orden      int        `json:"orden"`                 // Posición en el catálogo, de menor a mayor
+	Imagen     *string    `json:"imagen,omitempty"`      // URL del arte en el CDN
+	RetiradaAt *time.Time `json:"retirada_at,omitempty"` // Ya no se puede ganar
+	Rareza     float64    `json:"rareza"`                // % de usuarios activos que la tienen
+
+	// Medallas de tiempo limitado o de eventos: solo se ganan dentro de la ventana y, si
+	// se indican, participando en el torneo o con acciones en la ciudad durante la ventana
+	DisponibleDesde *time.Time `json:"disponible_desde,omitempty"`
+	DisponibleHasta *time.Time `json:"disponible_hasta,omitempty"`
+	IDTorneo        *string    `json:"id_torneo,omitempty"`
+	Ciudad          *string    `json:"ciudad,omitempty"`
+	EdicionLimitada bool       `json:"edicion_limitada"` // La ventana ya cerró
+
+	// Criterios para ganar la medalla. Se pueden enviar como regla (por ejemplo
+	// "amigos >= 10 and puntos >= 500") o como lista; sin ninguna de las dos se arman
+	// a partir de los indicadores requiere_* y numero_requerido
+	Regla     *string           `json:"regla,omitempty"`
+	Criterios []CriterioMedalla `json:"criterios"`
+}
+
+// Políticas de revocación de las medallas
+const (
+	PoliticaPermanente = "permanente" // Una vez ganada no se pierde
+	PoliticaRevocable  = "revocable"  // Se pierde si el usuario deja de cumplir los criterios
+)
+
+// CriterioMedalla compara una métrica del usuario con un umbral. Los criterios de un
+// mismo grupo deben cumplirse todos (AND) y basta con cumplir un grupo (OR)
+type CriterioMedalla struct {
+	Grupo    int    `json:"grupo"`
+	Metrica  string `json:"metrica"`
+	Operador string `json:"operador"` // >=, >, <=, < o =
+	Umbral   int    `json:"umbral"`
+}
+
+// Métricas de usuario que pueden usar los criterios de medalla
+const (
+	MetricaAmigos         = "amigos"
+	MetricaPuntos         = "puntos"
+	MetricaAcciones       = "acciones"
+	MetricaTorneos        = "torneos"
+	MetricaTorneosGanados = "torneos_ganados"
+	MetricaRachaDias      = "streak_days" // Racha más larga de días seguidos con acciones
+)
+
+// MetricasSimples son las métricas que una regla usa por su nombre
+var MetricasSimples = []string{MetricaAmigos, MetricaPuntos, MetricaAcciones, MetricaTorneos,
+	MetricaTorneosGanados, MetricaRachaDias}
+
+// Fuentes de las métricas count(...) de las reglas de medalla
+const (
+	FuenteAcciones = "actions"     // Acciones del usuario que no fueron borradas
+	FuenteTorneos  = "tournaments" // Torneos no cancelados en los que participó
+	FuenteAmigos   = "friends"     // Amistades aceptadas
+)
+
+// Operadores de comparación de los criterios de medalla
+var OperadoresCriterio = []string{">=", ">", "<=", "<", "="}
+
+// SimulacionMedalla indica qué usuarios ganarían una medalla con ciertos criterios
+type SimulacionMedalla struct {
+	Total    int                 `json:"total"`
+	Usuarios []UsuarioSimulacion `json:"usuarios"` // Muestra ordenada por ID
+}
+
+type UsuarioSimulacion struct {
+	UserID   string `json:"user_id"`
+	Nombre   string `json:"nombre"`
+	Apellido string `json:"apellido"`
+}
+
+// ProgresoMedalla es el avance de un usuario hacia una medalla. ValorActual y Objetivo
+// son los del criterio que más le falta en el grupo de criterios más avanzado
+type ProgresoMedalla struct {
+	IDMedalla   string             `json:"id_medalla"`
+	Nombre      string             `json:"nombre"`
+	Descripcion string             `json:"descripcion"`
+	Dificultad  int                `json:"dificultad"`
+	Ganada      bool               `json:"ganada"`
+	FechaGanada *time.Time         `json:"fecha_ganada,omitempty"`
+	Porcentaje  int                `json:"porcentaje"` // De 0 a 100
+	Metrica     string             `json:"metrica,omitempty"`
+	ValorActual int                `json:"valor_actual"`
+	Objetivo    int                `json:"objetivo"`
+	Criterios   []ProgresoCriterio `json:"criterios"`
+}
+
+type ProgresoCriterio struct {
+	CriterioMedalla
+	ValorActual int  `json:"valor_actual"`
+	Cumplido    bool `json:"cumplido"`
+	Porcentaje  int  `json:"porcentaje"`
+}
+
+// Estados del otorgamiento retroactivo de una medalla
+const (
+	EstadoBackfillPendiente  = "pendiente"
+	EstadoBackfillEnProceso  = "en_proceso"
+	EstadoBackfillCompletado = "completado"
+	EstadoBackfillCancelado  = "cancelado" // La medalla cambió antes de terminar
+	EstadoBackfillError      = "error"
+)
+
+// MedallaBackfill es el otorgamiento retroactivo de una medalla a los usuarios existentes
+type MedallaBackfill struct {
+	ID            string     `json:"id"`
+	IDMedalla     string     `json:"id_medalla"`
+	Estado        string     `json:"estado"`
+	TotalUsuarios int        `json:"total_usuarios"`
+	Procesados    int        `json:"procesados"`
+	Otorgadas     int        `json:"otorgadas"`
+	Error         *string    `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	IniciadoAt    *time.Time `json:"iniciado_at,omitempty"`
+	TerminadoAt   *time.Time `json:"terminado_at,omitempty"`
+}
+
+// MedallaRevocada es una medalla que el usuario perdió
+type MedallaRevocada struct {
+	ID            string    `json:"id"`
+	IDMedalla     string    `json:"id_medalla"`
+	Nombre        string    `json:"nombre"`
+	FechaGanada   time.Time `json:"fecha_ganada"`
+	FechaRevocada time.Time `json:"fecha_revocada"`
+	Motivo        string    `json:"motivo"`
 }
 
 type MedallaGanada struct {
@@ -20,4 +150,4 @@ type MedallaGanada struct {
 	IDUsuario   string    `json:"id_usuario"`
 	IDMedalla   string    `json:"id_medalla"`
 	FechaGanada time.Time `json:"fecha_ganada"`
-} 
\ No newline at end of file
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Tipos de notificación
+const (
+	NotificacionTorneoCancelado    = "torneo_cancelado"     // Se canceló un torneo en el que participaba o esperaba lugar
+	NotificacionMedallaRevocada    = "medalla_revocada"     // Perdió una medalla al dejar de cumplir sus requisitos
+	NotificacionListaEsperaVencida = "lista_espera_vencida" // Perdió su lugar en la lista de espera por entrar a otro torneo
+	NotificacionAccionAnulada      = "accion_anulada"       // Un organizador anuló una de sus acciones en un torneo
+)
+
+// Notificacion es un aviso para un usuario
+type Notificacion struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Tipo      string    `json:"tipo"`
+	Mensaje   string    `json:"mensaje"`
+	IDTorneo  *string   `json:"id_torneo,omitempty"`
+	Leida     bool      `json:"leida"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// RatingJugador es el rating de habilidad de un jugador. Es provisional mientras no
+// haya completado suficientes torneos, para distinguir a los nuevos de los veteranos
+type RatingJugador struct {
+	Posicion           int       `json:"posicion,omitempty"`
+	UserID             string    `json:"user_id"`
+	Nombre             string    `json:"nombre"`
+	Apellido           string    `json:"apellido"`
+	Rating             int       `json:"rating"`
+	TorneosCalificados int       `json:"torneos_calificados"`
+	Provisional        bool      `json:"provisional"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// HistorialRating es el cambio de rating de un jugador al finalizar un torneo
+type HistorialRating struct {
+	ID             string    `json:"id"`
+	IDTorneo       *string   `json:"id_torneo,omitempty"`
+	NombreTorneo   *string   `json:"nombre_torneo,omitempty"`
+	RatingAnterior int       `json:"rating_anterior"`
+	RatingNuevo    int       `json:"rating_nuevo"`
+	Posicion       int       `json:"posicion"`
+	Participantes  int       `json:"participantes"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Formas de sumar puntos en la clasificación de una temporada
+const (
+	PuntuacionTemporadaPuntos   = "puntos"   // Se suman los puntos de cada torneo
+	PuntuacionTemporadaPosicion = "posicion" // Se otorgan puntos según la posición final en cada torneo
+)
+
+// Temporada agrupa varios torneos en una liga con clasificación acumulada
+type Temporada struct {
+	ID          string    `json:"id"`
+	IDCreator   string    `json:"id_creator"`
+	Nombre      string    `json:"nombre"`
+	FechaInicio time.Time `json:"fecha_inicio"`
+	FechaFin    time.Time `json:"fecha_fin"`
+	Puntuacion  string    `json:"puntuacion"`
+	// Puntos por posición (el primer elemento corresponde al primer lugar)
+	PuntosPosicion []int64   `json:"puntos_posicion"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// RankingTemporada es la posición de un jugador en la clasificación de una temporada
+type RankingTemporada struct {
+	Posicion  int    `json:"posicion"`
+	UserID    string `json:"user_id"`
+	Nombre    string `json:"nombre"`
+	Apellido  string `json:"apellido"`
+	Puntos    int    `json:"puntos"`
+	Torneos   int    `json:"torneos"`
+	Victorias int    `json:"victorias"`
+}
+
+// PlantillaTorneo crea automáticamente un torneo cada vez que se cumple su recurrencia
+type PlantillaTorneo struct {
+	ID          string  `json:"id"`
+	IDCreator   string  `json:"id_creator"`
+	IDTemporada *string `json:"id_temporada,omitempty"`
+	Nombre      string  `json:"nombre"`
+	// Recurrencia en formato cron estándar de cinco campos (p. ej. "0 9 * * 6")
+	Recurrencia      string     `json:"recurrencia"`
+	DuracionMinutos  int        `json:"duracion_minutos"`
+	Torneo           Torneo     `json:"torneo"`
+	Activa           bool       `json:"activa"`
+	ProximaEjecucion time.Time  `json:"proxima_ejecucion"`
+	UltimaEjecucion  *time.Time `json:"ultima_ejecucion,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
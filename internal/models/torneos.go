@@ -3,38 +3,263 @@ package models
 import "time"
 
 type Torneo struct {
-	ID                  string    `json:"id"`
-	IDCreator           string    `json:"id_creator"`
-	Nombre              string    `json:"nombre"`
-	Modalidad           string    `json:"modalidad"`
-	UbicacionALatitud   float64   `json:"ubicacion_a_latitud"`
-	UbicacionALongitud  float64   `json:"ubicacion_a_longitud"`
-	NombreUbicacionA    string    `json:"nombre_ubicacion_a"`
-	UbicacionBLatitud   *float64  `json:"ubicacion_b_latitud,omitempty"`
-	UbicacionBLongitud  *float64  `json:"ubicacion_b_longitud,omitempty"`
-	NombreUbicacionB    *string   `json:"nombre_ubicacion_b,omitempty"`
-	FechaInicio         time.Time `json:"fecha_inicio"`
-	FechaFin            time.Time `json:"fecha_fin"`
-	UbicacionAproximada bool      `json:"ubicacion_aproximada"`
-	MetrosAprox         *int      `json:"metros_aproximados,omitempty"`
-	Finalizado          bool      `json:"finalizado"`
-	CodeID              string    `json:"code_id"`
-	GanadorVersus       *bool     `json:"ganador_versus,omitempty"`
-	GanadorIndividual   *string   `json:"ganador_individual,omitempty"`
+	ID                  string         `json:"id"`
+	IDCreator           string         `json:"id_creator"`
+	Nombre              string         `json:"nombre"`
+	Modalidad           string         `json:"modalidad"`
+	UbicacionALatitud   float64        `json:"ubicacion_a_latitud"`
+	UbicacionALongitud  float64        `json:"ubicacion_a_longitud"`
+	NombreUbicacionA    string         `json:"nombre_ubicacion_a"`
+	UbicacionBLatitud   *float64       `json:"ubicacion_b_latitud,omitempty"`
+	UbicacionBLongitud  *float64       `json:"ubicacion_b_longitud,omitempty"`
+	NombreUbicacionB    *string        `json:"nombre_ubicacion_b,omitempty"`
+	FechaInicio         time.Time      `json:"fecha_inicio"`
+	FechaFin            time.Time      `json:"fecha_fin"`
+	UbicacionAproximada bool           `json:"ubicacion_aproximada"`
+	MetrosAprox         *int           `json:"metros_aproximados,omitempty"`
+	Finalizado          bool           `json:"finalizado"`
+	CodeID              string         `json:"code_id"`
+	GanadorEquipo       *string        `json:"ganador_equipo,omitempty"`
+	GanadorIndividual   *string        `json:"ganador_individual,omitempty"`
+	Equipos             []TorneoEquipo `json:"equipos,omitempty"`
+	Visibilidad         string         `json:"visibilidad"` // publica, no_listada o privada
+	IDTemporada         *string        `json:"id_temporada,omitempty"`
+	IDPlantilla         *string        `json:"id_plantilla,omitempty"`
+
+	// Formato de competición. En eliminatoria la llave se arma al comenzar el torneo
+	Formato              string `json:"formato"` // clasico o eliminatoria
+	DuracionRondaMinutos *int   `json:"duracion_ronda_minutos,omitempty"`
+
+	// Un torneo cancelado queda finalizado, sin ganador y sin contar como participación
+	Cancelado         bool       `json:"cancelado"`
+	FechaCancelacion  *time.Time `json:"fecha_cancelacion,omitempty"`
+	MotivoCancelacion *string    `json:"motivo_cancelacion,omitempty"`
+
+	// Periodo de revisión tras la fecha de fin (nil: el ganador se decide al terminar).
+	// Mientras dura, los organizadores pueden anular acciones antes de cerrar el torneo
+	MinutosVerificacion *int       `json:"minutos_verificacion,omitempty"`
+	EnVerificacion      bool       `json:"en_verificacion"`
+	FinVerificacion     *time.Time `json:"fin_verificacion,omitempty"`
+
+	// Reglas de equipos en modalidad Versus
+	AsignacionEquipos        string `json:"asignacion_equipos"` // libre, cantidad, puntos o rating
+	EquiposBloqueados        bool   `json:"equipos_bloqueados"`
+	PermitirCambioEquipo     bool   `json:"permitir_cambio_equipo"`
+	CambioRequiereAprobacion bool   `json:"cambio_requiere_aprobacion"`
+
+	// Capacidad e inscripciones (nil significa sin límite)
+	MaxParticipantes       *int       `json:"max_participantes,omitempty"`
+	MaxPorEquipo           *int       `json:"max_por_equipo,omitempty"`
+	FechaCierreInscripcion *time.Time `json:"fecha_cierre_inscripcion,omitempty"`
+
+	// Área en la que las acciones puntúan para el torneo
+	GeocercaTipo        string      `json:"geocerca_tipo"` // ninguna, radio o poligono
+	GeocercaRadioMetros *int        `json:"geocerca_radio_metros,omitempty"`
+	GeocercaPoligono    PoligonoGeo `json:"geocerca_poligono,omitempty"`
 }
 
-type TorneoEstadisticas struct {
-	ID         string `json:"id"`
-	IDJugador  string `json:"id_jugador"`
-	Equipo     bool   `json:"equipo"`
-	IDTorneo   string `json:"id_torneo"`
-	Modalidad  string `json:"modalidad"`
+// Visibilidad de un torneo
+const (
+	VisibilidadPublica   = "publica"    // Aparece en los listados y en la búsqueda de torneos cercanos
+	VisibilidadNoListada = "no_listada" // Solo se encuentra con el código o el ID
+	VisibilidadPrivada   = "privada"    // Solo se puede entrar con invitación
+)
+
+// Formatos de competición de un torneo
+const (
+	FormatoClasico      = "clasico"      // Gana quien más puntos suma hasta la fecha de fin
+	FormatoEliminatoria = "eliminatoria" // Enfrentamientos directos por rondas de duración fija
+)
+
+// Estados de un enfrentamiento de la llave
+const (
+	EnfrentamientoPendiente  = "pendiente"
+	EnfrentamientoEnCurso    = "en_curso"
+	EnfrentamientoFinalizado = "finalizado"
+)
+
+// CompetidorLlave es un jugador (Individual) o un equipo (Versus) dentro de la llave
+type CompetidorLlave struct {
+	ID      string `json:"id"`
+	Nombre  string `json:"nombre"`
+	Semilla int    `json:"semilla"`
+	Puntos  int    `json:"puntos"`
+}
+
+// EnfrentamientoLlave es un enfrentamiento directo de una ronda. Sin competidor B es un
+// pase directo. El ganador pasa al enfrentamiento Posicion/2 de la ronda siguiente
+type EnfrentamientoLlave struct {
+	ID          string           `json:"id"`
+	Ronda       int              `json:"ronda"`
+	Posicion    int              `json:"posicion"`
+	CompetidorA CompetidorLlave  `json:"competidor_a"`
+	CompetidorB *CompetidorLlave `json:"competidor_b,omitempty"`
+	IDGanador   *string          `json:"id_ganador,omitempty"`
+	Estado      string           `json:"estado"`
+	Inicio      time.Time        `json:"inicio"`
+	Fin         time.Time        `json:"fin"`
+}
+
+// RondaLlave agrupa los enfrentamientos de una ronda
+type RondaLlave struct {
+	Ronda           int                   `json:"ronda"`
+	Enfrentamientos []EnfrentamientoLlave `json:"enfrentamientos"`
+}
+
+// LlaveTorneo es el árbol de enfrentamientos de un torneo eliminatoria. Solo contiene
+// las rondas ya armadas; TotalRondas permite dibujar las que faltan
+type LlaveTorneo struct {
+	IDTorneo    string       `json:"id_torneo"`
+	TotalRondas int          `json:"total_rondas"`
+	RondaActual int          `json:"ronda_actual"`
+	IDCampeon   *string      `json:"id_campeon,omitempty"`
+	Rondas      []RondaLlave `json:"rondas"`
+}
+
+// TorneoCercano es un torneo público encontrado cerca de una coordenada
+type TorneoCercano struct {
+	Torneo
+	DistanciaKm float64 `json:"distancia_km"`
+}
+
+// Estados posibles al inscribirse en un torneo
+const (
+	InscripcionInscrito    = "inscrito"
+	InscripcionListaEspera = "lista_espera"
+)
+
+// Inscripcion es el resultado de intentar inscribirse en un torneo: el jugador queda
+// inscrito o, si el torneo o su equipo están llenos, en la lista de espera
+type Inscripcion struct {
+	IDTorneo       string  `json:"id_torneo"`
+	Estado         string  `json:"estado"`
+	IDEquipo       *string `json:"id_equipo,omitempty"`
+	PosicionEspera *int    `json:"posicion_espera,omitempty"`
+}
+
+// ListaEspera representa a un jugador en la lista de espera de un torneo
+type ListaEspera struct {
+	IDJugador string    `json:"id_jugador"`
+	IDEquipo  *string   `json:"id_equipo,omitempty"`
+	Posicion  int       `json:"posicion"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Modos de asignación de equipos al inscribirse en un torneo Versus
+const (
+	AsignacionEquiposLibre    = "libre"    // El jugador elige su equipo
+	AsignacionEquiposCantidad = "cantidad" // Se asigna el equipo con menos integrantes
+	AsignacionEquiposPuntos   = "puntos"   // Se equilibra el promedio de puntos históricos de los equipos
+	AsignacionEquiposRating   = "rating"   // Se equilibra el promedio de rating de habilidad de los equipos
+)
+
+// InvitacionTorneo es la invitación de un organizador o participante a un amigo
+type InvitacionTorneo struct {
+	ID              string     `json:"id"`
+	IDTorneo        string     `json:"id_torneo"`
+	NombreTorneo    string     `json:"nombre_torneo"`
+	IDInvitador     string     `json:"id_invitador"`
+	NombreInvitador string     `json:"nombre_invitador"`
+	IDInvitado      string     `json:"id_invitado"`
+	IDEquipo        *string    `json:"id_equipo,omitempty"`
+	Estado          string     `json:"estado"`
+	CreatedAt       time.Time  `json:"created_at"`
+	RespondidaAt    *time.Time `json:"respondida_at,omitempty"`
+}
+
+// ReglaAccionTorneo son los puntos que otorga un tipo de acción en un torneo
+type ReglaAccionTorneo struct {
+	TipoAccion string `json:"tipo_accion"`
 	Puntos     int    `json:"puntos"`
 	Habilitado bool   `json:"habilitado"`
 }
 
+// ReglasPuntuacion son las reglas con las que un torneo puntúa las acciones
+type ReglasPuntuacion struct {
+	IDTorneo         string              `json:"id_torneo"`
+	Acciones         []ReglaAccionTorneo `json:"acciones"`
+	MaxPuntosDiarios *int                `json:"max_puntos_diarios,omitempty"`
+	BonoLugarNuevo   int                 `json:"bono_lugar_nuevo"`
+}
+
+// CambioEquipo representa una solicitud de un jugador para cambiarse de equipo
+type CambioEquipo struct {
+	ID              string     `json:"id"`
+	IDTorneo        string     `json:"id_torneo"`
+	IDJugador       string     `json:"id_jugador"`
+	IDEquipoOrigen  *string    `json:"id_equipo_origen,omitempty"`
+	IDEquipoDestino string     `json:"id_equipo_destino"`
+	Estado          string     `json:"estado"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ResueltoAt      *time.Time `json:"resuelto_at,omitempty"`
+}
+
+// TorneoEquipo representa un equipo dentro de un torneo en modalidad Versus
+type TorneoEquipo struct {
+	ID          string  `json:"id"`
+	IDTorneo    string  `json:"id_torneo"`
+	Nombre      string  `json:"nombre"`
+	Color       string  `json:"color"`
+	IDCapitan   *string `json:"id_capitan,omitempty"`
+	Orden       int     `json:"orden"`
+	Integrantes int     `json:"integrantes"`
+	Puntos      int     `json:"puntos"`
+}
+
+type TorneoEstadisticas struct {
+	ID                  string  `json:"id"`
+	IDJugador           string  `json:"id_jugador"`
+	IDEquipo            *string `json:"id_equipo,omitempty"`
+	IDTorneo            string  `json:"id_torneo"`
+	Modalidad           string  `json:"modalidad"`
+	Puntos              int     `json:"puntos"`
+	Habilitado          bool    `json:"habilitado"`
+	MotivoDeshabilitado *string `json:"motivo_deshabilitado,omitempty"`
+}
+
+// Roles de los organizadores de un torneo
+const (
+	RolDueno         = "dueno"         // Administra el torneo y su equipo de organizadores
+	RolCoorganizador = "coorganizador" // Administra el torneo salvo transferirlo, borrarlo y gestionar organizadores
+)
+
+// StaffTorneo es un organizador de un torneo
+type StaffTorneo struct {
+	IDTorneo  string    `json:"id_torneo"`
+	UserID    string    `json:"user_id"`
+	Nombre    string    `json:"nombre"`
+	Apellido  string    `json:"apellido"`
+	Rol       string    `json:"rol"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SancionTorneo registra una acción de un organizador sobre un participante
+// (deshabilitar o rehabilitar su puntuación, expulsarlo o banearlo)
+type SancionTorneo struct {
+	ID            string     `json:"id"`
+	IDTorneo      string     `json:"id_torneo"`
+	IDJugador     string     `json:"id_jugador"`
+	IDOrganizador string     `json:"id_organizador"`
+	Tipo          string     `json:"tipo"`
+	Motivo        string     `json:"motivo"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LevantadoAt   *time.Time `json:"levantado_at,omitempty"`
+}
+
+// EstadoParticipante es la vista del jugador sobre su participación en un torneo,
+// incluyendo los motivos de cualquier sanción aplicada por el organizador
+type EstadoParticipante struct {
+	IDTorneo            string          `json:"id_torneo"`
+	IDJugador           string          `json:"id_jugador"`
+	Inscrito            bool            `json:"inscrito"`
+	Habilitado          bool            `json:"habilitado"`
+	MotivoDeshabilitado *string         `json:"motivo_deshabilitado,omitempty"`
+	Baneado             bool            `json:"baneado"`
+	Sanciones           []SancionTorneo `json:"sanciones"`
+}
+
 // TorneoResumen representa un resumen de un torneo para listar en la interfaz de usuario
 type TorneoResumen struct {
-	ID        string `json:"id"`
-	Nombre    string `json:"nombre"`
+	ID     string `json:"id"`
+	Nombre string `json:"nombre"`
 }
@@ -15,22 +15,39 @@ type UserAction struct {
 	Colaboradores  *[]string  `json:"colaboradores,omitempty"`
 	EsParaTorneo   bool       `json:"es_para_torneo"`
 	IDTorneo       *string    `json:"id_torneo,omitempty"`
+	PuntosTorneo   int        `json:"puntos_torneo"`
 	CreatedAt      time.Time  `json:"created_at"`
 	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+
+	// Anulación por un organizador del torneo: puntos_torneo pasa a cero y lo que
+	// había sumado queda en puntos_anulados
+	AnuladaAt       *time.Time `json:"anulada_at,omitempty"`
+	AnuladaPor      *string    `json:"anulada_por,omitempty"`
+	MotivoAnulacion *string    `json:"motivo_anulacion,omitempty"`
+	PuntosAnulados  int        `json:"puntos_anulados,omitempty"`
+
+	// Resultado de la acción en el torneo del usuario (solo en la respuesta de creación)
+	ResultadoTorneo *ResultadoTorneo `json:"resultado_torneo,omitempty"`
 }
 
+// TiposAccion son los tipos de acción que se pueden registrar
+var TiposAccion = []string{"ayuda", "alerta", "descubrimiento"}
+
 type UserRanking struct {
-	UserID           string `json:"user_id"`
-	Puntos           int    `json:"puntos"`
-	Acciones         int    `json:"acciones"`
-	TorneosGanados   int    `json:"torneos_ganados"`
-	CantidadAmigos   int    `json:"cantidad_amigos"`
-	Slogan           string `json:"slogan"`
-	Cabello          string `json:"cabello"`
-	Vestimenta       string `json:"vestimenta"`
-	Barba            string `json:"barba"`
-	DetalleFacial    string `json:"detalle_facial"`
-	DetalleAdicional string `json:"detalle_adicional"`
-	Nombre           string `json:"nombre"`
-	Apellido         string `json:"apellido"`
+	UserID           string  `json:"user_id"`
+	Puntos           int     `json:"puntos"`
+	Acciones         int     `json:"acciones"`
+	TorneosGanados   int     `json:"torneos_ganados"`
+	CantidadAmigos   int     `json:"cantidad_amigos"`
+	Slogan           string  `json:"slogan"`
+	Cabello          string  `json:"cabello"`
+	Vestimenta       string  `json:"vestimenta"`
+	Barba            string  `json:"barba"`
+	DetalleFacial    string  `json:"detalle_facial"`
+	DetalleAdicional string  `json:"detalle_adicional"`
+	Nombre           string  `json:"nombre"`
+	Apellido         string  `json:"apellido"`
+	IDEquipo         *string `json:"id_equipo,omitempty"`
+	NombreEquipo     *string `json:"nombre_equipo,omitempty"`
+	ColorEquipo      *string `json:"color_equipo,omitempty"`
 }
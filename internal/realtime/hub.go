@@ -0,0 +1,105 @@
+package realtime
+
+import "sync"
+
+// Cantidad de eventos que puede acumular un suscriptor antes de considerarse lento
+const bufferSuscriptor = 16
+
+// Evento es un mensaje para los suscriptores de un torneo. El ID crece con cada
+// evento publicado en el torneo
+type Evento struct {
+	ID    int64
+	Tipo  string
+	Datos interface{}
+}
+
+// Hub reparte en memoria los eventos de cada torneo entre sus suscriptores. Un
+// suscriptor que no consume a tiempo se desconecta (se cierra su canal) para que
+// vuelva a conectarse y reciba el estado completo
+type Hub struct {
+	mu           sync.Mutex
+	suscriptores map[string]map[chan Evento]struct{}
+	ultimoID     map[string]int64
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		suscriptores: map[string]map[chan Evento]struct{}{},
+		ultimoID:     map[string]int64{},
+	}
+}
+
+// Suscribir registra un suscriptor del torneo. Devuelve el canal de eventos y la
+// función para darse de baja, que se debe llamar al desconectarse
+func (h *Hub) Suscribir(torneoID string) (<-chan Evento, func()) {
+	canal := make(chan Evento, bufferSuscriptor)
+
+	h.mu.Lock()
+	if h.suscriptores[torneoID] == nil {
+		h.suscriptores[torneoID] = map[chan Evento]struct{}{}
+	}
+	h.suscriptores[torneoID][canal] = struct{}{}
+	h.mu.Unlock()
+
+	return canal, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.quitar(torneoID, canal)
+	}
+}
+
+// Suscriptores indica cuántos suscriptores tiene el torneo
+func (h *Hub) Suscriptores(torneoID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.suscriptores[torneoID])
+}
+
+// UltimoID devuelve el ID del último evento publicado en el torneo
+func (h *Hub) UltimoID(torneoID string) int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.ultimoID[torneoID]
+}
+
+// Publicar envía un evento a todos los suscriptores del torneo sin bloquearse
+func (h *Hub) Publicar(torneoID string, tipo string, datos interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.ultimoID[torneoID]++
+	evento := Evento{ID: h.ultimoID[torneoID], Tipo: tipo, Datos: datos}
+
+	for canal := range h.suscriptores[torneoID] {
+		select {
+		case canal <- evento:
+		default:
+			h.quitar(torneoID, canal)
+		}
+	}
+}
+
+// Cerrar desconecta a todos los suscriptores del torneo, por ejemplo cuando finaliza
+func (h *Hub) Cerrar(torneoID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for canal := range h.suscriptores[torneoID] {
+		h.quitar(torneoID, canal)
+	}
+	delete(h.ultimoID, torneoID)
+}
+
+// quitar da de baja un suscriptor y cierra su canal. Se llama con el mutex tomado
+func (h *Hub) quitar(torneoID string, canal chan Evento) {
+	canales := h.suscriptores[torneoID]
+	if _, ok := canales[canal]; !ok {
+		return
+	}
+
+	delete(canales, canal)
+	close(canal)
+	if len(canales) == 0 {
+		delete(h.suscriptores, torneoID)
+	}
+}
@@ -0,0 +1,255 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/pkg/database"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var ErrBackfillNoEncontrado = errors.New("la medalla no tiene otorgamientos retroactivos")
+
+// tamanoLoteBackfill es la cantidad de usuarios que se evalúan en cada transacción, para
+// que ninguna retenga bloqueos mucho tiempo
+const tamanoLoteBackfill = 1000
+
+// encolarBackfill cancela el otorgamiento retroactivo que la medalla tuviera pendiente y
+// encola uno nuevo con sus criterios actuales
+func encolarBackfill(tx *sql.Tx, medallaID string) (*models.MedallaBackfill, error) {
+	_, err := tx.Exec(`
+		UPDATE medalla_backfills
+		SET estado = $2, terminado_at = NOW()
+		WHERE id_medalla = $1 AND estado IN ($3, $4)`,
+		medallaID, models.EstadoBackfillCancelado, models.EstadoBackfillPendiente, models.EstadoBackfillEnProceso)
+	if err != nil {
+		return nil, fmt.Errorf("error al cancelar el otorgamiento anterior: %w", err)
+	}
+
+	backfill := &models.MedallaBackfill{IDMedalla: medallaID, Estado: models.EstadoBackfillPendiente}
+	err = tx.QueryRow(`
+		INSERT INTO medalla_backfills (id_medalla, estado)
+		VALUES ($1, $2)
+		RETURNING id, created_at`, medallaID, backfill.Estado).Scan(&backfill.ID, &backfill.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error al encolar el otorgamiento retroactivo: %w", err)
+	}
+
+	return backfill, nil
+}
+
+// IniciarBackfill encola de nuevo el otorgamiento retroactivo de una medalla, por
+// ejemplo después de que uno terminara con error
+func (r *MedallasRepository) IniciarBackfill(medallaID string) (*models.MedallaBackfill, error) {
+	var backfill *models.MedallaBackfill
+	err := database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var retirada bool
+		err := tx.QueryRow(`SELECT retirada_at IS NOT NULL FROM medallas WHERE id = $1`, medallaID).Scan(&retirada)
+		if err == sql.ErrNoRows {
+			return ErrMedallaNoEncontrada
+		}
+		if err != nil {
+			return fmt.Errorf("error al verificar la medalla: %w", err)
+		}
+		if retirada {
+			return ErrMedallaRetirada
+		}
+
+		backfill, err = encolarBackfill(tx, medallaID)
+		return err
+	})
+
+	return backfill, err
+}
+
+// GetBackfill devuelve el último otorgamiento retroactivo de la medalla con su avance
+func (r *MedallasRepository) GetBackfill(medallaID string) (*models.MedallaBackfill, error) {
+	var b models.MedallaBackfill
+	err := r.db.QueryRow(`
+		SELECT id, id_medalla, estado, total_usuarios, procesados, otorgadas, error,
+			created_at, iniciado_at, terminado_at
+		FROM medalla_backfills
+		WHERE id_medalla = $1
+		ORDER BY created_at DESC
+		LIMIT 1`, medallaID).Scan(
+		&b.ID, &b.IDMedalla, &b.Estado, &b.TotalUsuarios, &b.Procesados, &b.Otorgadas, &b.Error,
+		&b.CreatedAt, &b.IniciadoAt, &b.TerminadoAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrBackfillNoEncontrado
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el otorgamiento retroactivo: %w", err)
+	}
+
+	return &b, nil
+}
+
+// ProcesarBackfills avanza los otorgamientos retroactivos pendientes, hasta maxLotes
+// lotes de usuarios. Cada lote va en su propia transacción y toma el otorgamiento con
+// SKIP LOCKED, así que varias ejecuciones a la vez no se estorban
+func (r *MedallasRepository) ProcesarBackfills(maxLotes int) error {
+	for i := 0; i < maxLotes; i++ {
+		avanzo, err := r.procesarLoteBackfill()
+		if err != nil || !avanzo {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// procesarLoteBackfill evalúa el siguiente lote de usuarios del otorgamiento más antiguo.
+// Devuelve false si no había nada que procesar
+func (r *MedallasRepository) procesarLoteBackfill() (bool, error) {
+	var backfillID string
+	err := database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var medallaID, estado string
+		var ultimoUsuario sql.NullString
+		// Las medallas cuya ventana todavía no abre esperan en la cola
+		err := tx.QueryRow(`
+			SELECT b.id, b.id_medalla, b.estado, b.ultimo_usuario
+			FROM medalla_backfills b
+			JOIN medallas m ON m.id = b.id_medalla
+			WHERE b.estado IN ($1, $2)
+			AND (m.disponible_desde IS NULL OR m.disponible_desde <= NOW())
+			ORDER BY b.created_at
+			LIMIT 1
+			FOR UPDATE OF b SKIP LOCKED`,
+			models.EstadoBackfillPendiente, models.EstadoBackfillEnProceso,
+		).Scan(&backfillID, &medallaID, &estado, &ultimoUsuario)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error al obtener el otorgamiento pendiente: %w", err)
+		}
+
+		if estado == models.EstadoBackfillPendiente {
+			_, err := tx.Exec(`
+				UPDATE medalla_backfills
+				SET estado = $2, iniciado_at = NOW(), total_usuarios = (SELECT COUNT(*) FROM user_access)
+				WHERE id = $1`, backfillID, models.EstadoBackfillEnProceso)
+			if err != nil {
+				return fmt.Errorf("error al iniciar el otorgamiento retroactivo: %w", err)
+			}
+		}
+
+		criterios, err := cargarCriterios(tx, []string{medallaID})
+		if err != nil {
+			return err
+		}
+
+		// Una medalla sin criterios solo se asigna a mano
+		if len(criterios[medallaID]) == 0 {
+			_, err := tx.Exec(`
+				UPDATE medalla_backfills
+				SET estado = $2, procesados = total_usuarios, terminado_at = NOW()
+				WHERE id = $1`, backfillID, models.EstadoBackfillCompletado)
+			return err
+		}
+
+		lote, err := listarIDs(tx, `
+			SELECT id FROM user_access
+			WHERE $1::uuid IS NULL OR id > $1::uuid
+			ORDER BY id
+			LIMIT $2`, ultimoUsuario, tamanoLoteBackfill)
+		if err != nil {
+			return fmt.Errorf("error al obtener el lote de usuarios: %w", err)
+		}
+
+		// Se toman los mismos bloqueos que al otorgar medallas a un usuario, en el mismo
+		// orden, para no cruzarse con AutoAsignMedallas ni con las revocaciones
+		if err := bloquearMedallasUsuarios(tx, lote); err != nil {
+			return err
+		}
+
+		params := &parametrosRegla{}
+		medalla := params.agregar(medallaID)
+		usuarios := params.agregar(pq.Array(lote))
+		condicion, err := condicionCriterios(criterios[medallaID], params, r.zonaHoraria)
+		if err != nil {
+			return err
+		}
+
+		// Las medallas nuevas, el aviso en pending_medalla y los artículos de avatar de la
+		// medalla se guardan en la misma consulta.
+		// RETURNING solo devuelve las filas insertadas, así que el contador no se infla
+		var otorgadas int
+		err = tx.QueryRow(`
+			WITH nuevas AS (
+				INSERT INTO medallas_ganadas (id_usuario, id_medalla, fecha_ganada)
+				SELECT u.id, m.id, NOW()
+				FROM unnest(`+usuarios+`::uuid[]) AS u(id)
+				JOIN medallas m ON m.id = `+medalla+`::uuid
+				WHERE `+condicionMedallaDisponible+`
+				AND NOT EXISTS (
+					SELECT 1 FROM medallas_ganadas mg
+					WHERE mg.id_usuario = u.id AND mg.id_medalla = `+medalla+`::uuid
+				)
+				AND `+condicion+`
+				ON CONFLICT (id_usuario, id_medalla) DO NOTHING
+				RETURNING id_usuario
+			), avisos AS (
+				UPDATE user_stats SET pending_medalla = pending_medalla + 1
+				WHERE user_id IN (SELECT id_usuario FROM nuevas)
+			), items AS (
+				INSERT INTO user_avatar_items (id_usuario, id_item)
+				SELECT n.id_usuario, ai.id
+				FROM nuevas n
+				JOIN avatar_items ai ON ai.id_medalla = `+medalla+`::uuid AND ai.tipo_desbloqueo = 'medalla'
+				ON CONFLICT (id_usuario, id_item) DO NOTHING
+			)
+			SELECT COUNT(*) FROM nuevas`, params.args...,
+		).Scan(&otorgadas)
+		if err != nil {
+			return fmt.Errorf("error al evaluar el lote de usuarios: %w", err)
+		}
+
+		var ultimoLote *string
+		if len(lote) > 0 {
+			ultimoLote = &lote[len(lote)-1]
+		}
+
+		_, err = tx.Exec(`
+			UPDATE medalla_backfills
+			SET procesados = procesados + $2,
+				otorgadas = otorgadas + $3,
+				ultimo_usuario = COALESCE($4, ultimo_usuario),
+				estado = CASE WHEN $5 THEN $6 ELSE estado END,
+				terminado_at = CASE WHEN $5 THEN NOW() ELSE terminado_at END
+			WHERE id = $1`,
+			backfillID, len(lote), otorgadas, ultimoLote,
+			len(lote) < tamanoLoteBackfill, models.EstadoBackfillCompletado)
+		if err != nil {
+			return fmt.Errorf("error al guardar el avance del otorgamiento: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil && backfillID != "" && !errorTransitorio(err) {
+		// Se marca fuera de la transacción fallida para que no se reintente en cada ejecución.
+		// Los interbloqueos y fallos de serialización se reintentan en la siguiente
+		_, errEstado := r.db.Exec(`
+			UPDATE medalla_backfills SET estado = $2, error = $3, terminado_at = NOW()
+			WHERE id = $1`, backfillID, models.EstadoBackfillError, err.Error())
+		if errEstado != nil {
+			return false, fmt.Errorf("%w (además no se pudo marcar el error: %v)", err, errEstado)
+		}
+	}
+
+	return backfillID != "" && err == nil, err
+}
+
+// errorTransitorio indica si el error es un interbloqueo o un fallo de serialización de
+// Postgres, que se resuelven al volver a intentar la transacción
+func errorTransitorio(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40P01" || pqErr.Code == "40001"
+}
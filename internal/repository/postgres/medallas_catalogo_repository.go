@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/pkg/database"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMedallaNoEncontrada = errors.New("la medalla no existe")
+	ErrMedallaRetirada     = errors.New("la medalla está retirada")
+)
+
+// GetMedalla devuelve una medalla del catálogo con sus criterios, aunque esté retirada
+func (r *MedallasRepository) GetMedalla(medallaID string) (*models.Medalla, error) {
+	var m models.Medalla
+	err := r.db.QueryRow(`
+		SELECT `+columnasMedalla+`
+		FROM medallas m
+		WHERE m.id = $1`, medallaID).Scan(destinosMedalla(&m)...)
+	if err == sql.ErrNoRows {
+		return nil, ErrMedallaNoEncontrada
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la medalla: %w", err)
+	}
+
+	criterios, err := cargarCriterios(r.db, []string{medallaID})
+	if err != nil {
+		return nil, err
+	}
+	m.Criterios = criterios[medallaID]
+
+	return &m, nil
+}
+
+// UpdateMedalla guarda los cambios de una medalla. Si cambiaron los criterios se
+// reemplazan; con reotorgar se encola su otorgamiento retroactivo
+func (r *MedallasRepository) UpdateMedalla(medalla *models.Medalla, cambioCriterios, reotorgar bool) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE medallas
+			SET nombre = $2, descripcion = $3, dificultad = $4, requiere_amistades = $5,
+				requiere_puntos = $6, requiere_acciones = $7, requiere_torneos = $8,
+				requiere_victoria_torneos = $9, numero_requerido = $10, regla = $11,
+				politica_revocacion = $12, categoria = $13, orden = $14, disponible_desde = $15,
+				disponible_hasta = $16, id_torneo = $17, ciudad = $18
+			WHERE id = $1 AND retirada_at IS NULL`,
+			medalla.ID, medalla.Nombre, medalla.Descripcion, medalla.Dificultad, medalla.RequiereAmistades,
+			medalla.RequierePuntos, medalla.RequiereAcciones, medalla.RequiereTorneos,
+			medalla.RequiereVictoriaTorneos, medalla.NumeroRequerido, medalla.Regla,
+			medalla.PoliticaRevocacion, medalla.Categoria, medalla.Orden, medalla.DisponibleDesde,
+			medalla.DisponibleHasta, medalla.IDTorneo, medalla.Ciudad)
+		if err != nil {
+			return fmt.Errorf("error al actualizar la medalla: %w", err)
+		}
+
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrMedallaRetirada
+		}
+
+		if cambioCriterios {
+			if len(medalla.Criterios) == 0 {
+				medalla.Criterios = criteriosPorIndicadores(medalla)
+			}
+
+			if _, err := tx.Exec(`DELETE FROM medalla_criterios WHERE id_medalla = $1`, medalla.ID); err != nil {
+				return fmt.Errorf("error al reemplazar los criterios de la medalla: %w", err)
+			}
+
+			if err := insertarCriterios(tx, medalla.ID, medalla.Criterios); err != nil {
+				return err
+			}
+		}
+
+		if !reotorgar {
+			return nil
+		}
+
+		_, err = encolarBackfill(tx, medalla.ID)
+		return err
+	})
+}
+
+// RetirarMedalla saca la medalla del catálogo: ya no se puede ganar ni se revoca, y
+// quienes la tienen la conservan. Se cancela su otorgamiento retroactivo pendiente
+func (r *MedallasRepository) RetirarMedalla(medallaID string) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var retirada sql.NullTime
+		err := tx.QueryRow(`
+			SELECT retirada_at FROM medallas WHERE id = $1 FOR UPDATE`, medallaID).Scan(&retirada)
+		if err == sql.ErrNoRows {
+			return ErrMedallaNoEncontrada
+		}
+		if err != nil {
+			return fmt.Errorf("error al obtener la medalla: %w", err)
+		}
+		if retirada.Valid {
+			return ErrMedallaRetirada
+		}
+
+		if _, err := tx.Exec(`UPDATE medallas SET retirada_at = NOW() WHERE id = $1`, medallaID); err != nil {
+			return fmt.Errorf("error al retirar la medalla: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE medalla_backfills
+			SET estado = $2, terminado_at = NOW()
+			WHERE id_medalla = $1 AND estado IN ($3, $4)`,
+			medallaID, models.EstadoBackfillCancelado, models.EstadoBackfillPendiente, models.EstadoBackfillEnProceso)
+		if err != nil {
+			return fmt.Errorf("error al cancelar el otorgamiento de la medalla: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// CambiarImagenMedalla guarda la URL del arte de la medalla y devuelve la anterior para
+// que se borre del almacenamiento
+func (r *MedallasRepository) CambiarImagenMedalla(medallaID, imagen string) (*string, error) {
+	var anterior *string
+	err := database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(`SELECT imagen FROM medallas WHERE id = $1 FOR UPDATE`, medallaID).Scan(&anterior)
+		if err == sql.ErrNoRows {
+			return ErrMedallaNoEncontrada
+		}
+		if err != nil {
+			return fmt.Errorf("error al obtener la medalla: %w", err)
+		}
+
+		_, err = tx.Exec(`UPDATE medallas SET imagen = $2 WHERE id = $1`, medallaID, imagen)
+		if err != nil {
+			return fmt.Errorf("error al guardar la imagen de la medalla: %w", err)
+		}
+		return nil
+	})
+
+	return anterior, err
+}
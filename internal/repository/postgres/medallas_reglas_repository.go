@@ -0,0 +1,256 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/internal/utils"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Las métricas de las reglas se traducen a subconsultas correlacionadas sobre u.id,
+// el usuario evaluado. Los valores de las condiciones siempre van como parámetros
+
+// consultasMetricasSimples son las subconsultas de las métricas que se usan por nombre
+var consultasMetricasSimples = map[string]string{
+	models.MetricaAmigos:         `COALESCE((SELECT cantidad_amigos FROM user_stats WHERE user_id = u.id), 0)`,
+	models.MetricaPuntos:         `COALESCE((SELECT puntos FROM user_stats WHERE user_id = u.id), 0)`,
+	models.MetricaAcciones:       `COALESCE((SELECT acciones FROM user_stats WHERE user_id = u.id), 0)`,
+	models.MetricaTorneos:        `COALESCE((SELECT torneos_participados FROM user_stats WHERE user_id = u.id), 0)`,
+	models.MetricaTorneosGanados: `COALESCE((SELECT torneos_ganados FROM user_stats WHERE user_id = u.id), 0)`,
+}
+
+// consultasFuentes son los conteos base de cada fuente de count(...)
+var consultasFuentes = map[string]string{
+	models.FuenteAcciones: `
+		SELECT COUNT(*) FROM user_actions a
+		WHERE a.user_id = u.id AND a.deleted_at IS NULL`,
+	models.FuenteTorneos: `
+		SELECT COUNT(*) FROM torneo_estadisticas te
+		JOIN torneos t ON t.id = te.id_torneo
+		WHERE te.id_jugador = u.id AND t.cancelado = false`,
+	models.FuenteAmigos: `
+		SELECT COUNT(*) FROM user_friends f
+		WHERE (f.user_id = u.id OR f.friend_id = u.id)
+		AND f.deleted_at IS NULL AND f.pending_id IS NULL`,
+}
+
+// columnasFuentes son las expresiones SQL de los campos filtrables de cada fuente
+var columnasFuentes = map[string]map[string]string{
+	models.FuenteAcciones: {
+		"tipo":            "a.tipo_accion",
+		"ciudad":          "a.ciudad",
+		"lugar":           "a.lugar",
+		"en_colaboracion": "a.en_colaboracion",
+		"es_para_torneo":  "a.es_para_torneo",
+	},
+	models.FuenteTorneos: {
+		"modalidad":  "t.modalidad",
+		"formato":    "t.formato",
+		"finalizado": "t.finalizado",
+		"ganado":     "COALESCE(t.ganador_individual = te.id_jugador OR t.ganador_equipo = te.id_equipo, false)",
+		"puntos":     "te.puntos",
+	},
+	models.FuenteAmigos: {},
+}
+
+// operadoresSQL traduce los operadores de las reglas a SQL
+var operadoresSQL = map[string]string{
+	">=": ">=", ">": ">", "<=": "<=", "<": "<", "=": "=", "!=": "<>",
+}
+
+// parametrosRegla acumula los argumentos de una consulta armada con reglas
+type parametrosRegla struct {
+	args []interface{}
+}
+
+func (p *parametrosRegla) agregar(valor interface{}) string {
+	p.args = append(p.args, valor)
+	return fmt.Sprintf("$%d", len(p.args))
+}
+
+// expresionMetrica devuelve la subconsulta SQL de una métrica guardada en un criterio
+func expresionMetrica(texto string, params *parametrosRegla, zonaHoraria string) (string, error) {
+	metrica, err := utils.ParsearMetrica(texto)
+	if err != nil {
+		return "", fmt.Errorf("métrica de medalla no válida %q: %w", texto, err)
+	}
+
+	if metrica.Nombre == models.MetricaRachaDias {
+		// Días distintos con acciones: en una racha, el día menos su número de orden es constante
+		return `COALESCE((
+			SELECT MAX(dias) FROM (
+				SELECT COUNT(*) AS dias
+				FROM (
+					SELECT dia, dia - (ROW_NUMBER() OVER (ORDER BY dia))::int AS racha
+					FROM (
+						SELECT DISTINCT (a.created_at::timestamptz AT TIME ZONE ` + params.agregar(zonaHoraria) + `)::date AS dia
+						FROM user_actions a
+						WHERE a.user_id = u.id AND a.deleted_at IS NULL
+					) dias_con_acciones
+				) rachas
+				GROUP BY racha
+			) largos
+		), 0)`, nil
+	}
+
+	if consulta, ok := consultasMetricasSimples[metrica.Nombre]; ok {
+		return consulta, nil
+	}
+
+	consulta, ok := consultasFuentes[metrica.Fuente]
+	if !ok {
+		return "", fmt.Errorf("métrica de medalla sin consulta: %q", texto)
+	}
+
+	var b strings.Builder
+	b.WriteString("(" + consulta)
+	for _, c := range metrica.Condiciones {
+		columna, ok := columnasFuentes[metrica.Fuente][c.Campo]
+		if !ok {
+			return "", fmt.Errorf("campo de medalla sin columna: %s.%s", metrica.Fuente, c.Campo)
+		}
+		fmt.Fprintf(&b, " AND %s %s %s", columna, operadoresSQL[c.Operador], params.agregar(c.Valor))
+	}
+	b.WriteString(")")
+
+	return b.String(), nil
+}
+
+// condicionCriterios devuelve la condición SQL (sobre u.id) que cumple un usuario que
+// gana la medalla: algún grupo con todos sus criterios
+func condicionCriterios(criterios []models.CriterioMedalla, params *parametrosRegla, zonaHoraria string) (string, error) {
+	if len(criterios) == 0 {
+		return "false", nil
+	}
+
+	grupos := make(map[int][]string)
+	var orden []int
+	for _, c := range criterios {
+		expresion, err := expresionMetrica(c.Metrica, params, zonaHoraria)
+		if err != nil {
+			return "", err
+		}
+
+		operador, ok := operadoresSQL[c.Operador]
+		if !ok || c.Operador == "!=" {
+			return "", fmt.Errorf("operador de medalla no válido: %q", c.Operador)
+		}
+
+		if _, ok := grupos[c.Grupo]; !ok {
+			orden = append(orden, c.Grupo)
+		}
+		grupos[c.Grupo] = append(grupos[c.Grupo], fmt.Sprintf("%s %s %s", expresion, operador, params.agregar(c.Umbral)))
+	}
+
+	partes := make([]string, len(orden))
+	for i, grupo := range orden {
+		partes[i] = "(" + strings.Join(grupos[grupo], " AND ") + ")"
+	}
+
+	return "(" + strings.Join(partes, " OR ") + ")", nil
+}
+
+// valoresMetricas calcula las métricas indicadas para cada usuario
+func valoresMetricas(q consultor, userIDs []string, metricas []string, zonaHoraria string) (map[string]map[string]int, error) {
+	valores := make(map[string]map[string]int, len(userIDs))
+	for _, userID := range userIDs {
+		valores[userID] = make(map[string]int)
+	}
+
+	if len(userIDs) == 0 || len(metricas) == 0 {
+		return valores, nil
+	}
+
+	params := &parametrosRegla{}
+	usuarios := params.agregar(pq.Array(userIDs))
+
+	expresiones := make([]string, len(metricas))
+	for i, metrica := range metricas {
+		expresion, err := expresionMetrica(metrica, params, zonaHoraria)
+		if err != nil {
+			return nil, err
+		}
+		expresiones[i] = expresion
+	}
+
+	rows, err := q.Query(`
+		SELECT u.id, `+strings.Join(expresiones, ", ")+`
+		FROM unnest(`+usuarios+`::uuid[]) AS u(id)`, params.args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al calcular las métricas de medallas: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID string
+		numeros := make([]int, len(metricas))
+		destinos := []interface{}{&userID}
+		for i := range numeros {
+			destinos = append(destinos, &numeros[i])
+		}
+
+		if err := rows.Scan(destinos...); err != nil {
+			return nil, fmt.Errorf("error al leer las métricas del usuario: %w", err)
+		}
+
+		for i, metrica := range metricas {
+			valores[userID][metrica] = numeros[i]
+		}
+	}
+
+	return valores, rows.Err()
+}
+
+// metricaAfectada indica si una métrica depende de alguna de las métricas simples o
+// fuentes de count(...) indicadas
+func metricaAfectada(texto string, afectadas []string) bool {
+	metrica, err := utils.ParsearMetrica(texto)
+	if err != nil {
+		return false
+	}
+
+	if metrica.Nombre == "count" {
+		return slices.Contains(afectadas, metrica.Fuente)
+	}
+	return slices.Contains(afectadas, metrica.Nombre)
+}
+
+// SimularMedalla cuenta los usuarios que hoy cumplen los criterios y devuelve una
+// muestra de hasta limite de ellos, sin otorgar nada
+func (r *MedallasRepository) SimularMedalla(criterios []models.CriterioMedalla, limite int) (*models.SimulacionMedalla, error) {
+	params := &parametrosRegla{}
+	condicion, err := condicionCriterios(criterios, params, r.zonaHoraria)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT u.id, COALESCE(ubi.nombre, ''), COALESCE(ubi.apellido, ''), COUNT(*) OVER ()
+		FROM user_access u
+		LEFT JOIN user_basic_info ubi ON ubi.user_id = u.id
+		WHERE `+condicion+`
+		ORDER BY u.id
+		LIMIT `+params.agregar(limite), params.args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al simular la medalla: %w", err)
+	}
+	defer rows.Close()
+
+	simulacion := &models.SimulacionMedalla{Usuarios: []models.UsuarioSimulacion{}}
+	for rows.Next() {
+		var u models.UsuarioSimulacion
+		if err := rows.Scan(&u.UserID, &u.Nombre, &u.Apellido, &simulacion.Total); err != nil {
+			return nil, fmt.Errorf("error al leer usuario de la simulación: %w", err)
+		}
+		simulacion.Usuarios = append(simulacion.Usuarios, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar la simulación: %w", err)
+	}
+
+	return simulacion, nil
+}
@@ -2,28 +2,38 @@ package postgres
 
 import (
 	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/internal/utils"
 	"backend_proyecto_verde/pkg/database"
 	"database/sql"
+	"fmt"
+	"sort"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type MedallasRepository struct {
-	db            *sql.DB
-	userStatsRepo *UserRepository
+	db          *sql.DB
+	zonaHoraria string // Zona en la que empieza el día para las rachas de días
 }
 
-func NewMedallasRepository(db *sql.DB, userStatsRepo *UserRepository) *MedallasRepository {
-	return &MedallasRepository{db: db, userStatsRepo: userStatsRepo}
+func NewMedallasRepository(db *sql.DB, zonaHoraria string) *MedallasRepository {
+	return &MedallasRepository{db: db, zonaHoraria: zonaHoraria}
 }
 
 func (r *MedallasRepository) CreateMedalla(medalla *models.Medalla) error {
+	if len(medalla.Criterios) == 0 {
+		medalla.Criterios = criteriosPorIndicadores(medalla)
+	}
+
 	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
 		query := `
 			INSERT INTO medallas (
 				nombre, descripcion, dificultad, requiere_amistades,
 				requiere_puntos, requiere_acciones, requiere_torneos,
-				requiere_victoria_torneos, numero_requerido
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+				requiere_victoria_torneos, numero_requerido, regla, politica_revocacion,
+				categoria, orden, disponible_desde, disponible_hasta, id_torneo, ciudad
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 			RETURNING id`
 
 		err := tx.QueryRow(
@@ -37,140 +47,408 @@ func (r *MedallasRepository) CreateMedalla(medalla *models.Medalla) error {
 			medalla.RequiereTorneos,
 			medalla.RequiereVictoriaTorneos,
 			medalla.NumeroRequerido,
+			medalla.Regla,
+			medalla.PoliticaRevocacion,
+			medalla.Categoria,
+			medalla.Orden,
+			medalla.DisponibleDesde,
+			medalla.DisponibleHasta,
+			medalla.IDTorneo,
+			medalla.Ciudad,
 		).Scan(&medalla.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := insertarCriterios(tx, medalla.ID, medalla.Criterios); err != nil {
+			return err
+		}
 
+		// Los usuarios que ya cumplen los criterios la reciben en segundo plano
+		_, err = encolarBackfill(tx, medalla.ID)
 		return err
 	})
 }
 
-func (r *MedallasRepository) AutoAsignMedallas(userID string) error {
-	// Obtener todas las medallas
-	query := `SELECT id, nombre, descripcion, dificultad, requiere_amistades, requiere_puntos,
-              requiere_acciones, requiere_torneos, requiere_victoria_torneos, numero_requerido
-              FROM medallas`
-	rows, err := r.db.Query(query)
-	if err != nil {
-		return err
+// criteriosPorIndicadores arma los criterios de una medalla creada con los indicadores
+// requiere_*: cada indicador es un grupo propio, así que basta con cumplir uno
+func criteriosPorIndicadores(medalla *models.Medalla) []models.CriterioMedalla {
+	if medalla.NumeroRequerido == nil {
+		return nil
 	}
-	defer rows.Close()
 
-	medallas := make([]models.Medalla, 0)
-	for rows.Next() {
-		var m models.Medalla
-		if err := rows.Scan(&m.ID, &m.Nombre, &m.Descripcion, &m.Dificultad,
-			&m.RequiereAmistades, &m.RequierePuntos, &m.RequiereAcciones,
-			&m.RequiereTorneos, &m.RequiereVictoriaTorneos, &m.NumeroRequerido); err != nil {
-			return err
-		}
-		medallas = append(medallas, m)
+	indicadores := []struct {
+		activo  bool
+		metrica string
+	}{
+		{medalla.RequiereAmistades, models.MetricaAmigos},
+		{medalla.RequierePuntos, models.MetricaPuntos},
+		{medalla.RequiereAcciones, models.MetricaAcciones},
+		{medalla.RequiereTorneos, models.MetricaTorneos},
+		{medalla.RequiereVictoriaTorneos, models.MetricaTorneosGanados},
 	}
-	if err := rows.Err(); err != nil {
-		return err
+
+	var criterios []models.CriterioMedalla
+	for _, indicador := range indicadores {
+		if indicador.activo {
+			criterios = append(criterios, models.CriterioMedalla{
+				Grupo:    len(criterios),
+				Metrica:  indicador.metrica,
+				Operador: ">=",
+				Umbral:   *medalla.NumeroRequerido,
+			})
+		}
 	}
 
-	// Obtener medallas ya ganadas por el usuario
-	queryAsignadas := `SELECT id_medalla FROM medallas_ganadas WHERE id_usuario = $1`
-	rowsAsignadas, err := r.db.Query(queryAsignadas, userID)
-	if err != nil {
-		return err
+	return criterios
+}
+
+// insertarCriterios guarda los criterios de una medalla
+func insertarCriterios(tx *sql.Tx, medallaID string, criterios []models.CriterioMedalla) error {
+	for _, c := range criterios {
+		_, err := tx.Exec(`
+			INSERT INTO medalla_criterios (id_medalla, grupo, metrica, operador, umbral)
+			VALUES ($1, $2, $3, $4, $5)`, medallaID, c.Grupo, c.Metrica, c.Operador, c.Umbral)
+		if err != nil {
+			return fmt.Errorf("error al guardar los criterios de la medalla: %w", err)
+		}
 	}
-	defer rowsAsignadas.Close()
 
-	medallasGanadas := make(map[string]bool)
-	for rowsAsignadas.Next() {
-		var idMedalla string
-		if err := rowsAsignadas.Scan(&idMedalla); err != nil {
+	return nil
+}
+
+// AutoAsignMedallas otorga al usuario las medallas disponibles que todavía no tiene y
+// cuyos criterios ya cumple. Dos evaluaciones del mismo usuario no corren a la vez
+func (r *MedallasRepository) AutoAsignMedallas(userID string) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		if err := bloquearMedallasUsuarios(tx, []string{userID}); err != nil {
 			return err
 		}
-		medallasGanadas[idMedalla] = true
-	}
 
-	// Filtrar medallas ya ganadas
-	medallasPendientes := make([]models.Medalla, 0)
-	for _, m := range medallas {
-		if !medallasGanadas[m.ID] {
-			medallasPendientes = append(medallasPendientes, m)
+		pendientes, err := listarIDs(tx, `
+			SELECT m.id
+			FROM medallas m
+			CROSS JOIN (SELECT $1::uuid AS id) u
+			WHERE `+condicionMedallaDisponible+`
+			AND NOT EXISTS (
+				SELECT 1 FROM medallas_ganadas mg
+				WHERE mg.id_medalla = m.id AND mg.id_usuario = $1
+			)`, userID)
+		if err != nil {
+			return fmt.Errorf("error al obtener las medallas pendientes: %w", err)
 		}
-	}
 
-	// Obtener estadísticas del usuario
-	userStats, err := r.userStatsRepo.GetUserStats(userID)
-	if err != nil {
-		return err
-	}
+		criterios, err := cargarCriterios(tx, pendientes)
+		if err != nil {
+			return err
+		}
+
+		valores, err := valoresMetricas(tx, []string{userID}, metricasCriterios(criterios), r.zonaHoraria)
+		if err != nil {
+			return err
+		}
+
+		for _, medallaID := range pendientes {
+			if !utils.CumpleCriterios(criterios[medallaID], valores[userID]) {
+				continue
+			}
 
-	// Asignar medallas que cumplan con los requisitos
-	medallasAsignadas := 0
-	for _, m := range medallasPendientes {
-		if cumpleRequisitos(m, *userStats) {
-			if err := r.AsignarMedalla(userID, m.ID); err != nil {
+			if err := otorgarMedalla(tx, userID, medallaID); err != nil {
 				return err
 			}
-			medallasAsignadas++
 		}
+
+		return nil
+	})
+}
+
+// bloquearMedallasUsuarios bloquea las estadísticas de los usuarios, en orden para no
+// provocar interbloqueos, y así serializa las evaluaciones de medallas de cada uno
+func bloquearMedallasUsuarios(tx *sql.Tx, userIDs []string) error {
+	_, err := tx.Exec(`
+		SELECT 1 FROM user_stats
+		WHERE user_id = ANY($1::uuid[])
+		ORDER BY user_id
+		FOR UPDATE`, pq.Array(userIDs))
+	if err != nil {
+		return fmt.Errorf("error al bloquear las medallas de los usuarios: %w", err)
 	}
+	return nil
+}
 
-	err = r.userStatsRepo.UpdateUserPendingMedalla(medallasAsignadas, userID)
+// otorgarMedalla guarda la medalla ganada y la suma a las pendientes de revisar. Si el
+// usuario ya la tenía no hace nada
+func otorgarMedalla(tx *sql.Tx, userID, medallaID string) error {
+	result, err := tx.Exec(`
+		INSERT INTO medallas_ganadas (id_usuario, id_medalla, fecha_ganada)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (id_usuario, id_medalla) DO NOTHING`, userID, medallaID, time.Now())
 	if err != nil {
+		return fmt.Errorf("error al asignar la medalla: %w", err)
+	}
+
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
 		return err
 	}
 
-	return nil
+	_, err = tx.Exec(`
+		UPDATE user_stats SET pending_medalla = pending_medalla + 1
+		WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar las medallas pendientes: %w", err)
+	}
+
+	return desbloquearItemsMedalla(tx, userID, medallaID)
 }
 
-// Función auxiliar para verificar si el usuario cumple con los requisitos de la medalla
-func cumpleRequisitos(m models.Medalla, userStats models.UserStats) bool {
-	if m.RequiereAmistades && userStats.CantidadAmigos >= *m.NumeroRequerido {
-		return true
+// consultor es lo que comparten *sql.DB y *sql.Tx para hacer consultas
+type consultor interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// cargarCriterios devuelve los criterios de las medallas indicadas, agrupados por medalla
+func cargarCriterios(q consultor, medallaIDs []string) (map[string][]models.CriterioMedalla, error) {
+	criterios := make(map[string][]models.CriterioMedalla)
+	if len(medallaIDs) == 0 {
+		return criterios, nil
 	}
-	if m.RequierePuntos && userStats.Puntos >= *m.NumeroRequerido {
-		return true
+
+	rows, err := q.Query(`
+		SELECT id_medalla, grupo, metrica, operador, umbral
+		FROM medalla_criterios
+		WHERE id_medalla = ANY($1::uuid[])
+		ORDER BY grupo, metrica`, pq.Array(medallaIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los criterios de las medallas: %w", err)
 	}
-	if m.RequiereAcciones && userStats.Acciones >= *m.NumeroRequerido {
-		return true
+	defer rows.Close()
+
+	for rows.Next() {
+		var medallaID string
+		var c models.CriterioMedalla
+		if err := rows.Scan(&medallaID, &c.Grupo, &c.Metrica, &c.Operador, &c.Umbral); err != nil {
+			return nil, fmt.Errorf("error al leer criterio de medalla: %w", err)
+		}
+		criterios[medallaID] = append(criterios[medallaID], c)
 	}
-	if m.RequiereTorneos && userStats.TorneosParticipados >= *m.NumeroRequerido {
-		return true
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar los criterios de las medallas: %w", err)
 	}
-	if m.RequiereVictoriaTorneos && userStats.TorneosGanados >= *m.NumeroRequerido {
-		return true
+
+	return criterios, nil
+}
+
+// metricasCriterios devuelve las métricas distintas que usan los criterios
+func metricasCriterios(criterios map[string][]models.CriterioMedalla) []string {
+	vistas := make(map[string]bool)
+	var metricas []string
+	for _, lista := range criterios {
+		for _, c := range lista {
+			if !vistas[c.Metrica] {
+				vistas[c.Metrica] = true
+				metricas = append(metricas, c.Metrica)
+			}
+		}
 	}
-	return false
+
+	return metricas
 }
 
-func (r *MedallasRepository) GetMedallas() ([]models.Medalla, error) {
-	query := `SELECT * FROM medallas`
+// columnasMedalla son las columnas de medallas (alias m) que se leen con destinosMedalla
+const columnasMedalla = `
+	m.id, m.nombre, COALESCE(m.descripcion, ''), m.dificultad, m.requiere_amistades,
+	m.requiere_puntos, m.requiere_acciones, m.requiere_torneos, m.requiere_victoria_torneos,
+	m.numero_requerido, m.regla, m.politica_revocacion, m.categoria, m.orden, m.imagen, m.retirada_at,
+	m.disponible_desde, m.disponible_hasta, m.id_torneo, m.ciudad,
+	m.disponible_hasta IS NOT NULL AND m.disponible_hasta <= NOW()`
+
+// destinosMedalla devuelve los destinos de Scan para columnasMedalla
+func destinosMedalla(m *models.Medalla) []interface{} {
+	return []interface{}{
+		&m.ID, &m.Nombre, &m.Descripcion, &m.Dificultad, &m.RequiereAmistades,
+		&m.RequierePuntos, &m.RequiereAcciones, &m.RequiereTorneos, &m.RequiereVictoriaTorneos,
+		&m.NumeroRequerido, &m.Regla, &m.PoliticaRevocacion, &m.Categoria, &m.Orden, &m.Imagen, &m.RetiradaAt,
+		&m.DisponibleDesde, &m.DisponibleHasta, &m.IDTorneo, &m.Ciudad, &m.EdicionLimitada,
+	}
+}
 
-	rows, err := r.db.Query(query)
+// condicionMedallaDisponible es la condición SQL de una medalla (alias m) que el usuario
+// (u.id) puede ganar ahora: no retirada, con la ventana abierta y, si es de un torneo o
+// una ciudad, con participación en el torneo o acciones en la ciudad durante la ventana
+const condicionMedallaDisponible = `
+	m.retirada_at IS NULL
+	AND (m.disponible_desde IS NULL OR m.disponible_desde <= NOW())
+	AND (m.disponible_hasta IS NULL OR m.disponible_hasta > NOW())
+	AND (m.id_torneo IS NULL OR EXISTS (
+		SELECT 1 FROM torneo_estadisticas te
+		WHERE te.id_torneo = m.id_torneo AND te.id_jugador = u.id
+	))
+	AND (m.ciudad IS NULL OR EXISTS (
+		SELECT 1 FROM user_actions a
+		WHERE a.user_id = u.id AND a.ciudad = m.ciudad AND a.deleted_at IS NULL
+		AND (m.disponible_desde IS NULL OR a.created_at >= m.disponible_desde)
+		AND (m.disponible_hasta IS NULL OR a.created_at < m.disponible_hasta)
+	))`
+
+// diasUsuarioActivo es la ventana de acciones con la que un usuario cuenta como activo
+// para la rareza de las medallas
+const diasUsuarioActivo = 30
+
+// GetMedallas devuelve el catálogo ordenado con sus criterios y su rareza: el porcentaje
+// de usuarios activos (con acciones en los últimos 30 días) que tienen cada medalla
+func (r *MedallasRepository) GetMedallas(incluirRetiradas bool) ([]models.Medalla, error) {
+	query := `
+		WITH activos AS (
+			SELECT DISTINCT user_id FROM user_actions
+			WHERE deleted_at IS NULL AND created_at >= NOW() - make_interval(days => $2)
+		), poseedores AS (
+			SELECT mg.id_medalla, COUNT(DISTINCT mg.id_usuario) AS cantidad
+			FROM medallas_ganadas mg
+			JOIN activos a ON a.user_id = mg.id_usuario
+			GROUP BY mg.id_medalla
+		)
+		SELECT ` + columnasMedalla + `,
+			COALESCE(ROUND(p.cantidad * 100.0 / NULLIF((SELECT COUNT(*) FROM activos), 0), 2), 0)::float8
+		FROM medallas m
+		LEFT JOIN poseedores p ON p.id_medalla = m.id
+		WHERE $1 OR m.retirada_at IS NULL
+		ORDER BY m.orden, m.nombre`
+
+	rows, err := r.db.Query(query, incluirRetiradas, diasUsuarioActivo)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var medallas []models.Medalla
+	medallas := []models.Medalla{}
 	for rows.Next() {
 		var m models.Medalla
-		err := rows.Scan(
-			&m.ID, &m.Nombre, &m.Descripcion, &m.Dificultad,
-			&m.RequiereAmistades, &m.RequierePuntos, &m.RequiereAcciones,
-			&m.RequiereTorneos, &m.RequiereVictoriaTorneos, &m.NumeroRequerido,
-		)
-		if err != nil {
+		if err := rows.Scan(append(destinosMedalla(&m), &m.Rareza)...); err != nil {
 			return nil, err
 		}
 		medallas = append(medallas, m)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(medallas))
+	for i, m := range medallas {
+		ids[i] = m.ID
+	}
+
+	criterios, err := cargarCriterios(r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range medallas {
+		medallas[i].Criterios = criterios[medallas[i].ID]
+	}
+
 	return medallas, nil
 }
 
+// GetProgresoMedallas devuelve el avance del usuario hacia cada medalla, evaluado igual
+// que en AutoAsignMedallas. Primero van las que le faltan, de la más cercana a la más lejana
+func (r *MedallasRepository) GetProgresoMedallas(userID string) ([]models.ProgresoMedalla, error) {
+	rows, err := r.db.Query(`
+		SELECT m.id, m.nombre, COALESCE(m.descripcion, ''), m.dificultad, mg.fecha_ganada
+		FROM medallas m
+		LEFT JOIN LATERAL (
+			SELECT MIN(fecha_ganada) AS fecha_ganada
+			FROM medallas_ganadas
+			WHERE id_medalla = m.id AND id_usuario = $1
+		) mg ON true
+		CROSS JOIN (SELECT $1::uuid AS id) u
+		WHERE mg.fecha_ganada IS NOT NULL OR (`+condicionMedallaDisponible+`)`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las medallas: %w", err)
+	}
+	defer rows.Close()
+
+	progresos := []models.ProgresoMedalla{}
+	for rows.Next() {
+		var p models.ProgresoMedalla
+		if err := rows.Scan(&p.IDMedalla, &p.Nombre, &p.Descripcion, &p.Dificultad, &p.FechaGanada); err != nil {
+			return nil, fmt.Errorf("error al leer medalla: %w", err)
+		}
+		p.Ganada = p.FechaGanada != nil
+		progresos = append(progresos, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar las medallas: %w", err)
+	}
+
+	ids := make([]string, len(progresos))
+	for i, p := range progresos {
+		ids[i] = p.IDMedalla
+	}
+
+	criterios, err := cargarCriterios(r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	valores, err := valoresMetricas(r.db, []string{userID}, metricasCriterios(criterios), r.zonaHoraria)
+	if err != nil {
+		return nil, err
+	}
+	valoresUsuario := valores[userID]
+
+	for i := range progresos {
+		p := &progresos[i]
+		lista := criterios[p.IDMedalla]
+
+		p.Criterios = make([]models.ProgresoCriterio, len(lista))
+		for j, c := range lista {
+			valor := valoresUsuario[c.Metrica]
+			p.Criterios[j] = models.ProgresoCriterio{
+				CriterioMedalla: c,
+				ValorActual:     valor,
+				Cumplido:        utils.CompararCriterio(valor, c.Operador, c.Umbral),
+				Porcentaje:      utils.PorcentajeCriterio(valor, c.Operador, c.Umbral),
+			}
+		}
+
+		porcentaje, limitante := utils.ProgresoCriterios(lista, valoresUsuario)
+		p.Porcentaje = porcentaje
+		if limitante >= 0 {
+			p.Metrica = lista[limitante].Metrica
+			p.ValorActual = valoresUsuario[lista[limitante].Metrica]
+			p.Objetivo = lista[limitante].Umbral
+		}
+		if p.Ganada {
+			p.Porcentaje = 100
+		}
+	}
+
+	sort.SliceStable(progresos, func(i, j int) bool {
+		a, b := progresos[i], progresos[j]
+		if a.Ganada != b.Ganada {
+			return !a.Ganada
+		}
+		if a.Porcentaje != b.Porcentaje {
+			return a.Porcentaje > b.Porcentaje
+		}
+		if a.Dificultad != b.Dificultad {
+			return a.Dificultad < b.Dificultad
+		}
+		return a.Nombre < b.Nombre
+	})
+
+	return progresos, nil
+}
+
+// AsignarMedalla otorga a mano una medalla; asignarla de nuevo no tiene efecto
 func (r *MedallasRepository) AsignarMedalla(userID, medallaID string) error {
 	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
-		query := `
-			INSERT INTO medallas_ganadas (id_usuario, id_medalla, fecha_ganada)
-			VALUES ($1, $2, $3)`
-
-		_, err := tx.Exec(query, userID, medallaID, time.Now())
-		return err
+		if err := bloquearMedallasUsuarios(tx, []string{userID}); err != nil {
+			return err
+		}
+		return otorgarMedalla(tx, userID, medallaID)
 	})
 }
 
@@ -201,126 +479,153 @@ func (r *MedallasRepository) GetMedallasUsuario(userID string) ([]models.Medalla
 	return medallas, nil
 }
 
+// VerifyAndUpdateMedallas vuelve a evaluar todas las medallas revocables del usuario y
+// quita las que ya no cumple, por ejemplo después de borrar una acción
 func (r *MedallasRepository) VerifyAndUpdateMedallas(userID string) error {
-	// Primero obtenemos las estadísticas actuales del usuario
-		var stats struct {
-			Puntos   int
-			Acciones int
-		}
-
-		err := r.db.QueryRow(`
-		SELECT puntos, acciones 
-		FROM user_stats 
-		WHERE user_id = $1
-	`, userID).Scan(&stats.Puntos, &stats.Acciones)
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		return revocarMedallasIncumplidas(tx, []string{userID}, nil, r.zonaHoraria, "Tus estadísticas cambiaron.", nil)
+	})
+}
 
-		if err != nil {
-			return err
-		}
+func (r *MedallasRepository) GetSlogansMedallasGanadas(userID string) ([]string, error) {
+	query := `
+		SELECT m.nombre 
+		FROM medallas_ganadas mg
+		JOIN medallas m ON mg.id_medalla = m.id
+		WHERE mg.id_usuario = $1
+		ORDER BY mg.fecha_ganada DESC`
 
-	// Comenzar una transacción
-	tx, err := r.db.Begin()
+	rows, err := r.db.Query(query, userID)
 	if err != nil {
-			return err
+		return nil, err
+	}
+	defer rows.Close()
+
+	var slogans []string
+	for rows.Next() {
+		var nombre string
+		if err := rows.Scan(&nombre); err != nil {
+			return nil, err
 		}
+		slogans = append(slogans, nombre)
+	}
 
-	defer tx.Rollback() // Se ejecuta si hay un error en cualquier parte
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 
-	// Verificar y posiblemente eliminar medallas de puntos
-	if err = r.verifyPointsMedals(tx, userID, stats.Puntos); err != nil {
-			return err
-		}
+	return slogans, nil
+}
 
-	// Verificar y posiblemente eliminar medallas de acciones
-	if err = r.verifyActionsMedals(tx, userID, stats.Acciones); err != nil {
-			return err
-		}
+func (r *MedallasRepository) ResetPendingMedallas(userID string) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		// Marcar todas las medallas como notificadas
+		query := `
+			UPDATE user_stats
+			SET pending_medalla = 0
+			WHERE user_id = $1`
 
-	return tx.Commit()
+		_, err := tx.Exec(query, userID)
+		return err
+	})
 }
 
-func (r *MedallasRepository) verifyPointsMedals(tx *sql.Tx, userID string, currentPoints int) error {
-	// Obtener todas las medallas de puntos del usuario
+// revocarMedallasIncumplidas vuelve a evaluar las medallas revocables, no retiradas y
+// con la ventana abierta ganadas por los usuarios, solo las que tienen algún criterio sobre las métricas o
+// fuentes afectadas (todas si afectadas es nil). Las que ya no se cumplen se quitan,
+// quedan en el historial con el motivo y se avisa al usuario
+func revocarMedallasIncumplidas(tx *sql.Tx, userIDs []string, afectadas []string, zonaHoraria string, motivo string, torneoID *string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	if err := bloquearMedallasUsuarios(tx, userIDs); err != nil {
+		return err
+	}
+
 	rows, err := tx.Query(`
-		SELECT m.id, m.numero_requerido
+		SELECT mg.id_usuario, mg.id_medalla, m.nombre, mg.fecha_ganada
 		FROM medallas_ganadas mg
-		JOIN medallas m ON mg.id_medalla = m.id
-		WHERE mg.id_usuario = $1 AND m.requiere_puntos = true
-	`, userID)
+		JOIN medallas m ON m.id = mg.id_medalla
+		WHERE mg.id_usuario = ANY($1::uuid[]) AND m.politica_revocacion = $2
+		AND m.retirada_at IS NULL
+		AND (m.disponible_hasta IS NULL OR m.disponible_hasta > NOW())`,
+		pq.Array(userIDs), models.PoliticaRevocable)
 	if err != nil {
-		return err
+		return fmt.Errorf("error al obtener las medallas ganadas: %w", err)
 	}
-	defer rows.Close()
-
-	// Lista para almacenar las medallas que deben eliminarse
-	var toDelete []string
 
+	type ganada struct {
+		userID, medallaID, nombre string
+		fecha                     time.Time
+	}
+	var ganadas []ganada
+	var medallaIDs []string
 	for rows.Next() {
-		var medallaID string
-		var puntosRequeridos int
-		if err := rows.Scan(&medallaID, &puntosRequeridos); err != nil {
-			return err
-		}
-
-		// Si ya no cumple con los puntos requeridos, agregar a la lista de eliminaciones
-		if currentPoints < puntosRequeridos {
-			toDelete = append(toDelete, medallaID)
+		var g ganada
+		if err := rows.Scan(&g.userID, &g.medallaID, &g.nombre, &g.fecha); err != nil {
+			rows.Close()
+			return fmt.Errorf("error al leer medalla ganada: %w", err)
 		}
+		ganadas = append(ganadas, g)
+		medallaIDs = append(medallaIDs, g.medallaID)
 	}
-	// Verificar si hubo errores en la iteración de `rows`
-	if err := rows.Err(); err != nil {
-				return err
-			}
 
-	// Ejecutar las eliminaciones después de procesar todas las filas
-	query := `DELETE FROM medallas_ganadas WHERE id_usuario = $1 AND id_medalla = $2`
-	for _, medallaID := range toDelete {
-		if _, err := tx.Exec(query, userID, medallaID); err != nil {
-			return err
-		}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("error al procesar las medallas ganadas: %w", err)
 	}
 
-	return nil
-}
+	if len(ganadas) == 0 {
+		return nil
+	}
 
-func (r *MedallasRepository) verifyActionsMedals(tx *sql.Tx, userID string, currentActions int) error {
-	// Obtener todas las medallas de acciones del usuario
-	rows, err := tx.Query(`
-		SELECT m.id, m.numero_requerido
-		FROM medallas_ganadas mg
-		JOIN medallas m ON mg.id_medalla = m.id
-		WHERE mg.id_usuario = $1 AND m.requiere_acciones = true
-	`, userID)
+	criterios, err := cargarCriterios(tx, medallaIDs)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
 
-	// Lista para almacenar las medallas que deben eliminarse
-	var toDelete []string
+	// Solo se reevalúan las medallas con algún criterio afectado
+	if afectadas != nil {
+		for medallaID, lista := range criterios {
+			afectada := false
+			for _, c := range lista {
+				afectada = afectada || metricaAfectada(c.Metrica, afectadas)
+			}
+			if !afectada {
+				delete(criterios, medallaID)
+			}
+		}
+	}
+
+	valores, err := valoresMetricas(tx, userIDs, metricasCriterios(criterios), zonaHoraria)
+	if err != nil {
+		return err
+	}
 
-	for rows.Next() {
-		var medallaID string
-		var accionesRequeridas int
-		if err := rows.Scan(&medallaID, &accionesRequeridas); err != nil {
-			return err
+	for _, g := range ganadas {
+		lista, ok := criterios[g.medallaID]
+		if !ok || utils.CumpleCriterios(lista, valores[g.userID]) {
+			continue
 		}
 
-		// Si ya no cumple con las acciones requeridas, agregar a la lista de eliminaciones
-		if currentActions < accionesRequeridas {
-			toDelete = append(toDelete, medallaID)
+		_, err := tx.Exec(`
+			DELETE FROM medallas_ganadas
+			WHERE id_usuario = $1 AND id_medalla = $2`, g.userID, g.medallaID)
+		if err != nil {
+			return fmt.Errorf("error al revocar la medalla: %w", err)
 		}
-	}
 
-	// Verificar si hubo errores en la iteración de `rows`
-	if err := rows.Err(); err != nil {
-				return err
-			}
+		_, err = tx.Exec(`
+			INSERT INTO medallas_revocadas (id_usuario, id_medalla, fecha_ganada, motivo)
+			VALUES ($1, $2, $3, $4)`, g.userID, g.medallaID, g.fecha, motivo)
+		if err != nil {
+			return fmt.Errorf("error al guardar el historial de la medalla: %w", err)
+		}
 
-	// Ejecutar las eliminaciones después de procesar todas las filas
-	query := `DELETE FROM medallas_ganadas WHERE id_usuario = $1 AND id_medalla = $2`
-	for _, medallaID := range toDelete {
-		if _, err := tx.Exec(query, userID, medallaID); err != nil {
+		mensaje := fmt.Sprintf("Perdiste la medalla \"%s\" porque ya no cumples sus requisitos. %s", g.nombre, motivo)
+		if err := crearNotificacion(tx, g.userID, models.NotificacionMedallaRevocada, mensaje, torneoID); err != nil {
 			return err
 		}
 	}
@@ -328,45 +633,32 @@ func (r *MedallasRepository) verifyActionsMedals(tx *sql.Tx, userID string, curr
 	return nil
 }
 
-func (r *MedallasRepository) GetSlogansMedallasGanadas(userID string) ([]string, error) {
-	query := `
-		SELECT m.nombre 
-		FROM medallas_ganadas mg
-		JOIN medallas m ON mg.id_medalla = m.id
-		WHERE mg.id_usuario = $1
-		ORDER BY mg.fecha_ganada DESC`
-
-	rows, err := r.db.Query(query, userID)
+// GetMedallasRevocadas devuelve el historial de medallas que perdió el usuario, de la
+// más reciente a la más antigua
+func (r *MedallasRepository) GetMedallasRevocadas(userID string) ([]models.MedallaRevocada, error) {
+	rows, err := r.db.Query(`
+		SELECT mr.id, mr.id_medalla, m.nombre, mr.fecha_ganada, mr.fecha_revocada, mr.motivo
+		FROM medallas_revocadas mr
+		JOIN medallas m ON m.id = mr.id_medalla
+		WHERE mr.id_usuario = $1
+		ORDER BY mr.fecha_revocada DESC`, userID)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error al obtener las medallas revocadas: %w", err)
 	}
 	defer rows.Close()
 
-	var slogans []string
+	revocadas := []models.MedallaRevocada{}
 	for rows.Next() {
-		var nombre string
-		if err := rows.Scan(&nombre); err != nil {
-			return nil, err
+		var m models.MedallaRevocada
+		if err := rows.Scan(&m.ID, &m.IDMedalla, &m.Nombre, &m.FechaGanada, &m.FechaRevocada, &m.Motivo); err != nil {
+			return nil, fmt.Errorf("error al leer medalla revocada: %w", err)
 		}
-		slogans = append(slogans, nombre)
+		revocadas = append(revocadas, m)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar las medallas revocadas: %w", err)
 	}
 
-	return slogans, nil
-}
-
-func (r *MedallasRepository) ResetPendingMedallas(userID string) error {
-	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
-		// Marcar todas las medallas como notificadas
-		query := `
-			UPDATE user_stats
-			SET pending_medalla = 0
-			WHERE user_id = $1`
-
-		_, err := tx.Exec(query, userID)
-		return err
-	})
+	return revocadas, nil
 }
@@ -0,0 +1,445 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/internal/utils"
+	"backend_proyecto_verde/pkg/database"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrTemporadaNoEncontrada  = errors.New("la temporada no existe o no pertenece al organizador")
+	ErrFueraDeTemporada       = errors.New("el torneo comienza fuera de las fechas de la temporada")
+	ErrPlantillaNoEncontrada  = errors.New("la plantilla no existe o no pertenece al organizador")
+	ErrCreadorConTorneoActivo = errors.New("el organizador ya tiene un torneo activo")
+)
+
+type TemporadasRepository struct {
+	db *sql.DB
+}
+
+func NewTemporadasRepository(db *sql.DB) *TemporadasRepository {
+	return &TemporadasRepository{db: db}
+}
+
+// verificarTemporada comprueba que la temporada pertenezca al organizador y que el
+// torneo comience dentro de sus fechas
+func verificarTemporada(tx *sql.Tx, temporadaID string, creatorID string, inicio time.Time) error {
+	var dentro bool
+	err := tx.QueryRow(`
+		SELECT $3 >= fecha_inicio AND $3 < fecha_fin
+		FROM temporadas
+		WHERE id = $1 AND id_creator = $2`, temporadaID, creatorID, inicio).Scan(&dentro)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrTemporadaNoEncontrada
+		}
+		return fmt.Errorf("error al verificar la temporada: %w", err)
+	}
+
+	if !dentro {
+		return ErrFueraDeTemporada
+	}
+
+	return nil
+}
+
+// CreateTemporada registra una temporada. Si no se indica la forma de puntuar se
+// suman los puntos de cada torneo
+func (r *TemporadasRepository) CreateTemporada(temporada *models.Temporada) error {
+	if temporada.Puntuacion == "" {
+		temporada.Puntuacion = models.PuntuacionTemporadaPuntos
+	}
+
+	// Si no se envían puntos por posición se usan los de la base de datos
+	var puntosPosicion interface{}
+	if len(temporada.PuntosPosicion) > 0 {
+		puntosPosicion = pq.Array(temporada.PuntosPosicion)
+	}
+
+	query := `
+		INSERT INTO temporadas (id_creator, nombre, fecha_inicio, fecha_fin, puntuacion, puntos_posicion)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6::int[], '{10, 8, 6, 5, 4, 3, 2, 1}'))
+		RETURNING id, puntos_posicion, created_at`
+
+	err := r.db.QueryRow(query, temporada.IDCreator, temporada.Nombre, temporada.FechaInicio,
+		temporada.FechaFin, temporada.Puntuacion, puntosPosicion,
+	).Scan(&temporada.ID, pq.Array(&temporada.PuntosPosicion), &temporada.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error al crear la temporada: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TemporadasRepository) GetTemporada(id string) (*models.Temporada, error) {
+	query := `
+		SELECT id, id_creator, nombre, fecha_inicio, fecha_fin, puntuacion, puntos_posicion, created_at
+		FROM temporadas
+		WHERE id = $1`
+
+	var t models.Temporada
+	err := r.db.QueryRow(query, id).Scan(&t.ID, &t.IDCreator, &t.Nombre, &t.FechaInicio,
+		&t.FechaFin, &t.Puntuacion, pq.Array(&t.PuntosPosicion), &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// GetTorneosTemporada lista los torneos de una temporada por fecha de inicio
+func (r *TemporadasRepository) GetTorneosTemporada(temporadaID string) ([]models.Torneo, error) {
+	query := `
+		SELECT id, nombre, modalidad, nombre_ubicacion_a, fecha_inicio, fecha_fin,
+			finalizado, ganador_equipo, ganador_individual, id_plantilla, cancelado
+		FROM torneos
+		WHERE id_temporada = $1
+		ORDER BY fecha_inicio`
+
+	rows, err := r.db.Query(query, temporadaID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los torneos de la temporada: %w", err)
+	}
+	defer rows.Close()
+
+	torneos := []models.Torneo{}
+	for rows.Next() {
+		var t models.Torneo
+		err := rows.Scan(&t.ID, &t.Nombre, &t.Modalidad, &t.NombreUbicacionA, &t.FechaInicio,
+			&t.FechaFin, &t.Finalizado, &t.GanadorEquipo, &t.GanadorIndividual, &t.IDPlantilla, &t.Cancelado)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer torneo de la temporada: %w", err)
+		}
+		t.IDTemporada = &temporadaID
+		torneos = append(torneos, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar los torneos de la temporada: %w", err)
+	}
+
+	return torneos, nil
+}
+
+// GetRankingTemporada calcula la clasificación acumulada de una temporada. En modo
+// "puntos" se suman los puntos de cada torneo (también los que siguen en curso); en
+// modo "posicion" cada torneo finalizado otorga los puntos de la posición alcanzada.
+// Los torneos cancelados no cuentan
+func (r *TemporadasRepository) GetRankingTemporada(temporadaID string, limit, offset int) ([]models.RankingTemporada, error) {
+	query := `
+		WITH participaciones AS (
+			SELECT te.id_jugador, te.id_torneo, te.id_equipo, te.puntos, t.finalizado, t.modalidad,
+				COALESCE(t.ganador_individual = te.id_jugador OR t.ganador_equipo = te.id_equipo, false) AS gano
+			FROM torneo_estadisticas te
+			JOIN torneos t ON t.id = te.id_torneo
+			WHERE t.id_temporada = $1 AND t.cancelado = false AND te.habilitado = true
+		), posiciones_equipo AS (
+			SELECT id_torneo, id_equipo,
+				RANK() OVER (PARTITION BY id_torneo ORDER BY SUM(puntos) DESC) AS posicion
+			FROM participaciones
+			WHERE modalidad = 'Versus' AND id_equipo IS NOT NULL
+			GROUP BY id_torneo, id_equipo
+		), resultados AS (
+			-- En Versus cada jugador recibe el puesto de su equipo según el total del equipo
+			SELECT p.id_jugador, p.puntos, p.finalizado, p.gano,
+				CASE
+					WHEN p.modalidad = 'Versus' THEN pe.posicion
+					ELSE RANK() OVER (PARTITION BY p.id_torneo ORDER BY p.puntos DESC)
+				END AS posicion
+			FROM participaciones p
+			LEFT JOIN posiciones_equipo pe ON pe.id_torneo = p.id_torneo AND pe.id_equipo = p.id_equipo
+		), totales AS (
+			SELECT r.id_jugador,
+				SUM(CASE
+					WHEN s.puntuacion = 'posicion' THEN
+						CASE WHEN r.finalizado THEN COALESCE(s.puntos_posicion[r.posicion], 0) ELSE 0 END
+					ELSE r.puntos
+				END)::int AS puntos,
+				COUNT(*)::int AS torneos,
+				(COUNT(*) FILTER (WHERE r.gano))::int AS victorias
+			FROM resultados r
+			JOIN temporadas s ON s.id = $1
+			GROUP BY r.id_jugador
+		)
+		SELECT RANK() OVER (ORDER BY tt.puntos DESC, tt.victorias DESC)::int, tt.id_jugador,
+			COALESCE(ub.nombre, ''), COALESCE(ub.apellido, ''), tt.puntos, tt.torneos, tt.victorias
+		FROM totales tt
+		LEFT JOIN user_basic_info ub ON ub.user_id = tt.id_jugador
+		ORDER BY tt.puntos DESC, tt.victorias DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(query, temporadaID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la clasificación de la temporada: %w", err)
+	}
+	defer rows.Close()
+
+	ranking := []models.RankingTemporada{}
+	for rows.Next() {
+		var fila models.RankingTemporada
+		err := rows.Scan(&fila.Posicion, &fila.UserID, &fila.Nombre, &fila.Apellido,
+			&fila.Puntos, &fila.Torneos, &fila.Victorias)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer la clasificación de la temporada: %w", err)
+		}
+		ranking = append(ranking, fila)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar la clasificación de la temporada: %w", err)
+	}
+
+	return ranking, nil
+}
+
+// consultaPlantillas selecciona las plantillas de torneos recurrentes
+const consultaPlantillas = `
+	SELECT id, id_creator, id_temporada, nombre, recurrencia, duracion_minutos, configuracion,
+		activa, proxima_ejecucion, ultima_ejecucion, created_at
+	FROM torneo_plantillas`
+
+func scanPlantilla(row interface{ Scan(...interface{}) error }) (*models.PlantillaTorneo, error) {
+	var p models.PlantillaTorneo
+	var configuracion []byte
+	err := row.Scan(&p.ID, &p.IDCreator, &p.IDTemporada, &p.Nombre, &p.Recurrencia,
+		&p.DuracionMinutos, &configuracion, &p.Activa, &p.ProximaEjecucion,
+		&p.UltimaEjecucion, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(configuracion, &p.Torneo); err != nil {
+		return nil, fmt.Errorf("error al leer la configuración de la plantilla: %w", err)
+	}
+
+	return &p, nil
+}
+
+func (r *TemporadasRepository) queryPlantillas(query string, args ...interface{}) ([]models.PlantillaTorneo, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener plantillas: %w", err)
+	}
+	defer rows.Close()
+
+	plantillas := []models.PlantillaTorneo{}
+	for rows.Next() {
+		plantilla, err := scanPlantilla(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer plantilla: %w", err)
+		}
+		plantillas = append(plantillas, *plantilla)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar plantillas: %w", err)
+	}
+
+	return plantillas, nil
+}
+
+// CreatePlantilla guarda una plantilla de torneo recurrente. La primera ejecución
+// (ProximaEjecucion) la calcula quien llama a partir de la recurrencia
+func (r *TemporadasRepository) CreatePlantilla(plantilla *models.PlantillaTorneo) error {
+	configuracion, err := json.Marshal(plantilla.Torneo)
+	if err != nil {
+		return fmt.Errorf("error al guardar la configuración de la plantilla: %w", err)
+	}
+
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		if plantilla.IDTemporada != nil {
+			err := verificarTemporada(tx, *plantilla.IDTemporada, plantilla.IDCreator, plantilla.ProximaEjecucion)
+			if err != nil && err != ErrFueraDeTemporada {
+				return err
+			}
+		}
+
+		err := tx.QueryRow(`
+			INSERT INTO torneo_plantillas (
+				id_creator, id_temporada, nombre, recurrencia, duracion_minutos,
+				configuracion, proxima_ejecucion
+			) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id, activa, created_at`,
+			plantilla.IDCreator, plantilla.IDTemporada, plantilla.Nombre, plantilla.Recurrencia,
+			plantilla.DuracionMinutos, configuracion, plantilla.ProximaEjecucion,
+		).Scan(&plantilla.ID, &plantilla.Activa, &plantilla.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("error al crear la plantilla: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *TemporadasRepository) GetPlantilla(id string) (*models.PlantillaTorneo, error) {
+	return scanPlantilla(r.db.QueryRow(consultaPlantillas+` WHERE id = $1`, id))
+}
+
+// GetPlantillasUsuario lista las plantillas creadas por un organizador
+func (r *TemporadasRepository) GetPlantillasUsuario(userID string) ([]models.PlantillaTorneo, error) {
+	return r.queryPlantillas(consultaPlantillas+`
+		WHERE id_creator = $1
+		ORDER BY created_at DESC`, userID)
+}
+
+// GetPlantillasPendientes lista las plantillas activas cuya próxima ejecución ya llegó
+func (r *TemporadasRepository) GetPlantillasPendientes() ([]models.PlantillaTorneo, error) {
+	return r.queryPlantillas(consultaPlantillas + `
+		WHERE activa = true AND proxima_ejecucion <= NOW()
+		ORDER BY proxima_ejecucion`)
+}
+
+// SetPlantillaActiva pausa o reanuda una plantilla. Al reanudarla se reprograma la
+// próxima ejecución para no crear los torneos que se omitieron mientras estaba pausada
+func (r *TemporadasRepository) SetPlantillaActiva(id string, idCreator string, activa bool, proximaEjecucion time.Time) error {
+	result, err := r.db.Exec(`
+		UPDATE torneo_plantillas
+		SET activa = $1, proxima_ejecucion = CASE WHEN $1 THEN $2 ELSE proxima_ejecucion END
+		WHERE id = $3 AND id_creator = $4`, activa, proximaEjecucion, id, idCreator)
+	if err != nil {
+		return fmt.Errorf("error al actualizar la plantilla: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrPlantillaNoEncontrada
+	}
+
+	return nil
+}
+
+// DeletePlantilla elimina una plantilla. Los torneos ya creados se conservan
+func (r *TemporadasRepository) DeletePlantilla(id string, idCreator string) error {
+	result, err := r.db.Exec(`
+		DELETE FROM torneo_plantillas
+		WHERE id = $1 AND id_creator = $2`, id, idCreator)
+	if err != nil {
+		return fmt.Errorf("error al eliminar la plantilla: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrPlantillaNoEncontrada
+	}
+
+	return nil
+}
+
+// CrearTorneoDesdePlantilla crea el torneo de la ejecución pendiente de una plantilla y
+// la reprograma para siguienteEjecucion. Si el organizador aún tiene un torneo activo
+// devuelve ErrCreadorConTorneoActivo y la plantilla queda pendiente para reintentarse;
+// si la ventana del torneo ya pasó, la ejecución se omite y se devuelve un torneo nil
+func (r *TemporadasRepository) CrearTorneoDesdePlantilla(plantilla *models.PlantillaTorneo, siguienteEjecucion time.Time) (*models.Torneo, error) {
+	torneo := plantilla.Torneo
+	torneo.CodeID = utils.GenerateUniqueFriendId(r.db, true)
+
+	var creado *models.Torneo
+	err := database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		// Bloquear la plantilla para que otra ejecución no cree el mismo torneo
+		var pendiente bool
+		err := tx.QueryRow(`
+			SELECT activa AND proxima_ejecucion = $2
+			FROM torneo_plantillas
+			WHERE id = $1
+			FOR UPDATE`, plantilla.ID, plantilla.ProximaEjecucion).Scan(&pendiente)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return fmt.Errorf("error al bloquear la plantilla: %w", err)
+		}
+
+		if !pendiente {
+			return nil
+		}
+
+		reprogramar := func() error {
+			_, err := tx.Exec(`
+				UPDATE torneo_plantillas
+				SET proxima_ejecucion = $1, ultima_ejecucion = NOW()
+				WHERE id = $2`, siguienteEjecucion, plantilla.ID)
+			if err != nil {
+				return fmt.Errorf("error al reprogramar la plantilla: %w", err)
+			}
+			return nil
+		}
+
+		// Si el intento llega tarde (por reintentos) el torneo empieza ahora y
+		// conserva la hora de fin que le correspondía
+		duracion := time.Duration(plantilla.DuracionMinutos) * time.Minute
+		ahora := time.Now()
+		torneo.FechaInicio = plantilla.ProximaEjecucion
+		torneo.FechaFin = plantilla.ProximaEjecucion.Add(duracion)
+		if !torneo.FechaFin.After(ahora) {
+			return reprogramar()
+		}
+		if torneo.FechaInicio.Before(ahora) {
+			torneo.FechaInicio = ahora
+		}
+
+		var ocupado bool
+		err = tx.QueryRow(`
+			SELECT
+				EXISTS (
+					SELECT 1 FROM torneos
+					WHERE id_creator = $1 AND finalizado = false
+				)
+				OR EXISTS (
+					SELECT 1 FROM torneo_estadisticas te
+					JOIN torneos t ON t.id = te.id_torneo
+					WHERE te.id_jugador = $1 AND t.finalizado = false
+				)`, plantilla.IDCreator).Scan(&ocupado)
+		if err != nil {
+			return fmt.Errorf("error al verificar los torneos del organizador: %w", err)
+		}
+
+		if ocupado {
+			return ErrCreadorConTorneoActivo
+		}
+
+		if torneo.Nombre == "" {
+			torneo.Nombre = plantilla.Nombre
+		}
+		torneo.IDCreator = plantilla.IDCreator
+		torneo.IDPlantilla = &plantilla.ID
+		torneo.IDTemporada = plantilla.IDTemporada
+		torneo.FechaCierreInscripcion = nil
+
+		// Las ejecuciones fuera de las fechas de la temporada se crean sin temporada
+		if torneo.IDTemporada != nil {
+			if err := verificarTemporada(tx, *torneo.IDTemporada, torneo.IDCreator, torneo.FechaInicio); err != nil {
+				if err != ErrFueraDeTemporada && err != ErrTemporadaNoEncontrada {
+					return err
+				}
+				torneo.IDTemporada = nil
+			}
+		}
+
+		if err := crearTorneo(tx, &torneo); err != nil {
+			return err
+		}
+
+		creado = &torneo
+		return reprogramar()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return creado, nil
+}
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/pkg/database"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// CancelarTorneo cancela un torneo activo conservándolo en el historial: queda finalizado
+// sin ganador, se descuenta la participación de sus jugadores, se liberan el dueño y los
+// participantes para otros torneos, se revocan las medallas por torneos jugados que ya
+// no se cumplan y se avisa a participantes, lista de espera, invitados con invitaciones
+// pendientes y coorganizadores. Las medallas por puntos o acciones no se revocan: las
+// acciones registradas durante el torneo siguen contando en las estadísticas del jugador
+func (r *TorneoRepository) CancelarTorneo(torneoID string, motivo *string) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var idCreator, nombre string
+		var finalizado bool
+		err := tx.QueryRow(`
+			SELECT id_creator, nombre, finalizado
+			FROM torneos
+			WHERE id = $1
+			FOR UPDATE`, torneoID).Scan(&idCreator, &nombre, &finalizado)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return err
+			}
+			return fmt.Errorf("error al buscar torneo: %w", err)
+		}
+
+		if finalizado {
+			return ErrTorneoFinalizado
+		}
+
+		participantes, err := listarIDs(tx, `
+			SELECT id_jugador FROM torneo_estadisticas WHERE id_torneo = $1`, torneoID)
+		if err != nil {
+			return fmt.Errorf("error al obtener los participantes: %w", err)
+		}
+
+		enEspera, err := listarIDs(tx, `
+			DELETE FROM torneo_lista_espera WHERE id_torneo = $1
+			RETURNING id_jugador`, torneoID)
+		if err != nil {
+			return fmt.Errorf("error al vaciar la lista de espera: %w", err)
+		}
+
+		coorganizadores, err := listarIDs(tx, `
+			SELECT user_id FROM torneo_staff
+			WHERE id_torneo = $1 AND rol = 'coorganizador'`, torneoID)
+		if err != nil {
+			return fmt.Errorf("error al obtener los coorganizadores: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE torneos
+			SET finalizado = true, cancelado = true, fecha_cancelacion = NOW(),
+				motivo_cancelacion = $2, ganador_equipo = NULL, ganador_individual = NULL
+			WHERE id = $1`, torneoID, motivo)
+		if err != nil {
+			return fmt.Errorf("error al cancelar el torneo: %w", err)
+		}
+
+		invitados, err := listarIDs(tx, `
+			UPDATE torneo_invitaciones
+			SET estado = 'cancelada', respondida_at = NOW()
+			WHERE id_torneo = $1 AND estado = 'pendiente'
+			RETURNING id_invitado`, torneoID)
+		if err != nil {
+			return fmt.Errorf("error al cancelar las invitaciones pendientes: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE torneo_cambios_equipo
+			SET estado = 'cancelado', resuelto_at = NOW()
+			WHERE id_torneo = $1 AND estado = 'pendiente'`, torneoID)
+		if err != nil {
+			return fmt.Errorf("error al cancelar solicitudes de cambio de equipo: %w", err)
+		}
+
+		// El torneo cancelado no cuenta como participación
+		_, err = tx.Exec(`
+			UPDATE user_stats
+			SET torneos_participados = GREATEST(0, torneos_participados - 1)
+			WHERE user_id = ANY($1::uuid[])`, pq.Array(participantes))
+		if err != nil {
+			return fmt.Errorf("error al actualizar estadísticas de los participantes: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE user_stats
+			SET torneo_id = NULL,
+				es_dueno_torneo = CASE WHEN user_id = $2 THEN false ELSE es_dueno_torneo END
+			WHERE torneo_id = $1 OR user_id = $2`, torneoID, idCreator)
+		if err != nil {
+			return fmt.Errorf("error al liberar a los usuarios del torneo: %w", err)
+		}
+
+		mensaje := fmt.Sprintf("El torneo \"%s\" fue cancelado", nombre)
+		if motivo != nil && strings.TrimSpace(*motivo) != "" {
+			mensaje += ": " + strings.TrimSpace(*motivo)
+		}
+
+		avisados := map[string]bool{idCreator: true}
+		for _, grupo := range [][]string{participantes, enEspera, invitados, coorganizadores} {
+			for _, userID := range grupo {
+				if avisados[userID] {
+					continue
+				}
+				avisados[userID] = true
+				if err := crearNotificacion(tx, userID, models.NotificacionTorneoCancelado, mensaje, &torneoID); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Las medallas por torneos jugados que ya no se cumplen se pierden y se avisa aparte
+		return revocarMedallasIncumplidas(tx, participantes, []string{models.MetricaTorneos, models.FuenteTorneos},
+			r.zonaHoraria, fmt.Sprintf("Se canceló el torneo \"%s\".", nombre), &torneoID)
+	})
+}
+
+// listarIDs ejecuta una consulta que devuelve una sola columna de IDs
+func listarIDs(tx *sql.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
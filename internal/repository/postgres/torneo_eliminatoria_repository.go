@@ -0,0 +1,527 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/internal/utils"
+	"backend_proyecto_verde/pkg/database"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/bits"
+	"time"
+)
+
+var ErrNoEsEliminatoria = errors.New("el torneo no tiene formato eliminatoria")
+
+// ordenSemillas devuelve el orden de las semillas en la primera ronda de una llave de
+// tamaño potencia de dos, de modo que las mejores semillas solo se crucen al final
+// (para 8: 1-8, 4-5, 2-7, 3-6)
+func ordenSemillas(tamano int) []int {
+	orden := []int{1}
+	for len(orden) < tamano {
+		siguiente := make([]int, 0, len(orden)*2)
+		for _, semilla := range orden {
+			siguiente = append(siguiente, semilla, 2*len(orden)+1-semilla)
+		}
+		orden = siguiente
+	}
+	return orden
+}
+
+// FindEliminatoriasEnCurso busca los torneos eliminatoria ya iniciados y sin finalizar
+func (r *TorneoRepository) FindEliminatoriasEnCurso() ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT id
+		FROM torneos
+		WHERE formato = 'eliminatoria' AND finalizado = false AND fecha_inicio <= NOW()`)
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar torneos eliminatoria: %w", err)
+	}
+	defer rows.Close()
+
+	var torneoIDs []string
+	for rows.Next() {
+		var torneoID string
+		if err := rows.Scan(&torneoID); err != nil {
+			return nil, fmt.Errorf("error al leer ID del torneo: %w", err)
+		}
+		torneoIDs = append(torneoIDs, torneoID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar resultados: %w", err)
+	}
+
+	return torneoIDs, nil
+}
+
+// AvanzarEliminatoria arma la llave de un torneo que acaba de comenzar o, si ya está
+// armada, cierra los enfrentamientos vencidos y crea la ronda siguiente cuando todos
+// los de la ronda actual tienen ganador
+func (r *TorneoRepository) AvanzarEliminatoria(torneoID string) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var modalidad string
+		var duracionRonda int
+		var finalizado bool
+		err := tx.QueryRow(`
+			SELECT modalidad, duracion_ronda_minutos, finalizado
+			FROM torneos
+			WHERE id = $1 AND formato = 'eliminatoria'
+			FOR UPDATE`, torneoID).Scan(&modalidad, &duracionRonda, &finalizado)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNoEsEliminatoria
+			}
+			return fmt.Errorf("error al buscar torneo: %w", err)
+		}
+
+		if finalizado {
+			return nil
+		}
+
+		var rondaActual int
+		err = tx.QueryRow(`
+			SELECT COALESCE(MAX(ronda), 0)
+			FROM torneo_llaves
+			WHERE id_torneo = $1`, torneoID).Scan(&rondaActual)
+		if err != nil {
+			return fmt.Errorf("error al obtener la ronda actual: %w", err)
+		}
+
+		if rondaActual == 0 {
+			return sembrarLlave(tx, torneoID, modalidad, duracionRonda)
+		}
+
+		if err := cerrarEnfrentamientosVencidos(tx, torneoID, rondaActual); err != nil {
+			return err
+		}
+
+		return crearSiguienteRonda(tx, torneoID, rondaActual, duracionRonda)
+	})
+}
+
+// sembrarLlave arma la primera ronda. Los competidores se ordenan por rating (promedio
+// de los integrantes en Versus) y las mejores semillas reciben los pases directos. Con
+// menos de dos competidores no se arma la llave y se reintenta en la siguiente pasada.
+// La fecha de fin del torneo pasa a ser el fin de la ronda final
+func sembrarLlave(tx *sql.Tx, torneoID string, modalidad string, duracionRonda int) error {
+	query := `
+		SELECT te.id_jugador
+		FROM torneo_estadisticas te
+		LEFT JOIN user_rating ur ON ur.user_id = te.id_jugador
+		WHERE te.id_torneo = $1 AND te.habilitado = true
+		ORDER BY COALESCE(ur.rating, $2) DESC, te.id_jugador`
+	if modalidad == "Versus" {
+		query = `
+			SELECT e.id
+			FROM torneo_equipos e
+			JOIN torneo_estadisticas te ON te.id_equipo = e.id AND te.habilitado = true
+			LEFT JOIN user_rating ur ON ur.user_id = te.id_jugador
+			WHERE e.id_torneo = $1
+			GROUP BY e.id
+			ORDER BY AVG(COALESCE(ur.rating, $2)) DESC, e.orden`
+	}
+
+	rows, err := tx.Query(query, torneoID, utils.RatingInicial)
+	if err != nil {
+		return fmt.Errorf("error al obtener los competidores: %w", err)
+	}
+
+	var competidores []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("error al leer competidor: %w", err)
+		}
+		competidores = append(competidores, id)
+	}
+
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("error al procesar los competidores: %w", err)
+	}
+
+	if len(competidores) < 2 {
+		return nil
+	}
+
+	// Tamaño de la llave: la menor potencia de dos que alcanza para todos
+	rondas := bits.Len(uint(len(competidores) - 1))
+	orden := ordenSemillas(1 << rondas)
+
+	for posicion := 0; posicion < len(orden)/2; posicion++ {
+		semillaA, semillaB := orden[2*posicion], orden[2*posicion+1]
+
+		competidorA := competidores[semillaA-1]
+		var competidorB, ganador *string
+		var semillaBValor *int
+		if semillaB <= len(competidores) {
+			competidorB = &competidores[semillaB-1]
+			semillaBValor = &semillaB
+		} else {
+			// Pase directo
+			ganador = &competidorA
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO torneo_llaves
+				(id_torneo, ronda, posicion, competidor_a, competidor_b, semilla_a, semilla_b, ganador, inicio, fin)
+			VALUES ($1, 1, $2, $3, $4, $5, $6, $7, NOW(), NOW() + make_interval(mins => $8))`,
+			torneoID, posicion, competidorA, competidorB, semillaA, semillaBValor, ganador, duracionRonda)
+		if err != nil {
+			return fmt.Errorf("error al crear enfrentamiento: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE torneos
+		SET fecha_fin = NOW() + make_interval(mins => $1)
+		WHERE id = $2`, duracionRonda*rondas, torneoID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar la fecha de fin del torneo: %w", err)
+	}
+
+	return nil
+}
+
+// cerrarEnfrentamientosVencidos guarda el resultado de los enfrentamientos de la ronda
+// cuyo tiempo terminó. Pasa quien sumó más puntos; ante un empate, la mejor semilla
+func cerrarEnfrentamientosVencidos(tx *sql.Tx, torneoID string, ronda int) error {
+	_, err := tx.Exec(`
+		UPDATE torneo_llaves l
+		SET puntos_a = (`+puntosEnfrentamiento("l.competidor_a")+`),
+			puntos_b = (`+puntosEnfrentamiento("l.competidor_b")+`)
+		WHERE l.id_torneo = $1 AND l.ronda = $2 AND l.ganador IS NULL AND l.fin <= NOW()`, torneoID, ronda)
+	if err != nil {
+		return fmt.Errorf("error al calcular los puntos de los enfrentamientos: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE torneo_llaves
+		SET ganador = CASE
+			WHEN puntos_b > puntos_a OR (puntos_b = puntos_a AND semilla_b < semilla_a) THEN competidor_b
+			ELSE competidor_a
+		END
+		WHERE id_torneo = $1 AND ronda = $2 AND ganador IS NULL AND puntos_a IS NOT NULL`, torneoID, ronda)
+	if err != nil {
+		return fmt.Errorf("error al cerrar los enfrentamientos: %w", err)
+	}
+
+	return nil
+}
+
+// recalcularEnfrentamiento vuelve a calcular el resultado de los enfrentamientos ya
+// cerrados que cubren el momento indicado, solo si pertenecen a la última ronda de la
+// llave: cuando ya se armó otra ronda con sus ganadores, el cruce se conserva
+func recalcularEnfrentamiento(tx *sql.Tx, torneoID string, momento time.Time) error {
+	_, err := tx.Exec(`
+		UPDATE torneo_llaves l
+		SET puntos_a = (`+puntosEnfrentamiento("l.competidor_a")+`),
+			puntos_b = (`+puntosEnfrentamiento("l.competidor_b")+`)
+		WHERE l.id_torneo = $1 AND l.ganador IS NOT NULL AND l.puntos_a IS NOT NULL
+		AND l.inicio <= $2 AND l.fin > $2
+		AND l.ronda = (SELECT MAX(ronda) FROM torneo_llaves WHERE id_torneo = $1)`, torneoID, momento)
+	if err != nil {
+		return fmt.Errorf("error al recalcular los puntos del enfrentamiento: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE torneo_llaves
+		SET ganador = CASE
+			WHEN puntos_b > puntos_a OR (puntos_b = puntos_a AND semilla_b < semilla_a) THEN competidor_b
+			ELSE competidor_a
+		END
+		WHERE id_torneo = $1 AND ganador IS NOT NULL AND puntos_a IS NOT NULL
+		AND inicio <= $2 AND fin > $2
+		AND ronda = (SELECT MAX(ronda) FROM torneo_llaves WHERE id_torneo = $1)`, torneoID, momento)
+	if err != nil {
+		return fmt.Errorf("error al recalcular el ganador del enfrentamiento: %w", err)
+	}
+
+	return nil
+}
+
+// crearSiguienteRonda cruza a los ganadores de la ronda cuando todos sus enfrentamientos
+// terminaron. La nueva ronda empieza cuando terminó la anterior
+func crearSiguienteRonda(tx *sql.Tx, torneoID string, ronda int, duracionRonda int) error {
+	rows, err := tx.Query(`
+		SELECT ganador, CASE WHEN ganador = competidor_a THEN semilla_a ELSE semilla_b END, fin
+		FROM torneo_llaves
+		WHERE id_torneo = $1 AND ronda = $2
+		ORDER BY posicion`, torneoID, ronda)
+	if err != nil {
+		return fmt.Errorf("error al obtener los ganadores de la ronda: %w", err)
+	}
+
+	var ganadores []string
+	var semillas []int
+	var fin time.Time
+	pendientes := false
+	for rows.Next() {
+		var ganador sql.NullString
+		var finEnfrentamiento time.Time
+		var semillaGanador sql.NullInt64
+		if err := rows.Scan(&ganador, &semillaGanador, &finEnfrentamiento); err != nil {
+			rows.Close()
+			return fmt.Errorf("error al leer enfrentamiento: %w", err)
+		}
+		if !ganador.Valid {
+			pendientes = true
+			continue
+		}
+		ganadores = append(ganadores, ganador.String)
+		semillas = append(semillas, int(semillaGanador.Int64))
+		if finEnfrentamiento.After(fin) {
+			fin = finEnfrentamiento
+		}
+	}
+
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("error al procesar los ganadores de la ronda: %w", err)
+	}
+
+	// La ronda sigue en juego o ya fue la final
+	if pendientes || len(ganadores) < 2 {
+		return nil
+	}
+
+	for posicion := 0; posicion < len(ganadores)/2; posicion++ {
+		_, err := tx.Exec(`
+			INSERT INTO torneo_llaves
+				(id_torneo, ronda, posicion, competidor_a, competidor_b, semilla_a, semilla_b, inicio, fin)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8::timestamp + make_interval(mins => $9))`,
+			torneoID, ronda+1, posicion, ganadores[2*posicion], ganadores[2*posicion+1],
+			semillas[2*posicion], semillas[2*posicion+1], fin, duracionRonda)
+		if err != nil {
+			return fmt.Errorf("error al crear enfrentamiento de la ronda %d: %w", ronda+1, err)
+		}
+	}
+
+	return nil
+}
+
+// campeonEliminatoria devuelve el ganador de la final, o nil si la llave no llegó a decidirse
+func campeonEliminatoria(tx *sql.Tx, torneoID string) (*string, error) {
+	var campeon *string
+	var enfrentamientos int
+	err := tx.QueryRow(`
+		SELECT ganador, COUNT(*) OVER (PARTITION BY ronda)
+		FROM torneo_llaves
+		WHERE id_torneo = $1
+		ORDER BY ronda DESC
+		LIMIT 1`, torneoID).Scan(&campeon, &enfrentamientos)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error al obtener el campeón de la llave: %w", err)
+	}
+
+	if enfrentamientos != 1 {
+		return nil, nil
+	}
+
+	return campeon, nil
+}
+
+// avanceEliminatoria devuelve para cada competidor de la llave (jugador o equipo) la
+// última ronda a la que llegó, con una ronda más para el campeón. Sirve para comparar a
+// los competidores por el resultado de la llave en lugar de por los puntos acumulados.
+// Devuelve nil si el torneo no tiene llave
+func avanceEliminatoria(tx *sql.Tx, torneoID string) (map[string]int, error) {
+	rows, err := tx.Query(`
+		SELECT c.competidor, MAX(l.ronda)
+		FROM torneo_llaves l
+		CROSS JOIN LATERAL (VALUES (l.competidor_a), (l.competidor_b)) AS c(competidor)
+		WHERE l.id_torneo = $1 AND c.competidor IS NOT NULL
+		GROUP BY c.competidor`, torneoID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el avance en la llave: %w", err)
+	}
+
+	var avance map[string]int
+	for rows.Next() {
+		var competidor string
+		var ronda int
+		if err := rows.Scan(&competidor, &ronda); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error al leer el avance en la llave: %w", err)
+		}
+		if avance == nil {
+			avance = map[string]int{}
+		}
+		avance[competidor] = ronda
+	}
+
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error al procesar el avance en la llave: %w", err)
+	}
+
+	if avance == nil {
+		return nil, nil
+	}
+
+	campeon, err := campeonEliminatoria(tx, torneoID)
+	if err != nil {
+		return nil, err
+	}
+	if campeon != nil {
+		avance[*campeon]++
+	}
+
+	return avance, nil
+}
+
+// registrarCampeonEliminatoria guarda al campeón de la llave como ganador del torneo
+// (el equipo en Versus) y suma la victoria a sus integrantes habilitados
+func registrarCampeonEliminatoria(tx *sql.Tx, torneoID string, modalidad string, campeon string) error {
+	if modalidad == "Versus" {
+		_, err := tx.Exec(`UPDATE torneos SET ganador_equipo = $1 WHERE id = $2`, campeon, torneoID)
+		if err != nil {
+			return fmt.Errorf("error al actualizar ganador: %w", err)
+		}
+
+		ganadores, err := listarIDs(tx, `
+			UPDATE user_stats
+			SET torneos_ganados = torneos_ganados + 1
+			FROM torneo_estadisticas
+			WHERE torneo_estadisticas.id_jugador = user_stats.user_id
+			AND torneo_estadisticas.id_torneo = $1
+			AND torneo_estadisticas.id_equipo = $2
+			AND torneo_estadisticas.habilitado = true
+			RETURNING user_stats.user_id`, torneoID, campeon)
+		if err != nil {
+			return fmt.Errorf("error al actualizar estadísticas de usuarios: %w", err)
+		}
+
+		return desbloquearItemsGanadores(tx, ganadores)
+	}
+
+	_, err := tx.Exec(`UPDATE torneos SET ganador_individual = $1 WHERE id = $2`, campeon, torneoID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar ganador: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE user_stats
+		SET torneos_ganados = torneos_ganados + 1
+		WHERE user_id = $1`, campeon)
+	if err != nil {
+		return fmt.Errorf("error al actualizar estadísticas del ganador: %w", err)
+	}
+
+	return desbloquearItemsGanadores(tx, []string{campeon})
+}
+
+// GetLlave obtiene la llave de un torneo eliminatoria con los puntos de cada
+// enfrentamiento (en vivo para la ronda en curso)
+func (r *TorneoRepository) GetLlave(torneoID string) (*models.LlaveTorneo, error) {
+	var formato string
+	err := r.db.QueryRow(`SELECT formato FROM torneos WHERE id = $1`, torneoID).Scan(&formato)
+	if err != nil {
+		return nil, err
+	}
+
+	if formato != models.FormatoEliminatoria {
+		return nil, ErrNoEsEliminatoria
+	}
+
+	nombreCompetidor := func(alias string) string {
+		return `COALESCE(NULLIF(TRIM(COALESCE(u` + alias + `.nombre, '') || ' ' || COALESCE(u` + alias + `.apellido, '')), ''), e` + alias + `.nombre, '')`
+	}
+
+	query := `
+		SELECT l.id, l.ronda, l.posicion, l.competidor_a, ` + nombreCompetidor("a") + `, l.semilla_a,
+			COALESCE(l.puntos_a, (` + puntosEnfrentamiento("l.competidor_a") + `)),
+			l.competidor_b, ` + nombreCompetidor("b") + `, l.semilla_b,
+			COALESCE(l.puntos_b, (` + puntosEnfrentamiento("l.competidor_b") + `)),
+			l.ganador, l.inicio, l.fin, l.inicio > NOW()
+		FROM torneo_llaves l
+		LEFT JOIN user_basic_info ua ON ua.user_id = l.competidor_a
+		LEFT JOIN torneo_equipos ea ON ea.id = l.competidor_a
+		LEFT JOIN user_basic_info ub ON ub.user_id = l.competidor_b
+		LEFT JOIN torneo_equipos eb ON eb.id = l.competidor_b
+		WHERE l.id_torneo = $1
+		ORDER BY l.ronda, l.posicion`
+
+	rows, err := r.db.Query(query, torneoID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la llave: %w", err)
+	}
+	defer rows.Close()
+
+	llave := &models.LlaveTorneo{IDTorneo: torneoID, Rondas: []models.RondaLlave{}}
+	for rows.Next() {
+		var e models.EnfrentamientoLlave
+		var idB, nombreB sql.NullString
+		var semillaB, puntosB sql.NullInt64
+		var pendiente bool
+		err := rows.Scan(&e.ID, &e.Ronda, &e.Posicion,
+			&e.CompetidorA.ID, &e.CompetidorA.Nombre, &e.CompetidorA.Semilla, &e.CompetidorA.Puntos,
+			&idB, &nombreB, &semillaB, &puntosB,
+			&e.IDGanador, &e.Inicio, &e.Fin, &pendiente)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer enfrentamiento: %w", err)
+		}
+
+		if idB.Valid {
+			e.CompetidorB = &models.CompetidorLlave{
+				ID:      idB.String,
+				Nombre:  nombreB.String,
+				Semilla: int(semillaB.Int64),
+				Puntos:  int(puntosB.Int64),
+			}
+		}
+
+		switch {
+		case e.IDGanador != nil:
+			e.Estado = models.EnfrentamientoFinalizado
+		case pendiente:
+			e.Estado = models.EnfrentamientoPendiente
+		default:
+			e.Estado = models.EnfrentamientoEnCurso
+		}
+
+		if len(llave.Rondas) < e.Ronda {
+			llave.Rondas = append(llave.Rondas, models.RondaLlave{Ronda: e.Ronda})
+		}
+		ronda := &llave.Rondas[e.Ronda-1]
+		ronda.Enfrentamientos = append(ronda.Enfrentamientos, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar la llave: %w", err)
+	}
+
+	if len(llave.Rondas) > 0 {
+		primeraRonda := len(llave.Rondas[0].Enfrentamientos)
+		llave.TotalRondas = bits.Len(uint(primeraRonda))
+		llave.RondaActual = len(llave.Rondas)
+
+		ultima := llave.Rondas[len(llave.Rondas)-1]
+		if llave.RondaActual == llave.TotalRondas && len(ultima.Enfrentamientos) == 1 {
+			llave.IDCampeon = ultima.Enfrentamientos[0].IDGanador
+		}
+	}
+
+	return llave, nil
+}
+
+// puntosEnfrentamiento es la subconsulta (sobre torneo_llaves l) con los puntos de torneo
+// que un competidor sumó durante el enfrentamiento: los de un jugador o, en Versus, los
+// de los integrantes habilitados de su equipo
+func puntosEnfrentamiento(competidor string) string {
+	return `
+		SELECT COALESCE(SUM(pa.puntos_torneo), 0)
+		FROM user_actions pa
+		JOIN torneo_estadisticas pte ON pte.id_torneo = pa.id_torneo AND pte.id_jugador = pa.user_id
+		WHERE pa.id_torneo = l.id_torneo AND pa.deleted_at IS NULL AND pte.habilitado = true
+		AND (pte.id_jugador = ` + competidor + ` OR pte.id_equipo = ` + competidor + `)
+		AND pa.created_at >= l.inicio AND pa.created_at < l.fin`
+}
@@ -0,0 +1,664 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/internal/utils"
+	"backend_proyecto_verde/pkg/database"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrEquipoNoEncontrado        = errors.New("el equipo no existe en este torneo")
+	ErrEquipoRequerido           = errors.New("debes elegir un equipo para este torneo")
+	ErrEquipoConIntegrantes      = errors.New("el equipo tiene integrantes y no puede eliminarse")
+	ErrEquiposBloqueados         = errors.New("los equipos de este torneo están bloqueados")
+	ErrCambioEquipoDeshabilitado = errors.New("este torneo no permite cambiar de equipo")
+	ErrTorneoIniciado            = errors.New("el torneo ya comenzó")
+	ErrMismoEquipo               = errors.New("ya perteneces a ese equipo")
+	ErrCambioNoEncontrado        = errors.New("la solicitud de cambio de equipo no existe o ya fue resuelta")
+	ErrNoEsVersus                = errors.New("solo los torneos en modalidad Versus tienen equipos")
+	ErrCapitanNoIntegrante       = errors.New("el capitán debe ser integrante del equipo")
+)
+
+// coloresEquipos son los colores asignados a los equipos creados sin color
+var coloresEquipos = []string{"#2E7D32", "#1565C0", "#C62828", "#F9A825", "#6A1B9A", "#EF6C00"}
+
+// equiposPorDefecto devuelve los dos equipos usados cuando un torneo Versus se crea sin equipos
+func equiposPorDefecto() []models.TorneoEquipo {
+	return []models.TorneoEquipo{
+		{Nombre: "Equipo A", Color: coloresEquipos[0]},
+		{Nombre: "Equipo B", Color: coloresEquipos[1]},
+	}
+}
+
+// insertarEquipos crea los equipos de un torneo dentro de una transacción
+func insertarEquipos(tx *sql.Tx, torneoID string, equipos []models.TorneoEquipo) error {
+	query := `
+		INSERT INTO torneo_equipos (id_torneo, nombre, color, id_capitan, orden)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+
+	for i := range equipos {
+		equipos[i].IDTorneo = torneoID
+		equipos[i].Orden = i
+		if equipos[i].Color == "" {
+			equipos[i].Color = coloresEquipos[i%len(coloresEquipos)]
+		}
+
+		err := tx.QueryRow(
+			query,
+			torneoID,
+			equipos[i].Nombre,
+			equipos[i].Color,
+			equipos[i].IDCapitan,
+			equipos[i].Orden,
+		).Scan(&equipos[i].ID)
+		if err != nil {
+			return fmt.Errorf("error al crear equipo %s: %w", equipos[i].Nombre, err)
+		}
+	}
+
+	return nil
+}
+
+// resolverEquipo valida el equipo elegido por el usuario al inscribirse. Si no se
+// envía un ID de equipo, el booleano heredado selecciona el primer (true) o
+// segundo (false) equipo del torneo
+func resolverEquipo(tx *sql.Tx, torneoID string, equipoID *string, equipoLegacy *bool) (*string, error) {
+	if equipoID != nil && *equipoID != "" {
+		var exists bool
+		err := tx.QueryRow(`
+			SELECT EXISTS (
+				SELECT 1 FROM torneo_equipos WHERE id = $1 AND id_torneo = $2
+			)`, *equipoID, torneoID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("error al verificar equipo: %w", err)
+		}
+		if !exists {
+			return nil, ErrEquipoNoEncontrado
+		}
+		return equipoID, nil
+	}
+
+	if equipoLegacy == nil {
+		return nil, ErrEquipoRequerido
+	}
+
+	orden := 1
+	if *equipoLegacy {
+		orden = 0
+	}
+
+	var id string
+	err := tx.QueryRow(`
+		SELECT id
+		FROM torneo_equipos
+		WHERE id_torneo = $1
+		ORDER BY orden
+		OFFSET $2 LIMIT 1`, torneoID, orden).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrEquipoNoEncontrado
+		}
+		return nil, fmt.Errorf("error al buscar equipo: %w", err)
+	}
+
+	return &id, nil
+}
+
+// asignarEquipoAutomatico elige el equipo para un nuevo integrante según el modo de
+// asignación del torneo. En modo "cantidad" se elige el equipo con menos integrantes.
+// En los modos "puntos" (user_stats.puntos) y "rating" (rating de habilidad) se
+// consideran los equipos con menos integrantes y, entre ellos, el que tras sumar al
+// jugador queda con el promedio más cercano al promedio de todos los jugadores del
+// torneo: un jugador fuerte va al equipo más débil y uno débil al más fuerte. Los
+// empates se resuelven por el orden del equipo. Los equipos que alcanzaron
+// maxPorEquipo se descartan; si todos están llenos se devuelve ErrEquiposLlenos
+func asignarEquipoAutomatico(tx *sql.Tx, torneoID string, userID string, asignacion string, maxPorEquipo *int) (*string, error) {
+	var valor string
+	switch asignacion {
+	case models.AsignacionEquiposCantidad:
+		valor = "0"
+	case models.AsignacionEquiposPuntos:
+		valor = "COALESCE(us.puntos, 0)"
+	case models.AsignacionEquiposRating:
+		// Los jugadores sin torneos completados cuentan con el rating inicial
+		valor = fmt.Sprintf("COALESCE(ur.rating, %d)", utils.RatingInicial)
+	default:
+		return nil, fmt.Errorf("modo de asignación de equipos no válido: %s", asignacion)
+	}
+
+	rows, err := tx.Query(`
+		SELECT e.id, COUNT(te.id)::int, COALESCE(SUM(`+valor+`), 0)::float8
+		FROM torneo_equipos e
+		LEFT JOIN torneo_estadisticas te ON te.id_equipo = e.id
+		LEFT JOIN user_stats us ON us.user_id = te.id_jugador
+		LEFT JOIN user_rating ur ON ur.user_id = te.id_jugador
+		WHERE e.id_torneo = $1
+		GROUP BY e.id
+		ORDER BY e.orden`, torneoID)
+	if err != nil {
+		return nil, fmt.Errorf("error al asignar equipo: %w", err)
+	}
+
+	type equipoCandidato struct {
+		id          string
+		integrantes int
+		suma        float64
+	}
+
+	var equipos []equipoCandidato
+	var totalIntegrantes int
+	var totalSuma float64
+	for rows.Next() {
+		var e equipoCandidato
+		if err := rows.Scan(&e.id, &e.integrantes, &e.suma); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error al leer equipo: %w", err)
+		}
+		equipos = append(equipos, e)
+		totalIntegrantes += e.integrantes
+		totalSuma += e.suma
+	}
+
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error al procesar equipos: %w", err)
+	}
+
+	if len(equipos) == 0 {
+		return nil, ErrEquipoNoEncontrado
+	}
+
+	// Valor del jugador que se está ubicando
+	var valorJugador float64
+	err = tx.QueryRow(`
+		SELECT COALESCE(MAX(`+valor+`), 0)::float8
+		FROM user_access u
+		LEFT JOIN user_stats us ON us.user_id = u.id
+		LEFT JOIN user_rating ur ON ur.user_id = u.id
+		WHERE u.id = $1`, userID).Scan(&valorJugador)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los puntos del jugador: %w", err)
+	}
+
+	promedioGeneral := (totalSuma + valorJugador) / float64(totalIntegrantes+1)
+
+	var elegido *equipoCandidato
+	var mejorDiferencia float64
+	for i := range equipos {
+		e := &equipos[i]
+		if maxPorEquipo != nil && e.integrantes >= *maxPorEquipo {
+			continue
+		}
+
+		diferencia := math.Abs((e.suma+valorJugador)/float64(e.integrantes+1) - promedioGeneral)
+		if elegido == nil || e.integrantes < elegido.integrantes ||
+			(e.integrantes == elegido.integrantes && diferencia < mejorDiferencia) {
+			elegido = e
+			mejorDiferencia = diferencia
+		}
+	}
+
+	if elegido == nil {
+		return nil, ErrEquiposLlenos
+	}
+
+	return &elegido.id, nil
+}
+
+// GetEquiposTorneo obtiene los equipos de un torneo con su número de integrantes y puntos
+func (r *TorneoRepository) GetEquiposTorneo(torneoID string) ([]models.TorneoEquipo, error) {
+	return r.queryEquipos(torneoID, "e.orden")
+}
+
+// GetRankingEquipos obtiene los equipos de un torneo ordenados por puntos
+func (r *TorneoRepository) GetRankingEquipos(torneoID string) ([]models.TorneoEquipo, error) {
+	return r.queryEquipos(torneoID, "puntos DESC, e.orden")
+}
+
+func (r *TorneoRepository) queryEquipos(torneoID string, orderBy string) ([]models.TorneoEquipo, error) {
+	query := `
+		SELECT e.id, e.id_torneo, e.nombre, e.color, e.id_capitan, e.orden,
+			COUNT(te.id) AS integrantes,
+			COALESCE(SUM(CASE WHEN te.habilitado THEN te.puntos ELSE 0 END), 0) AS puntos
+		FROM torneo_equipos e
+		LEFT JOIN torneo_estadisticas te ON te.id_equipo = e.id
+		WHERE e.id_torneo = $1
+		GROUP BY e.id
+		ORDER BY ` + orderBy
+
+	rows, err := r.db.Query(query, torneoID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener equipos: %w", err)
+	}
+	defer rows.Close()
+
+	equipos := []models.TorneoEquipo{}
+	for rows.Next() {
+		var e models.TorneoEquipo
+		if err := rows.Scan(&e.ID, &e.IDTorneo, &e.Nombre, &e.Color, &e.IDCapitan,
+			&e.Orden, &e.Integrantes, &e.Puntos); err != nil {
+			return nil, fmt.Errorf("error al leer equipo: %w", err)
+		}
+		equipos = append(equipos, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar equipos: %w", err)
+	}
+
+	return equipos, nil
+}
+
+// GetEquipo obtiene un equipo de un torneo con su número de integrantes y puntos
+func (r *TorneoRepository) GetEquipo(torneoID string, equipoID string) (*models.TorneoEquipo, error) {
+	query := `
+		SELECT e.id, e.id_torneo, e.nombre, e.color, e.id_capitan, e.orden,
+			COUNT(te.id) AS integrantes,
+			COALESCE(SUM(CASE WHEN te.habilitado THEN te.puntos ELSE 0 END), 0) AS puntos
+		FROM torneo_equipos e
+		LEFT JOIN torneo_estadisticas te ON te.id_equipo = e.id
+		WHERE e.id_torneo = $1 AND e.id = $2
+		GROUP BY e.id`
+
+	var e models.TorneoEquipo
+	err := r.db.QueryRow(query, torneoID, equipoID).Scan(&e.ID, &e.IDTorneo, &e.Nombre,
+		&e.Color, &e.IDCapitan, &e.Orden, &e.Integrantes, &e.Puntos)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrEquipoNoEncontrado
+		}
+		return nil, fmt.Errorf("error al obtener equipo: %w", err)
+	}
+
+	return &e, nil
+}
+
+// CreateEquipo agrega un equipo a un torneo Versus existente
+func (r *TorneoRepository) CreateEquipo(equipo *models.TorneoEquipo) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var modalidad string
+		err := tx.QueryRow(`SELECT modalidad FROM torneos WHERE id = $1`, equipo.IDTorneo).Scan(&modalidad)
+		if err != nil {
+			return fmt.Errorf("error al buscar torneo: %w", err)
+		}
+
+		if modalidad != "Versus" {
+			return ErrNoEsVersus
+		}
+
+		// Un equipo nuevo no tiene integrantes, así que todavía no puede tener capitán
+		if equipo.IDCapitan != nil {
+			return ErrCapitanNoIntegrante
+		}
+
+		var siguienteOrden int
+		err = tx.QueryRow(`
+			SELECT COALESCE(MAX(orden) + 1, 0)
+			FROM torneo_equipos
+			WHERE id_torneo = $1`, equipo.IDTorneo).Scan(&siguienteOrden)
+		if err != nil {
+			return fmt.Errorf("error al calcular el orden del equipo: %w", err)
+		}
+
+		equipo.Orden = siguienteOrden
+		if equipo.Color == "" {
+			equipo.Color = coloresEquipos[siguienteOrden%len(coloresEquipos)]
+		}
+
+		query := `
+			INSERT INTO torneo_equipos (id_torneo, nombre, color, id_capitan, orden)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id`
+
+		err = tx.QueryRow(query, equipo.IDTorneo, equipo.Nombre, equipo.Color,
+			equipo.IDCapitan, equipo.Orden).Scan(&equipo.ID)
+		if err != nil {
+			return fmt.Errorf("error al crear equipo: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// UpdateEquipo actualiza el nombre, color y capitán de un equipo. El capitán debe ser
+// integrante del equipo
+func (r *TorneoRepository) UpdateEquipo(equipo *models.TorneoEquipo) error {
+	if equipo.IDCapitan != nil {
+		var integrante bool
+		err := r.db.QueryRow(`
+			SELECT EXISTS (
+				SELECT 1 FROM torneo_estadisticas
+				WHERE id_torneo = $1 AND id_equipo = $2 AND id_jugador = $3
+			)`, equipo.IDTorneo, equipo.ID, *equipo.IDCapitan).Scan(&integrante)
+		if err != nil {
+			return fmt.Errorf("error al verificar al capitán: %w", err)
+		}
+
+		if !integrante {
+			return ErrCapitanNoIntegrante
+		}
+	}
+
+	query := `
+		UPDATE torneo_equipos
+		SET nombre = $1, color = $2, id_capitan = $3
+		WHERE id = $4 AND id_torneo = $5`
+
+	result, err := r.db.Exec(query, equipo.Nombre, equipo.Color, equipo.IDCapitan,
+		equipo.ID, equipo.IDTorneo)
+	if err != nil {
+		return fmt.Errorf("error al actualizar equipo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEquipoNoEncontrado
+	}
+
+	return nil
+}
+
+// DeleteEquipo elimina un equipo sin integrantes de un torneo
+func (r *TorneoRepository) DeleteEquipo(torneoID string, equipoID string) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var integrantes int
+		err := tx.QueryRow(`
+			SELECT COUNT(*)
+			FROM torneo_estadisticas
+			WHERE id_equipo = $1`, equipoID).Scan(&integrantes)
+		if err != nil {
+			return fmt.Errorf("error al verificar integrantes: %w", err)
+		}
+
+		if integrantes > 0 {
+			return ErrEquipoConIntegrantes
+		}
+
+		result, err := tx.Exec(`
+			DELETE FROM torneo_equipos
+			WHERE id = $1 AND id_torneo = $2`, equipoID, torneoID)
+		if err != nil {
+			return fmt.Errorf("error al eliminar equipo: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrEquipoNoEncontrado
+		}
+
+		return nil
+	})
+}
+
+// UpdateConfiguracionEquipos actualiza las reglas de asignación y cambio de equipos de un torneo
+func (r *TorneoRepository) UpdateConfiguracionEquipos(torneo *models.Torneo) error {
+	query := `
+		UPDATE torneos
+		SET asignacion_equipos = $1, equipos_bloqueados = $2,
+			permitir_cambio_equipo = $3, cambio_requiere_aprobacion = $4
+		WHERE id = $5`
+
+	result, err := r.db.Exec(query, torneo.AsignacionEquipos, torneo.EquiposBloqueados,
+		torneo.PermitirCambioEquipo, torneo.CambioRequiereAprobacion, torneo.ID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar la configuración de equipos: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// verificarCambioEquipoPermitido comprueba, bloqueando la fila del torneo, que aún se
+// puedan hacer cambios de equipo. Devuelve si los cambios requieren aprobación
+func verificarCambioEquipoPermitido(tx *sql.Tx, torneoID string) (bool, error) {
+	query := `
+		SELECT modalidad, finalizado, fecha_inicio <= NOW(), equipos_bloqueados,
+			permitir_cambio_equipo, cambio_requiere_aprobacion
+		FROM torneos
+		WHERE id = $1
+		FOR UPDATE`
+
+	var modalidad string
+	var finalizado, iniciado, bloqueados, permitido, requiereAprobacion bool
+	err := tx.QueryRow(query, torneoID).Scan(&modalidad, &finalizado, &iniciado,
+		&bloqueados, &permitido, &requiereAprobacion)
+	if err != nil {
+		return false, fmt.Errorf("error al buscar torneo: %w", err)
+	}
+
+	switch {
+	case modalidad != "Versus":
+		return false, ErrNoEsVersus
+	case finalizado || iniciado:
+		return false, ErrTorneoIniciado
+	case bloqueados:
+		return false, ErrEquiposBloqueados
+	case !permitido:
+		return false, ErrCambioEquipoDeshabilitado
+	}
+
+	return requiereAprobacion, nil
+}
+
+// SolicitarCambioEquipo registra la solicitud de un jugador para cambiarse de equipo
+// antes del inicio del torneo. Si el torneo no requiere aprobación el cambio se
+// aplica de inmediato. Una nueva solicitud cancela la pendiente del mismo jugador
+func (r *TorneoRepository) SolicitarCambioEquipo(torneoID string, userID string, equipoDestino string) (*models.CambioEquipo, error) {
+	cambio := &models.CambioEquipo{
+		IDTorneo:        torneoID,
+		IDJugador:       userID,
+		IDEquipoDestino: equipoDestino,
+	}
+
+	err := database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		requiereAprobacion, err := verificarCambioEquipoPermitido(tx, torneoID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(`
+			SELECT id_equipo
+			FROM torneo_estadisticas
+			WHERE id_torneo = $1 AND id_jugador = $2`, torneoID, userID).Scan(&cambio.IDEquipoOrigen)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrUsuarioNoInscrito
+			}
+			return fmt.Errorf("error al obtener equipo del usuario: %w", err)
+		}
+
+		if _, err := resolverEquipo(tx, torneoID, &equipoDestino, nil); err != nil {
+			return err
+		}
+
+		if cambio.IDEquipoOrigen != nil && *cambio.IDEquipoOrigen == equipoDestino {
+			return ErrMismoEquipo
+		}
+
+		// Cancelar solicitudes pendientes anteriores del jugador
+		_, err = tx.Exec(`
+			UPDATE torneo_cambios_equipo
+			SET estado = 'cancelado', resuelto_at = NOW()
+			WHERE id_torneo = $1 AND id_jugador = $2 AND estado = 'pendiente'`, torneoID, userID)
+		if err != nil {
+			return fmt.Errorf("error al cancelar solicitudes anteriores: %w", err)
+		}
+
+		cambio.Estado = "pendiente"
+		if !requiereAprobacion {
+			cambio.Estado = "aprobado"
+			if err := moverJugadorEquipo(tx, torneoID, userID, equipoDestino); err != nil {
+				return err
+			}
+		}
+
+		insertQuery := `
+			INSERT INTO torneo_cambios_equipo (
+				id_torneo, id_jugador, id_equipo_origen, id_equipo_destino, estado, resuelto_at
+			) VALUES ($1, $2, $3, $4, $5, CASE WHEN $6 THEN NOW() END)
+			RETURNING id, created_at, resuelto_at`
+
+		err = tx.QueryRow(insertQuery, torneoID, userID, cambio.IDEquipoOrigen,
+			equipoDestino, cambio.Estado, !requiereAprobacion).Scan(&cambio.ID, &cambio.CreatedAt, &cambio.ResueltoAt)
+		if err != nil {
+			return fmt.Errorf("error al registrar el cambio de equipo: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cambio, nil
+}
+
+// GetCambiosEquipo obtiene las solicitudes de cambio de equipo de un torneo.
+// Si estado está vacío se devuelven todas
+func (r *TorneoRepository) GetCambiosEquipo(torneoID string, estado string) ([]models.CambioEquipo, error) {
+	query := `
+		SELECT id, id_torneo, id_jugador, id_equipo_origen, id_equipo_destino,
+			estado, created_at, resuelto_at
+		FROM torneo_cambios_equipo
+		WHERE id_torneo = $1 AND ($2::text = '' OR estado = $2::text)
+		ORDER BY created_at`
+
+	rows, err := r.db.Query(query, torneoID, estado)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener solicitudes de cambio de equipo: %w", err)
+	}
+	defer rows.Close()
+
+	cambios := []models.CambioEquipo{}
+	for rows.Next() {
+		var c models.CambioEquipo
+		if err := rows.Scan(&c.ID, &c.IDTorneo, &c.IDJugador, &c.IDEquipoOrigen,
+			&c.IDEquipoDestino, &c.Estado, &c.CreatedAt, &c.ResueltoAt); err != nil {
+			return nil, fmt.Errorf("error al leer solicitud de cambio de equipo: %w", err)
+		}
+		cambios = append(cambios, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar solicitudes de cambio de equipo: %w", err)
+	}
+
+	return cambios, nil
+}
+
+// ResolverCambioEquipo aprueba o rechaza una solicitud pendiente de cambio de equipo.
+// Al aprobar se vuelven a comprobar las reglas del torneo antes de mover al jugador
+func (r *TorneoRepository) ResolverCambioEquipo(torneoID string, cambioID string, aprobar bool) (*models.CambioEquipo, error) {
+	var cambio models.CambioEquipo
+
+	err := database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		// Bloquear primero el torneo, en el mismo orden que SolicitarCambioEquipo, para
+		// evitar bloqueos mutuos con las solicitudes del jugador
+		var bloqueado string
+		err := tx.QueryRow(`SELECT id FROM torneos WHERE id = $1 FOR UPDATE`, torneoID).Scan(&bloqueado)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrCambioNoEncontrado
+			}
+			return fmt.Errorf("error al buscar torneo: %w", err)
+		}
+
+		query := `
+			SELECT id, id_torneo, id_jugador, id_equipo_origen, id_equipo_destino, estado, created_at
+			FROM torneo_cambios_equipo
+			WHERE id = $1 AND id_torneo = $2 AND estado = 'pendiente'
+			FOR UPDATE`
+
+		err = tx.QueryRow(query, cambioID, torneoID).Scan(&cambio.ID, &cambio.IDTorneo,
+			&cambio.IDJugador, &cambio.IDEquipoOrigen, &cambio.IDEquipoDestino,
+			&cambio.Estado, &cambio.CreatedAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrCambioNoEncontrado
+			}
+			return fmt.Errorf("error al obtener la solicitud de cambio de equipo: %w", err)
+		}
+
+		cambio.Estado = "rechazado"
+		if aprobar {
+			if _, err := verificarCambioEquipoPermitido(tx, torneoID); err != nil {
+				return err
+			}
+			if err := moverJugadorEquipo(tx, torneoID, cambio.IDJugador, cambio.IDEquipoDestino); err != nil {
+				return err
+			}
+			cambio.Estado = "aprobado"
+		}
+
+		err = tx.QueryRow(`
+			UPDATE torneo_cambios_equipo
+			SET estado = $1, resuelto_at = NOW()
+			WHERE id = $2
+			RETURNING resuelto_at`, cambio.Estado, cambio.ID).Scan(&cambio.ResueltoAt)
+		if err != nil {
+			return fmt.Errorf("error al resolver la solicitud de cambio de equipo: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &cambio, nil
+}
+
+// moverJugadorEquipo asigna un nuevo equipo a un jugador inscrito en el torneo,
+// respetando el cupo por equipo. El lugar liberado en el equipo de origen se
+// ofrece a la lista de espera
+func moverJugadorEquipo(tx *sql.Tx, torneoID string, userID string, equipoID string) error {
+	lleno, err := equipoLleno(tx, torneoID, equipoID)
+	if err != nil {
+		return err
+	}
+
+	if lleno {
+		return ErrEquipoLleno
+	}
+
+	result, err := tx.Exec(`
+		UPDATE torneo_estadisticas
+		SET id_equipo = $1
+		WHERE id_torneo = $2 AND id_jugador = $3`, equipoID, torneoID, userID)
+	if err != nil {
+		return fmt.Errorf("error al cambiar de equipo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUsuarioNoInscrito
+	}
+
+	return promoverListaEspera(tx, torneoID)
+}
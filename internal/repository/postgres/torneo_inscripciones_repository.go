@@ -0,0 +1,489 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInscripcionCerrada = errors.New("las inscripciones de este torneo están cerradas")
+	ErrYaEnListaEspera    = errors.New("ya estás en la lista de espera de este torneo")
+	ErrEquipoLleno        = errors.New("el equipo alcanzó el máximo de integrantes")
+	ErrEquiposLlenos      = errors.New("todos los equipos alcanzaron el máximo de integrantes")
+	ErrTorneoPrivado      = errors.New("este torneo es privado: solo se puede entrar con una invitación")
+	ErrDuenoTorneoActivo  = errors.New("eres dueño de un torneo activo")
+	ErrEnTorneoActivo     = errors.New("ya estás registrado en un torneo activo")
+)
+
+// reglasInscripcion son las reglas de un torneo que deciden si un jugador puede
+// ocupar un lugar. Se cargan con la fila del torneo bloqueada
+type reglasInscripcion struct {
+	torneoID           string
+	modalidad          string
+	asignacion         string
+	visibilidad        string
+	maxParticipantes   *int
+	maxPorEquipo       *int
+	finalizado         bool
+	terminado          bool // Pasó la fecha de fin (el torneo puede estar en verificación)
+	inscripcionCerrada bool
+}
+
+// cargarReglasInscripcion lee y bloquea la fila del torneo hasta el final de la transacción.
+// Devuelve sql.ErrNoRows si el torneo no existe
+func cargarReglasInscripcion(tx *sql.Tx, torneoID string) (*reglasInscripcion, error) {
+	query := `
+		SELECT id, modalidad, asignacion_equipos, visibilidad, max_participantes, max_por_equipo,
+			finalizado, fecha_fin <= NOW(), COALESCE(fecha_cierre_inscripcion <= NOW(), false)
+				OR EXISTS (SELECT 1 FROM torneo_llaves WHERE id_torneo = torneos.id)
+		FROM torneos
+		WHERE id = $1
+		FOR UPDATE`
+
+	reglas := &reglasInscripcion{}
+	err := tx.QueryRow(query, torneoID).Scan(&reglas.torneoID, &reglas.modalidad,
+		&reglas.asignacion, &reglas.visibilidad, &reglas.maxParticipantes, &reglas.maxPorEquipo,
+		&reglas.finalizado, &reglas.terminado, &reglas.inscripcionCerrada)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error al buscar torneo: %w", err)
+	}
+
+	return reglas, nil
+}
+
+// equipoLleno indica si un equipo alcanzó el máximo de integrantes del torneo
+func equipoLleno(tx *sql.Tx, torneoID string, equipoID string) (bool, error) {
+	query := `
+		SELECT t.max_por_equipo IS NOT NULL AND COUNT(te.id) >= t.max_por_equipo
+		FROM torneos t
+		LEFT JOIN torneo_estadisticas te ON te.id_torneo = t.id AND te.id_equipo = $2
+		WHERE t.id = $1
+		GROUP BY t.id`
+
+	var lleno bool
+	if err := tx.QueryRow(query, torneoID, equipoID).Scan(&lleno); err != nil {
+		return false, fmt.Errorf("error al verificar el cupo del equipo: %w", err)
+	}
+
+	return lleno, nil
+}
+
+// ubicarJugador resuelve el equipo del jugador e indica si hay cupo para inscribirlo.
+// Cuando no hay cupo devuelve el equipo preferido (si lo hay) para guardarlo en la lista de espera
+func (reglas *reglasInscripcion) ubicarJugador(tx *sql.Tx, userID string, equipoID *string, equipoLegacy *bool) (*string, bool, error) {
+	var idEquipo *string
+	var err error
+
+	versus := reglas.modalidad == "Versus"
+	libre := reglas.asignacion == models.AsignacionEquiposLibre
+
+	if versus && libre {
+		idEquipo, err = resolverEquipo(tx, reglas.torneoID, equipoID, equipoLegacy)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if reglas.maxParticipantes != nil {
+		var participantes int
+		err = tx.QueryRow(`
+			SELECT COUNT(*) FROM torneo_estadisticas
+			WHERE id_torneo = $1`, reglas.torneoID).Scan(&participantes)
+		if err != nil {
+			return nil, false, fmt.Errorf("error al contar participantes: %w", err)
+		}
+
+		if participantes >= *reglas.maxParticipantes {
+			return idEquipo, false, nil
+		}
+	}
+
+	if !versus {
+		return nil, true, nil
+	}
+
+	if libre {
+		lleno, err := equipoLleno(tx, reglas.torneoID, *idEquipo)
+		if err != nil {
+			return nil, false, err
+		}
+		return idEquipo, !lleno, nil
+	}
+
+	idEquipo, err = asignarEquipoAutomatico(tx, reglas.torneoID, userID, reglas.asignacion, reglas.maxPorEquipo)
+	if err != nil {
+		if errors.Is(err, ErrEquiposLlenos) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return idEquipo, true, nil
+}
+
+// inscribirEnTorneo aplica las reglas de inscripción con la fila del torneo bloqueada
+// e inscribe al jugador o lo deja en lista de espera. conInvitacion permite entrar a
+// torneos privados
+func inscribirEnTorneo(tx *sql.Tx, torneoID string, userID string, equipoID *string, equipoLegacy *bool, conInvitacion bool) (*models.Inscripcion, error) {
+	// Bloquear la fila del torneo para que las inscripciones concurrentes vean
+	// los participantes y equipos ya asignados
+	reglas, err := cargarReglasInscripcion(tx, torneoID)
+	if err != nil {
+		return nil, err
+	}
+
+	if reglas.finalizado {
+		return nil, ErrTorneoFinalizado
+	}
+
+	if reglas.inscripcionCerrada || reglas.terminado {
+		return nil, ErrInscripcionCerrada
+	}
+
+	// A un torneo privado solo se entra con invitación
+	if reglas.visibilidad == models.VisibilidadPrivada && !conInvitacion {
+		return nil, ErrTorneoPrivado
+	}
+
+	// Un jugador baneado no puede volver a entrar al torneo
+	baneado, err := estaBaneado(tx, torneoID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if baneado {
+		return nil, ErrUsuarioBaneado
+	}
+
+	// Los organizadores del torneo no pueden jugarlo
+	esStaff, err := esStaffTorneo(tx, torneoID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if esStaff {
+		return nil, ErrOrganizadorNoParticipa
+	}
+
+	if err := verificarJugadorDisponible(tx, userID); err != nil {
+		return nil, err
+	}
+
+	// Verificar si el usuario ya está inscrito o en lista de espera
+	checkQuery := `
+		SELECT
+			EXISTS (
+				SELECT 1 FROM torneo_estadisticas
+				WHERE id_torneo = $1 AND id_jugador = $2
+			),
+			EXISTS (
+				SELECT 1 FROM torneo_lista_espera
+				WHERE id_torneo = $1 AND id_jugador = $2
+			)`
+
+	var inscrito, enEspera bool
+	err = tx.QueryRow(checkQuery, torneoID, userID).Scan(&inscrito, &enEspera)
+	if err != nil {
+		return nil, fmt.Errorf("error al verificar inscripción: %w", err)
+	}
+
+	if inscrito {
+		return nil, fmt.Errorf("el usuario ya está inscrito en este torneo")
+	}
+
+	if enEspera {
+		return nil, ErrYaEnListaEspera
+	}
+
+	return inscribirOEsperar(tx, reglas, userID, equipoID, equipoLegacy)
+}
+
+// verificarJugadorDisponible comprueba, con la fila de estadísticas del jugador
+// bloqueada, que no sea dueño de un torneo activo ni participe en otro. Aplica las
+// mismas restricciones que GetIfTournamentOwner dentro de la transacción de la
+// inscripción, así dos inscripciones simultáneas no pueden dejarlo en dos torneos
+func verificarJugadorDisponible(tx *sql.Tx, userID string) error {
+	var esDueno bool
+	err := tx.QueryRow(`
+		SELECT es_dueno_torneo FROM user_stats
+		WHERE user_id = $1
+		FOR UPDATE`, userID).Scan(&esDueno)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("error al obtener estadísticas del usuario: %w", err)
+	}
+
+	var nombre string
+	err = tx.QueryRow(`
+		SELECT nombre FROM torneos
+		WHERE id_creator = $1 AND finalizado = false
+		LIMIT 1`, userID).Scan(&nombre)
+	if err == nil {
+		return fmt.Errorf("%w: %s", ErrDuenoTorneoActivo, nombre)
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("error al buscar torneos del usuario: %w", err)
+	}
+
+	err = tx.QueryRow(`
+		SELECT t.nombre
+		FROM torneo_estadisticas te
+		JOIN torneos t ON te.id_torneo = t.id
+		WHERE te.id_jugador = $1 AND t.finalizado = false
+		LIMIT 1`, userID).Scan(&nombre)
+	if err == nil {
+		return fmt.Errorf("%w: %s", ErrEnTorneoActivo, nombre)
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("error al buscar torneos del usuario: %w", err)
+	}
+
+	if esDueno {
+		return ErrDuenoTorneoActivo
+	}
+
+	return nil
+}
+
+// inscribirOEsperar inscribe al jugador si hay cupo o lo agrega a la lista de espera
+func inscribirOEsperar(tx *sql.Tx, reglas *reglasInscripcion, userID string, equipoID *string, equipoLegacy *bool) (*models.Inscripcion, error) {
+	idEquipo, hayCupo, err := reglas.ubicarJugador(tx, userID, equipoID, equipoLegacy)
+	if err != nil {
+		return nil, err
+	}
+
+	inscripcion := &models.Inscripcion{
+		IDTorneo: reglas.torneoID,
+		IDEquipo: idEquipo,
+	}
+
+	if hayCupo {
+		if err := insertarParticipante(tx, reglas, userID, idEquipo); err != nil {
+			return nil, err
+		}
+		inscripcion.Estado = models.InscripcionInscrito
+		return inscripcion, nil
+	}
+
+	insertQuery := `
+		INSERT INTO torneo_lista_espera (id_torneo, id_jugador, id_equipo)
+		VALUES ($1, $2, $3)`
+
+	if _, err := tx.Exec(insertQuery, reglas.torneoID, userID, idEquipo); err != nil {
+		return nil, fmt.Errorf("error al agregar a la lista de espera: %w", err)
+	}
+
+	var posicion int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM torneo_lista_espera
+		WHERE id_torneo = $1`, reglas.torneoID).Scan(&posicion)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la posición en la lista de espera: %w", err)
+	}
+
+	inscripcion.Estado = models.InscripcionListaEspera
+	inscripcion.PosicionEspera = &posicion
+	return inscripcion, nil
+}
+
+// insertarParticipante crea las estadísticas del jugador en el torneo y actualiza sus estadísticas de usuario
+func insertarParticipante(tx *sql.Tx, reglas *reglasInscripcion, userID string, idEquipo *string) error {
+	insertQuery := `
+		INSERT INTO torneo_estadisticas (id_jugador, id_equipo, id_torneo, modalidad, puntos, habilitado)
+		VALUES ($1, $2, $3, $4, 0, true)`
+
+	_, err := tx.Exec(insertQuery, userID, idEquipo, reglas.torneoID, reglas.modalidad)
+	if err != nil {
+		return fmt.Errorf("error al inscribir usuario: %w", err)
+	}
+
+	updateStatsQuery := `
+		UPDATE user_stats
+		SET torneos_participados = torneos_participados + 1, torneo_id = $1
+		WHERE user_id = $2`
+
+	_, err = tx.Exec(updateStatsQuery, reglas.torneoID, userID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar estadísticas: %w", err)
+	}
+
+	return nil
+}
+
+// promoverListaEspera inscribe, por orden de llegada, a los jugadores de la lista de
+// espera que ahora tienen cupo. Las entradas de quienes mientras tanto entraron a otro
+// torneo activo o crearon uno vencen y se avisa al jugador; las de los baneados se
+// descartan. Si el equipo preferido ya no existe, el jugador sigue en la lista sin
+// preferencia y se le asigna el equipo con menos integrantes
+func promoverListaEspera(tx *sql.Tx, torneoID string) error {
+	reglas, err := cargarReglasInscripcion(tx, torneoID)
+	if err != nil {
+		return err
+	}
+
+	if reglas.finalizado || reglas.terminado {
+		return nil
+	}
+
+	query := `
+		SELECT le.id_jugador, le.id_equipo, t.nombre,
+			EXISTS (
+				SELECT 1 FROM torneo_estadisticas te
+				JOIN torneos ot ON ot.id = te.id_torneo
+				WHERE te.id_jugador = le.id_jugador AND ot.finalizado = false
+			) OR EXISTS (
+				SELECT 1 FROM user_stats us
+				WHERE us.user_id = le.id_jugador AND us.es_dueno_torneo = true
+			)
+		FROM torneo_lista_espera le
+		JOIN torneos t ON t.id = le.id_torneo
+		WHERE le.id_torneo = $1
+		ORDER BY le.created_at`
+
+	rows, err := tx.Query(query, torneoID)
+	if err != nil {
+		return fmt.Errorf("error al obtener la lista de espera: %w", err)
+	}
+
+	type enEspera struct {
+		userID   string
+		idEquipo *string
+		torneo   string
+		ocupado  bool
+	}
+
+	var espera []enEspera
+	for rows.Next() {
+		var e enEspera
+		if err := rows.Scan(&e.userID, &e.idEquipo, &e.torneo, &e.ocupado); err != nil {
+			rows.Close()
+			return fmt.Errorf("error al leer la lista de espera: %w", err)
+		}
+		espera = append(espera, e)
+	}
+
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("error al procesar la lista de espera: %w", err)
+	}
+
+	for _, e := range espera {
+		if e.ocupado {
+			// El jugador ya juega u organiza otro torneo: su lugar en la lista vence
+			if err := quitarDeListaEspera(tx, torneoID, e.userID); err != nil {
+				return err
+			}
+
+			mensaje := fmt.Sprintf("Tu lugar en la lista de espera del torneo \"%s\" venció porque ya participas en otro torneo", e.torneo)
+			if err := crearNotificacion(tx, e.userID, models.NotificacionListaEsperaVencida, mensaje, &torneoID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		baneado, err := estaBaneado(tx, torneoID, e.userID)
+		if err != nil {
+			return err
+		}
+
+		if baneado {
+			if err := quitarDeListaEspera(tx, torneoID, e.userID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		idEquipo, hayCupo, err := reglas.ubicarEnEspera(tx, e.userID, e.idEquipo)
+		if errors.Is(err, ErrEquipoNoEncontrado) && e.idEquipo != nil {
+			// El equipo preferido ya no existe: el jugador conserva su lugar sin preferencia
+			_, err = tx.Exec(`
+				UPDATE torneo_lista_espera SET id_equipo = NULL
+				WHERE id_torneo = $1 AND id_jugador = $2`, torneoID, e.userID)
+			if err != nil {
+				return fmt.Errorf("error al actualizar la lista de espera: %w", err)
+			}
+			idEquipo, hayCupo, err = reglas.ubicarEnEspera(tx, e.userID, nil)
+		}
+		if errors.Is(err, ErrEquipoNoEncontrado) {
+			// El torneo todavía no tiene equipos
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if !hayCupo {
+			continue
+		}
+
+		if err := insertarParticipante(tx, reglas, e.userID, idEquipo); err != nil {
+			return err
+		}
+
+		if err := quitarDeListaEspera(tx, torneoID, e.userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ubicarEnEspera ubica a un jugador de la lista de espera. Sin equipo preferido en un
+// torneo de asignación libre se le asigna el equipo con menos integrantes
+func (reglas *reglasInscripcion) ubicarEnEspera(tx *sql.Tx, userID string, idEquipo *string) (*string, bool, error) {
+	if idEquipo == nil && reglas.asignacion == models.AsignacionEquiposLibre {
+		sinPreferencia := *reglas
+		sinPreferencia.asignacion = models.AsignacionEquiposCantidad
+		return sinPreferencia.ubicarJugador(tx, userID, nil, nil)
+	}
+
+	return reglas.ubicarJugador(tx, userID, idEquipo, nil)
+}
+
+// quitarDeListaEspera borra la entrada de un jugador en la lista de espera del torneo
+func quitarDeListaEspera(tx *sql.Tx, torneoID string, userID string) error {
+	_, err := tx.Exec(`
+		DELETE FROM torneo_lista_espera
+		WHERE id_torneo = $1 AND id_jugador = $2`, torneoID, userID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar la lista de espera: %w", err)
+	}
+
+	return nil
+}
+
+// GetListaEspera obtiene la lista de espera de un torneo en orden de llegada
+func (r *TorneoRepository) GetListaEspera(torneoID string) ([]models.ListaEspera, error) {
+	query := `
+		SELECT id_jugador, id_equipo, created_at
+		FROM torneo_lista_espera
+		WHERE id_torneo = $1
+		ORDER BY created_at`
+
+	rows, err := r.db.Query(query, torneoID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la lista de espera: %w", err)
+	}
+	defer rows.Close()
+
+	lista := []models.ListaEspera{}
+	for rows.Next() {
+		var e models.ListaEspera
+		if err := rows.Scan(&e.IDJugador, &e.IDEquipo, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error al leer la lista de espera: %w", err)
+		}
+		e.Posicion = len(lista) + 1
+		lista = append(lista, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar la lista de espera: %w", err)
+	}
+
+	return lista, nil
+}
@@ -0,0 +1,267 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/pkg/database"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNoSonAmigos            = errors.New("solo puedes invitar a usuarios de tu lista de amigos")
+	ErrNoPuedeInvitar         = errors.New("solo los organizadores y participantes pueden invitar a este torneo")
+	ErrInvitacionExistente    = errors.New("el usuario ya tiene una invitación pendiente a este torneo")
+	ErrInvitacionNoEncontrada = errors.New("invitación no encontrada o ya respondida")
+	ErrEquipoNoSeleccionable  = errors.New("este torneo asigna los equipos automáticamente")
+)
+
+// consultaInvitaciones selecciona las invitaciones con el nombre del torneo y del invitador
+const consultaInvitaciones = `
+	SELECT i.id, i.id_torneo, t.nombre, i.id_invitador,
+		COALESCE(ub.nombre || ' ' || ub.apellido, ''), i.id_invitado, i.id_equipo,
+		i.estado, i.created_at, i.respondida_at
+	FROM torneo_invitaciones i
+	JOIN torneos t ON t.id = i.id_torneo
+	LEFT JOIN user_basic_info ub ON ub.user_id = i.id_invitador`
+
+func scanInvitacion(row interface{ Scan(...interface{}) error }) (*models.InvitacionTorneo, error) {
+	var i models.InvitacionTorneo
+	err := row.Scan(&i.ID, &i.IDTorneo, &i.NombreTorneo, &i.IDInvitador, &i.NombreInvitador,
+		&i.IDInvitado, &i.IDEquipo, &i.Estado, &i.CreatedAt, &i.RespondidaAt)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// sonAmigos indica si dos usuarios tienen una amistad aceptada
+func sonAmigos(tx *sql.Tx, userID string, friendID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM user_friends
+			WHERE ((user_id = $1 AND friend_id = $2) OR (user_id = $2 AND friend_id = $1))
+			AND pending_id IS NULL AND deleted_at IS NULL
+		)`
+
+	var amigos bool
+	if err := tx.QueryRow(query, userID, friendID).Scan(&amigos); err != nil {
+		return false, fmt.Errorf("error al verificar la amistad: %w", err)
+	}
+
+	return amigos, nil
+}
+
+// InvitarAmigo registra la invitación de un organizador o participante a uno de sus
+// amigos. En modalidad Versus con asignación libre se puede preseleccionar el equipo;
+// si un participante no lo indica, se preselecciona su propio equipo
+func (r *TorneoRepository) InvitarAmigo(torneoID, invitadorID, invitadoID string, equipoID *string) (*models.InvitacionTorneo, error) {
+	esOrganizador, err := r.EsOrganizador(torneoID, invitadorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var invitacionID string
+	err = database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var modalidad, asignacion string
+		var finalizado bool
+		err := tx.QueryRow(`
+			SELECT modalidad, asignacion_equipos, finalizado
+			FROM torneos
+			WHERE id = $1`, torneoID).Scan(&modalidad, &asignacion, &finalizado)
+		if err != nil {
+			return fmt.Errorf("error al buscar torneo: %w", err)
+		}
+
+		if finalizado {
+			return ErrTorneoFinalizado
+		}
+
+		// El invitador debe organizar el torneo o participar en él
+		var equipoInvitador *string
+		err = tx.QueryRow(`
+			SELECT id_equipo
+			FROM torneo_estadisticas
+			WHERE id_torneo = $1 AND id_jugador = $2`, torneoID, invitadorID).Scan(&equipoInvitador)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("error al verificar al invitador: %w", err)
+		}
+
+		if err == sql.ErrNoRows && !esOrganizador {
+			return ErrNoPuedeInvitar
+		}
+
+		amigos, err := sonAmigos(tx, invitadorID, invitadoID)
+		if err != nil {
+			return err
+		}
+
+		if !amigos {
+			return ErrNoSonAmigos
+		}
+
+		baneado, err := estaBaneado(tx, torneoID, invitadoID)
+		if err != nil {
+			return err
+		}
+
+		if baneado {
+			return ErrUsuarioBaneado
+		}
+
+		var inscrito, pendiente bool
+		err = tx.QueryRow(`
+			SELECT
+				EXISTS (
+					SELECT 1 FROM torneo_estadisticas
+					WHERE id_torneo = $1 AND id_jugador = $2
+				),
+				EXISTS (
+					SELECT 1 FROM torneo_invitaciones
+					WHERE id_torneo = $1 AND id_invitado = $2 AND estado = 'pendiente'
+				)`, torneoID, invitadoID).Scan(&inscrito, &pendiente)
+		if err != nil {
+			return fmt.Errorf("error al verificar la inscripción del invitado: %w", err)
+		}
+
+		if inscrito {
+			return fmt.Errorf("el usuario ya está inscrito en este torneo")
+		}
+
+		if pendiente {
+			return ErrInvitacionExistente
+		}
+
+		// Resolver el equipo preseleccionado
+		eligeEquipo := modalidad == "Versus" && asignacion == models.AsignacionEquiposLibre
+		if equipoID != nil {
+			if !eligeEquipo {
+				return ErrEquipoNoSeleccionable
+			}
+			if _, err := resolverEquipo(tx, torneoID, equipoID, nil); err != nil {
+				return err
+			}
+		} else if eligeEquipo {
+			equipoID = equipoInvitador
+		}
+
+		err = tx.QueryRow(`
+			INSERT INTO torneo_invitaciones (id_torneo, id_invitador, id_invitado, id_equipo)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id`, torneoID, invitadorID, invitadoID, equipoID).Scan(&invitacionID)
+		if err != nil {
+			return fmt.Errorf("error al crear la invitación: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return scanInvitacion(r.db.QueryRow(consultaInvitaciones+` WHERE i.id = $1`, invitacionID))
+}
+
+// GetInvitacionesUsuario obtiene las invitaciones recibidas por un usuario.
+// Si estado está vacío se devuelven todas
+func (r *TorneoRepository) GetInvitacionesUsuario(userID string, estado string) ([]models.InvitacionTorneo, error) {
+	query := consultaInvitaciones + `
+		WHERE i.id_invitado = $1 AND ($2::text = '' OR i.estado = $2::text)
+		ORDER BY i.created_at DESC`
+
+	rows, err := r.db.Query(query, userID, estado)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener invitaciones: %w", err)
+	}
+	defer rows.Close()
+
+	invitaciones := []models.InvitacionTorneo{}
+	for rows.Next() {
+		invitacion, err := scanInvitacion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer invitación: %w", err)
+		}
+		invitaciones = append(invitaciones, *invitacion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar invitaciones: %w", err)
+	}
+
+	return invitaciones, nil
+}
+
+// ResponderInvitacion acepta o rechaza una invitación pendiente. Al aceptar se aplican
+// las mismas reglas que en InscribirUsuario (salvo la restricción de torneo privado)
+// y se usa el equipo preseleccionado. Si la inscripción falla la invitación sigue pendiente
+func (r *TorneoRepository) ResponderInvitacion(invitacionID string, userID string, aceptar bool) (*models.InvitacionTorneo, *models.Inscripcion, error) {
+	var inscripcion *models.Inscripcion
+
+	err := database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var torneoID string
+		var equipoID *string
+		err := tx.QueryRow(`
+			SELECT id_torneo, id_equipo
+			FROM torneo_invitaciones
+			WHERE id = $1 AND id_invitado = $2 AND estado = 'pendiente'
+			FOR UPDATE`, invitacionID, userID).Scan(&torneoID, &equipoID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrInvitacionNoEncontrada
+			}
+			return fmt.Errorf("error al obtener la invitación: %w", err)
+		}
+
+		estado := "rechazada"
+		if aceptar {
+			inscripcion, err = inscribirEnTorneo(tx, torneoID, userID, equipoID, nil, true)
+			if err != nil {
+				return err
+			}
+			estado = "aceptada"
+		}
+
+		_, err = tx.Exec(`
+			UPDATE torneo_invitaciones
+			SET estado = $1, respondida_at = NOW()
+			WHERE id = $2`, estado, invitacionID)
+		if err != nil {
+			return fmt.Errorf("error al responder la invitación: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	invitacion, err := scanInvitacion(r.db.QueryRow(consultaInvitaciones+` WHERE i.id = $1`, invitacionID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error al obtener la invitación: %w", err)
+	}
+
+	return invitacion, inscripcion, nil
+}
+
+// CancelarInvitacion permite al invitador retirar una invitación pendiente
+func (r *TorneoRepository) CancelarInvitacion(torneoID, invitacionID, invitadorID string) error {
+	result, err := r.db.Exec(`
+		UPDATE torneo_invitaciones
+		SET estado = 'cancelada', respondida_at = NOW()
+		WHERE id = $1 AND id_torneo = $2 AND id_invitador = $3 AND estado = 'pendiente'`,
+		invitacionID, torneoID, invitadorID)
+	if err != nil {
+		return fmt.Errorf("error al cancelar la invitación: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrInvitacionNoEncontrada
+	}
+
+	return nil
+}
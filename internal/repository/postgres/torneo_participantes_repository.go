@@ -0,0 +1,257 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/pkg/database"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUsuarioBaneado   = errors.New("fuiste baneado de este torneo por el organizador")
+	ErrTorneoFinalizado = errors.New("el torneo ya finalizó")
+)
+
+// estaBaneado indica si el jugador tiene un baneo vigente en el torneo
+func estaBaneado(tx *sql.Tx, torneoID string, userID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM torneo_sanciones
+			WHERE id_torneo = $1 AND id_jugador = $2
+			AND tipo = 'baneado' AND levantado_at IS NULL
+		)`
+
+	var baneado bool
+	if err := tx.QueryRow(query, torneoID, userID).Scan(&baneado); err != nil {
+		return false, fmt.Errorf("error al verificar baneos: %w", err)
+	}
+
+	return baneado, nil
+}
+
+// verificarTorneoActivo comprueba que el torneo exista y no haya finalizado
+func verificarTorneoActivo(tx *sql.Tx, torneoID string) error {
+	var finalizado bool
+	err := tx.QueryRow(`SELECT finalizado FROM torneos WHERE id = $1`, torneoID).Scan(&finalizado)
+	if err != nil {
+		return fmt.Errorf("error al buscar torneo: %w", err)
+	}
+
+	if finalizado {
+		return ErrTorneoFinalizado
+	}
+
+	return nil
+}
+
+// registrarSancion guarda una sanción dentro de una transacción
+func registrarSancion(tx *sql.Tx, torneoID, userID, organizadorID, tipo, motivo string) error {
+	query := `
+		INSERT INTO torneo_sanciones (id_torneo, id_jugador, id_organizador, tipo, motivo)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err := tx.Exec(query, torneoID, userID, organizadorID, tipo, motivo); err != nil {
+		return fmt.Errorf("error al registrar la sanción: %w", err)
+	}
+
+	return nil
+}
+
+// SetHabilitadoParticipante habilita o deshabilita la puntuación de un participante.
+// Mientras está deshabilitado sus acciones no suman puntos al torneo y no aparece en el ranking
+func (r *TorneoRepository) SetHabilitadoParticipante(torneoID, userID, organizadorID string, habilitado bool, motivo string) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		if err := verificarTorneoActivo(tx, torneoID); err != nil {
+			return err
+		}
+
+		var motivoDeshabilitado *string
+		tipo := "habilitado"
+		if !habilitado {
+			motivoDeshabilitado = &motivo
+			tipo = "deshabilitado"
+		}
+
+		result, err := tx.Exec(`
+			UPDATE torneo_estadisticas
+			SET habilitado = $1, motivo_deshabilitado = $2
+			WHERE id_torneo = $3 AND id_jugador = $4`, habilitado, motivoDeshabilitado, torneoID, userID)
+		if err != nil {
+			return fmt.Errorf("error al actualizar al participante: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrUsuarioNoInscrito
+		}
+
+		return registrarSancion(tx, torneoID, userID, organizadorID, tipo, motivo)
+	})
+}
+
+// ExpulsarParticipante saca a un participante del torneo o de su lista de espera. Si
+// banear es true, el jugador no podrá volver a inscribirse con el código del torneo; el
+// baneo se registra aunque el jugador todavía no esté inscrito
+func (r *TorneoRepository) ExpulsarParticipante(torneoID, userID, organizadorID, motivo string, banear bool) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		if err := verificarTorneoActivo(tx, torneoID); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(`
+			DELETE FROM torneo_estadisticas
+			WHERE id_torneo = $1 AND id_jugador = $2`, torneoID, userID)
+		if err != nil {
+			return fmt.Errorf("error al expulsar al participante: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		inscrito := rowsAffected > 0
+
+		// Un jugador en la lista de espera también pierde su lugar
+		result, err = tx.Exec(`
+			DELETE FROM torneo_lista_espera
+			WHERE id_torneo = $1 AND id_jugador = $2`, torneoID, userID)
+		if err != nil {
+			return fmt.Errorf("error al quitar al jugador de la lista de espera: %w", err)
+		}
+
+		rowsAffected, err = result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		enEspera := rowsAffected > 0
+
+		// Sin banear solo se puede expulsar a quien está inscrito o esperando; el baneo
+		// se registra igual para impedir que el jugador se inscriba más adelante
+		if !inscrito && !enEspera {
+			if !banear {
+				return ErrUsuarioNoInscrito
+			}
+
+			var existe bool
+			err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM user_access WHERE id = $1)`, userID).Scan(&existe)
+			if err != nil {
+				return fmt.Errorf("error al buscar usuario: %w", err)
+			}
+			if !existe {
+				return sql.ErrNoRows
+			}
+		}
+
+		if inscrito {
+			// Cancelar solicitudes de cambio de equipo pendientes del jugador
+			_, err = tx.Exec(`
+				UPDATE torneo_cambios_equipo
+				SET estado = 'cancelado', resuelto_at = NOW()
+				WHERE id_torneo = $1 AND id_jugador = $2 AND estado = 'pendiente'`, torneoID, userID)
+			if err != nil {
+				return fmt.Errorf("error al cancelar solicitudes de cambio de equipo: %w", err)
+			}
+
+			// Actualizar estadísticas del usuario como en SalirTorneo
+			_, err = tx.Exec(`
+				UPDATE user_stats
+				SET torneos_participados = GREATEST(0, torneos_participados - 1),
+					torneo_id = CASE WHEN torneo_id = $1 THEN NULL ELSE torneo_id END
+				WHERE user_id = $2`, torneoID, userID)
+			if err != nil {
+				return fmt.Errorf("error al actualizar estadísticas de usuario: %w", err)
+			}
+		}
+
+		tipo := "expulsado"
+		if banear {
+			tipo = "baneado"
+		}
+
+		if err := registrarSancion(tx, torneoID, userID, organizadorID, tipo, motivo); err != nil {
+			return err
+		}
+
+		if !inscrito {
+			return nil
+		}
+
+		// El lugar liberado se ofrece a la lista de espera
+		return promoverListaEspera(tx, torneoID)
+	})
+}
+
+// LevantarBaneo permite que un jugador baneado vuelva a inscribirse en el torneo
+func (r *TorneoRepository) LevantarBaneo(torneoID string, userID string) error {
+	result, err := r.db.Exec(`
+		UPDATE torneo_sanciones
+		SET levantado_at = NOW()
+		WHERE id_torneo = $1 AND id_jugador = $2
+		AND tipo = 'baneado' AND levantado_at IS NULL`, torneoID, userID)
+	if err != nil {
+		return fmt.Errorf("error al levantar el baneo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetEstadoParticipante obtiene el estado de un jugador en un torneo y el historial
+// de sanciones con sus motivos
+func (r *TorneoRepository) GetEstadoParticipante(torneoID string, userID string) (*models.EstadoParticipante, error) {
+	estado := &models.EstadoParticipante{
+		IDTorneo:  torneoID,
+		IDJugador: userID,
+		Sanciones: []models.SancionTorneo{},
+	}
+
+	err := r.db.QueryRow(`
+		SELECT habilitado, motivo_deshabilitado
+		FROM torneo_estadisticas
+		WHERE id_torneo = $1 AND id_jugador = $2`, torneoID, userID).Scan(&estado.Habilitado, &estado.MotivoDeshabilitado)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("error al obtener la inscripción: %w", err)
+	}
+	estado.Inscrito = err == nil
+
+	rows, err := r.db.Query(`
+		SELECT id, id_torneo, id_jugador, id_organizador, tipo, motivo, created_at, levantado_at
+		FROM torneo_sanciones
+		WHERE id_torneo = $1 AND id_jugador = $2
+		ORDER BY created_at DESC`, torneoID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener sanciones: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.SancionTorneo
+		if err := rows.Scan(&s.ID, &s.IDTorneo, &s.IDJugador, &s.IDOrganizador,
+			&s.Tipo, &s.Motivo, &s.CreatedAt, &s.LevantadoAt); err != nil {
+			return nil, fmt.Errorf("error al leer sanción: %w", err)
+		}
+		if s.Tipo == "baneado" && s.LevantadoAt == nil {
+			estado.Baneado = true
+		}
+		estado.Sanciones = append(estado.Sanciones, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar sanciones: %w", err)
+	}
+
+	return estado, nil
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/pkg/database"
+	"database/sql"
+	"fmt"
+)
+
+// GetReglasPuntuacion obtiene las reglas de puntuación de un torneo. Los tipos de
+// acción sin regla propia se devuelven con sus puntos por defecto
+func (r *TorneoRepository) GetReglasPuntuacion(torneoID string) (*models.ReglasPuntuacion, error) {
+	reglas := &models.ReglasPuntuacion{IDTorneo: torneoID}
+
+	err := r.db.QueryRow(`
+		SELECT max_puntos_diarios, bono_lugar_nuevo
+		FROM torneos
+		WHERE id = $1`, torneoID).Scan(&reglas.MaxPuntosDiarios, &reglas.BonoLugarNuevo)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT tipo_accion, puntos, habilitado
+		FROM torneo_reglas_accion
+		WHERE id_torneo = $1`, torneoID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las reglas del torneo: %w", err)
+	}
+	defer rows.Close()
+
+	propias := map[string]models.ReglaAccionTorneo{}
+	for rows.Next() {
+		var regla models.ReglaAccionTorneo
+		if err := rows.Scan(&regla.TipoAccion, &regla.Puntos, &regla.Habilitado); err != nil {
+			return nil, fmt.Errorf("error al leer regla del torneo: %w", err)
+		}
+		propias[regla.TipoAccion] = regla
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar las reglas del torneo: %w", err)
+	}
+
+	for _, tipo := range models.TiposAccion {
+		regla, ok := propias[tipo]
+		if !ok {
+			regla = models.ReglaAccionTorneo{TipoAccion: tipo, Puntos: puntosBaseAccion(tipo), Habilitado: true}
+		}
+		reglas.Acciones = append(reglas.Acciones, regla)
+	}
+
+	return reglas, nil
+}
+
+// UpdateReglasPuntuacion reemplaza las reglas de puntuación de un torneo. Solo se
+// permite antes del inicio para que todas las acciones se puntúen con las mismas reglas
+func (r *TorneoRepository) UpdateReglasPuntuacion(reglas *models.ReglasPuntuacion) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var iniciado bool
+		err := tx.QueryRow(`
+			SELECT finalizado OR fecha_inicio <= NOW()
+			FROM torneos
+			WHERE id = $1
+			FOR UPDATE`, reglas.IDTorneo).Scan(&iniciado)
+		if err != nil {
+			return fmt.Errorf("error al buscar torneo: %w", err)
+		}
+
+		if iniciado {
+			return ErrTorneoIniciado
+		}
+
+		_, err = tx.Exec(`
+			UPDATE torneos
+			SET max_puntos_diarios = $1, bono_lugar_nuevo = $2
+			WHERE id = $3`, reglas.MaxPuntosDiarios, reglas.BonoLugarNuevo, reglas.IDTorneo)
+		if err != nil {
+			return fmt.Errorf("error al actualizar las reglas del torneo: %w", err)
+		}
+
+		_, err = tx.Exec(`DELETE FROM torneo_reglas_accion WHERE id_torneo = $1`, reglas.IDTorneo)
+		if err != nil {
+			return fmt.Errorf("error al actualizar las reglas del torneo: %w", err)
+		}
+
+		for _, regla := range reglas.Acciones {
+			_, err = tx.Exec(`
+				INSERT INTO torneo_reglas_accion (id_torneo, tipo_accion, puntos, habilitado)
+				VALUES ($1, $2, $3, $4)`, reglas.IDTorneo, regla.TipoAccion, regla.Puntos, regla.Habilitado)
+			if err != nil {
+				return fmt.Errorf("error al guardar la regla de %s: %w", regla.TipoAccion, err)
+			}
+		}
+
+		return nil
+	})
+}
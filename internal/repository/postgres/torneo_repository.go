@@ -5,15 +5,65 @@ import (
 	"backend_proyecto_verde/internal/utils"
 	"backend_proyecto_verde/pkg/database"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+)
+
+var (
+	ErrNoEsOrganizador   = errors.New("solo el organizador del torneo puede realizar esta acción")
+	ErrUsuarioNoInscrito = errors.New("el usuario no está inscrito en este torneo")
+	// ErrTorneoConParticipantes impide cambiar la modalidad o el formato de un torneo con jugadores
+	ErrTorneoConParticipantes = errors.New("no se puede cambiar la modalidad ni el formato de un torneo con participantes")
 )
 
 type TorneoRepository struct {
-	db *sql.DB
+	db          *sql.DB
+	zonaHoraria string // Zona con la que se reevalúan las rachas de días de las medallas
 }
 
-func NewTorneoRepository(db *sql.DB) *TorneoRepository {
-	return &TorneoRepository{db: db}
+func NewTorneoRepository(db *sql.DB, zonaHoraria string) *TorneoRepository {
+	return &TorneoRepository{db: db, zonaHoraria: zonaHoraria}
+}
+
+// EsOrganizador indica si el usuario administra el torneo (como dueño o coorganizador)
+func (r *TorneoRepository) EsOrganizador(torneoID string, userID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM torneo_staff WHERE id_torneo = $1 AND user_id = $2
+		)`
+
+	var esOrganizador bool
+	err := r.db.QueryRow(query, torneoID, userID).Scan(&esOrganizador)
+	if err != nil {
+		return false, fmt.Errorf("error al verificar organizador del torneo: %w", err)
+	}
+
+	return esOrganizador, nil
+}
+
+// columnasConfiguracionTorneo son las columnas de configuración que se leen junto con cada torneo
+const columnasConfiguracionTorneo = `
+	visibilidad, asignacion_equipos, equipos_bloqueados, permitir_cambio_equipo, cambio_requiere_aprobacion,
+	max_participantes, max_por_equipo, fecha_cierre_inscripcion,
+	geocerca_tipo, geocerca_radio_metros, geocerca_poligono, id_temporada, id_plantilla,
+	formato, duracion_ronda_minutos, cancelado, fecha_cancelacion, motivo_cancelacion,
+	minutos_verificacion,
+	NOT finalizado AND minutos_verificacion IS NOT NULL AND fecha_fin <= NOW(),
+	fecha_fin + make_interval(mins => minutos_verificacion)`
+
+// destinosConfiguracionTorneo devuelve los destinos de Scan para columnasConfiguracionTorneo
+func destinosConfiguracionTorneo(torneo *models.Torneo) []interface{} {
+	return []interface{}{
+		&torneo.Visibilidad, &torneo.AsignacionEquipos, &torneo.EquiposBloqueados,
+		&torneo.PermitirCambioEquipo, &torneo.CambioRequiereAprobacion,
+		&torneo.MaxParticipantes, &torneo.MaxPorEquipo, &torneo.FechaCierreInscripcion,
+		&torneo.GeocercaTipo, &torneo.GeocercaRadioMetros, &torneo.GeocercaPoligono,
+		&torneo.IDTemporada, &torneo.IDPlantilla,
+		&torneo.Formato, &torneo.DuracionRondaMinutos,
+		&torneo.Cancelado, &torneo.FechaCancelacion, &torneo.MotivoCancelacion,
+		&torneo.MinutosVerificacion, &torneo.EnVerificacion, &torneo.FinVerificacion,
+	}
 }
 
 func (r *TorneoRepository) GetIfTournamentOwner(userID string) (*models.UserStats, string, error) {
@@ -82,65 +132,129 @@ func (r *TorneoRepository) CreateTorneo(torneo *models.Torneo) error {
 	torneo.CodeID = utils.GenerateUniqueFriendId(r.db, true)
 
 	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
-		query := `
-			INSERT INTO torneos (
-				nombre, id_creator, modalidad, ubicacion_a_latitud, ubicacion_a_longitud,
-				nombre_ubicacion_a, ubicacion_b_latitud, ubicacion_b_longitud,
-				nombre_ubicacion_b, fecha_inicio, fecha_fin, ubicacion_aproximada,
-				metros_aproximados, code_id
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
-			RETURNING id`
-
-		err := tx.QueryRow(
-			query,
-			torneo.Nombre,
-			torneo.IDCreator,
-			torneo.Modalidad,
-			torneo.UbicacionALatitud,
-			torneo.UbicacionALongitud,
-			torneo.NombreUbicacionA,
-			torneo.UbicacionBLatitud,
-			torneo.UbicacionBLongitud,
-			torneo.NombreUbicacionB,
-			torneo.FechaInicio,
-			torneo.FechaFin,
-			torneo.UbicacionAproximada,
-			torneo.MetrosAprox,
-			torneo.CodeID,
-		).Scan(&torneo.ID)
+		return crearTorneo(tx, torneo)
+	})
+}
 
-		if err != nil {
-			return fmt.Errorf("error al crear torneo: %w", err)
+// crearTorneo inserta el torneo con sus equipos y marca al creador como dueño.
+// torneo.CodeID ya debe estar generado
+func crearTorneo(tx *sql.Tx, torneo *models.Torneo) error {
+	if torneo.AsignacionEquipos == "" {
+		torneo.AsignacionEquipos = models.AsignacionEquiposLibre
+	}
+	if torneo.GeocercaTipo == "" {
+		torneo.GeocercaTipo = models.GeocercaNinguna
+	}
+	if torneo.Visibilidad == "" {
+		torneo.Visibilidad = models.VisibilidadPublica
+	}
+	if torneo.Formato == "" {
+		torneo.Formato = models.FormatoClasico
+	}
+
+	if torneo.IDTemporada != nil {
+		if err := verificarTemporada(tx, *torneo.IDTemporada, torneo.IDCreator, torneo.FechaInicio); err != nil {
+			return err
 		}
+	}
 
-		// Actualizar estadísticas del usuario como dueño del torneo
-		// Primero verificamos si existen estadísticas para este usuario
-		checkQuery := `
-			SELECT EXISTS (
-				SELECT 1 FROM user_stats WHERE user_id = $1
-			)`
+	query := `
+		INSERT INTO torneos (
+			nombre, id_creator, modalidad, ubicacion_a_latitud, ubicacion_a_longitud,
+			nombre_ubicacion_a, ubicacion_b_latitud, ubicacion_b_longitud,
+			nombre_ubicacion_b, fecha_inicio, fecha_fin, ubicacion_aproximada,
+			metros_aproximados, code_id, asignacion_equipos, equipos_bloqueados,
+			permitir_cambio_equipo, cambio_requiere_aprobacion, max_participantes,
+			max_por_equipo, fecha_cierre_inscripcion, geocerca_tipo, geocerca_radio_metros,
+			geocerca_poligono, visibilidad, id_temporada, id_plantilla, formato, duracion_ronda_minutos,
+			minutos_verificacion
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+			$21, $22, $23, $24, $25, $26, $27, $28, $29, $30)
+		RETURNING id`
+
+	err := tx.QueryRow(
+		query,
+		torneo.Nombre,
+		torneo.IDCreator,
+		torneo.Modalidad,
+		torneo.UbicacionALatitud,
+		torneo.UbicacionALongitud,
+		torneo.NombreUbicacionA,
+		torneo.UbicacionBLatitud,
+		torneo.UbicacionBLongitud,
+		torneo.NombreUbicacionB,
+		torneo.FechaInicio,
+		torneo.FechaFin,
+		torneo.UbicacionAproximada,
+		torneo.MetrosAprox,
+		torneo.CodeID,
+		torneo.AsignacionEquipos,
+		torneo.EquiposBloqueados,
+		torneo.PermitirCambioEquipo,
+		torneo.CambioRequiereAprobacion,
+		torneo.MaxParticipantes,
+		torneo.MaxPorEquipo,
+		torneo.FechaCierreInscripcion,
+		torneo.GeocercaTipo,
+		torneo.GeocercaRadioMetros,
+		torneo.GeocercaPoligono,
+		torneo.Visibilidad,
+		torneo.IDTemporada,
+		torneo.IDPlantilla,
+		torneo.Formato,
+		torneo.DuracionRondaMinutos,
+		torneo.MinutosVerificacion,
+	).Scan(&torneo.ID)
 
-		var exists bool
-		err = tx.QueryRow(checkQuery, torneo.IDCreator).Scan(&exists)
-		if err != nil {
-			return fmt.Errorf("error al verificar estadísticas del usuario: %w", err)
+	if err != nil {
+		return fmt.Errorf("error al crear torneo: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO torneo_staff (id_torneo, user_id, rol)
+		VALUES ($1, $2, 'dueno')`, torneo.ID, torneo.IDCreator)
+	if err != nil {
+		return fmt.Errorf("error al registrar al dueño del torneo: %w", err)
+	}
+
+	// Crear los equipos del torneo si es modalidad "Versus"
+	if torneo.Modalidad == "Versus" {
+		if len(torneo.Equipos) == 0 {
+			torneo.Equipos = equiposPorDefecto()
 		}
 
-		if exists {
-			// Actualizar estadísticas existentes
-			updateQuery := `
-				UPDATE user_stats
-				SET es_dueno_torneo = true, torneo_id = $1
-				WHERE user_id = $2`
+		if err := insertarEquipos(tx, torneo.ID, torneo.Equipos); err != nil {
+			return err
+		}
+	}
 
-			_, err = tx.Exec(updateQuery, torneo.ID, torneo.IDCreator)
-			if err != nil {
-				return fmt.Errorf("error al actualizar estadísticas del usuario: %w", err)
-			}
+	// Actualizar estadísticas del usuario como dueño del torneo
+	// Primero verificamos si existen estadísticas para este usuario
+	checkQuery := `
+		SELECT EXISTS (
+			SELECT 1 FROM user_stats WHERE user_id = $1
+		)`
+
+	var exists bool
+	err = tx.QueryRow(checkQuery, torneo.IDCreator).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("error al verificar estadísticas del usuario: %w", err)
+	}
+
+	if exists {
+		// Actualizar estadísticas existentes
+		updateQuery := `
+			UPDATE user_stats
+			SET es_dueno_torneo = true, torneo_id = $1
+			WHERE user_id = $2`
+
+		_, err = tx.Exec(updateQuery, torneo.ID, torneo.IDCreator)
+		if err != nil {
+			return fmt.Errorf("error al actualizar estadísticas del usuario: %w", err)
 		}
+	}
 
-		return nil
-	})
+	return nil
 }
 
 func (r *TorneoRepository) GetTorneoByCodeID(codeID string) (*models.Torneo, error) {
@@ -148,23 +262,30 @@ func (r *TorneoRepository) GetTorneoByCodeID(codeID string) (*models.Torneo, err
 		SELECT id, nombre, modalidad, ubicacion_a_latitud, ubicacion_a_longitud,
 			nombre_ubicacion_a, ubicacion_b_latitud, ubicacion_b_longitud,
 			nombre_ubicacion_b, fecha_inicio, fecha_fin, ubicacion_aproximada,
-			metros_aproximados, finalizado, ganador_versus, ganador_individual
+			metros_aproximados, finalizado, ganador_equipo, ganador_individual,` + columnasConfiguracionTorneo + `
 		FROM torneos
 		WHERE code_id = $1`
 
 	torneo := &models.Torneo{}
-	err := r.db.QueryRow(query, codeID).Scan(
+	err := r.db.QueryRow(query, codeID).Scan(append([]interface{}{
 		&torneo.ID, &torneo.Nombre, &torneo.Modalidad,
 		&torneo.UbicacionALatitud, &torneo.UbicacionALongitud,
 		&torneo.NombreUbicacionA, &torneo.UbicacionBLatitud,
 		&torneo.UbicacionBLongitud, &torneo.NombreUbicacionB,
 		&torneo.FechaInicio, &torneo.FechaFin,
 		&torneo.UbicacionAproximada, &torneo.MetrosAprox,
-		&torneo.Finalizado, &torneo.GanadorVersus, &torneo.GanadorIndividual,
-	)
+		&torneo.Finalizado, &torneo.GanadorEquipo, &torneo.GanadorIndividual,
+	}, destinosConfiguracionTorneo(torneo)...)...)
 	if err != nil {
 		return nil, err
 	}
+
+	if torneo.Modalidad == "Versus" {
+		torneo.Equipos, err = r.GetEquiposTorneo(torneo.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return torneo, nil
 }
 
@@ -173,62 +294,151 @@ func (r *TorneoRepository) GetTorneoByID(id string) (*models.Torneo, error) {
 		SELECT id, nombre, modalidad, ubicacion_a_latitud, ubicacion_a_longitud,
 			nombre_ubicacion_a, ubicacion_b_latitud, ubicacion_b_longitud,
 			nombre_ubicacion_b, fecha_inicio, fecha_fin, ubicacion_aproximada,
-			metros_aproximados, finalizado, ganador_versus, ganador_individual
+			metros_aproximados, finalizado, ganador_equipo, ganador_individual,` + columnasConfiguracionTorneo + `
 		FROM torneos
 		WHERE id = $1`
 
 	torneo := &models.Torneo{}
-	err := r.db.QueryRow(query, id).Scan(
+	err := r.db.QueryRow(query, id).Scan(append([]interface{}{
 		&torneo.ID, &torneo.Nombre, &torneo.Modalidad,
 		&torneo.UbicacionALatitud, &torneo.UbicacionALongitud,
 		&torneo.NombreUbicacionA, &torneo.UbicacionBLatitud,
 		&torneo.UbicacionBLongitud, &torneo.NombreUbicacionB,
 		&torneo.FechaInicio, &torneo.FechaFin,
 		&torneo.UbicacionAproximada, &torneo.MetrosAprox,
-		&torneo.Finalizado, &torneo.GanadorVersus, &torneo.GanadorIndividual,
-	)
+		&torneo.Finalizado, &torneo.GanadorEquipo, &torneo.GanadorIndividual,
+	}, destinosConfiguracionTorneo(torneo)...)...)
 	if err != nil {
 		return nil, err
 	}
+
+	if torneo.Modalidad == "Versus" {
+		torneo.Equipos, err = r.GetEquiposTorneo(torneo.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return torneo, nil
 }
 
-func (r *TorneoRepository) GetTorneoByAdminID(id string) (*models.Torneo, error) {
+// GetTorneoActivoCreador obtiene el ID del torneo activo del que el usuario es dueño.
+// Lo usan las rutas antiguas de administración, que reciben el ID del creador
+func (r *TorneoRepository) GetTorneoActivoCreador(idCreator string) (string, error) {
+	var torneoID string
+	err := r.db.QueryRow(`
+		SELECT id
+		FROM torneos
+		WHERE id_creator = $1 AND finalizado = false`, idCreator).Scan(&torneoID)
+	if err != nil {
+		return "", err
+	}
+
+	return torneoID, nil
+}
+
+// GetTorneoAdmin obtiene un torneo con los datos que solo ven sus organizadores
+// (código de acceso y dueño)
+func (r *TorneoRepository) GetTorneoAdmin(torneoID string) (*models.Torneo, error) {
 	query := `
 		SELECT id, id_creator, nombre, modalidad, ubicacion_a_latitud, ubicacion_a_longitud,
 			nombre_ubicacion_a, ubicacion_b_latitud, ubicacion_b_longitud,
 			nombre_ubicacion_b, fecha_inicio, fecha_fin, ubicacion_aproximada,
-			metros_aproximados, code_id, finalizado, ganador_versus, ganador_individual
+			metros_aproximados, code_id, finalizado, ganador_equipo, ganador_individual,` + columnasConfiguracionTorneo + `
 		FROM torneos
-		WHERE id_creator = $1 AND finalizado = false`
+		WHERE id = $1`
 
 	torneo := &models.Torneo{}
-	err := r.db.QueryRow(query, id).Scan(
+	err := r.db.QueryRow(query, torneoID).Scan(append([]interface{}{
 		&torneo.ID, &torneo.IDCreator, &torneo.Nombre, &torneo.Modalidad,
 		&torneo.UbicacionALatitud, &torneo.UbicacionALongitud,
 		&torneo.NombreUbicacionA, &torneo.UbicacionBLatitud,
 		&torneo.UbicacionBLongitud, &torneo.NombreUbicacionB,
 		&torneo.FechaInicio, &torneo.FechaFin,
 		&torneo.UbicacionAproximada, &torneo.MetrosAprox,
-		&torneo.CodeID, &torneo.Finalizado, &torneo.GanadorVersus, &torneo.GanadorIndividual,
-	)
+		&torneo.CodeID, &torneo.Finalizado, &torneo.GanadorEquipo, &torneo.GanadorIndividual,
+	}, destinosConfiguracionTorneo(torneo)...)...)
 	if err != nil {
 		return nil, err
 	}
+
+	if torneo.Modalidad == "Versus" {
+		torneo.Equipos, err = r.GetEquiposTorneo(torneo.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return torneo, nil
 }
 
+// UpdateTorneo guarda los datos editables de un torneo activo. Si cambia la modalidad o
+// el formato se exige que el torneo no tenga participantes ni llave; al pasar a Versus
+// se crean los equipos por defecto y al pasar a Individual se eliminan los equipos
 func (r *TorneoRepository) UpdateTorneo(torneo *models.Torneo) error {
+	if torneo.GeocercaTipo == "" {
+		torneo.GeocercaTipo = models.GeocercaNinguna
+	}
+	if torneo.Formato == "" {
+		torneo.Formato = models.FormatoClasico
+	}
+
 	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var modalidad, formato string
+		var finalizado bool
+		err := tx.QueryRow(`
+			SELECT modalidad, formato, finalizado
+			FROM torneos
+			WHERE id = $1
+			FOR UPDATE`, torneo.ID).Scan(&modalidad, &formato, &finalizado)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return err
+			}
+			return fmt.Errorf("error al buscar torneo: %w", err)
+		}
+
+		if finalizado {
+			return ErrTorneoFinalizado
+		}
+
+		if torneo.Modalidad != modalidad || torneo.Formato != formato {
+			var ocupado bool
+			err = tx.QueryRow(`
+				SELECT EXISTS (SELECT 1 FROM torneo_estadisticas WHERE id_torneo = $1)
+					OR EXISTS (SELECT 1 FROM torneo_lista_espera WHERE id_torneo = $1)
+					OR EXISTS (SELECT 1 FROM torneo_llaves WHERE id_torneo = $1)`, torneo.ID).Scan(&ocupado)
+			if err != nil {
+				return fmt.Errorf("error al verificar participantes: %w", err)
+			}
+
+			if ocupado {
+				return ErrTorneoConParticipantes
+			}
+		}
+
+		if torneo.Modalidad != modalidad {
+			if _, err := tx.Exec(`DELETE FROM torneo_equipos WHERE id_torneo = $1`, torneo.ID); err != nil {
+				return fmt.Errorf("error al eliminar los equipos del torneo: %w", err)
+			}
+
+			if torneo.Modalidad == "Versus" {
+				if err := insertarEquipos(tx, torneo.ID, equiposPorDefecto()); err != nil {
+					return err
+				}
+			}
+		}
+
 		query := `
 			UPDATE torneos
 			SET nombre = $1, modalidad = $2, ubicacion_a_latitud = $3, ubicacion_a_longitud = $4,
 				nombre_ubicacion_a = $5, ubicacion_b_latitud = $6, ubicacion_b_longitud = $7,
 				nombre_ubicacion_b = $8, fecha_inicio = $9, fecha_fin = $10, ubicacion_aproximada = $11,
-				metros_aproximados = $12
-			WHERE id = $13`
+				metros_aproximados = $12, max_participantes = $13, max_por_equipo = $14,
+				fecha_cierre_inscripcion = $15, geocerca_tipo = $16, geocerca_radio_metros = $17,
+				geocerca_poligono = $18, visibilidad = COALESCE(NULLIF($19, ''), visibilidad),
+				formato = $20, duracion_ronda_minutos = $21, minutos_verificacion = $22
+			WHERE id = $23`
 
-		_, err := tx.Exec(
+		_, err = tx.Exec(
 			query,
 			torneo.Nombre,
 			torneo.Modalidad,
@@ -242,6 +452,16 @@ func (r *TorneoRepository) UpdateTorneo(torneo *models.Torneo) error {
 			torneo.FechaFin,
 			torneo.UbicacionAproximada,
 			torneo.MetrosAprox,
+			torneo.MaxParticipantes,
+			torneo.MaxPorEquipo,
+			torneo.FechaCierreInscripcion,
+			torneo.GeocercaTipo,
+			torneo.GeocercaRadioMetros,
+			torneo.GeocercaPoligono,
+			torneo.Visibilidad,
+			torneo.Formato,
+			torneo.DuracionRondaMinutos,
+			torneo.MinutosVerificacion,
 			torneo.ID,
 		)
 
@@ -249,15 +469,17 @@ func (r *TorneoRepository) UpdateTorneo(torneo *models.Torneo) error {
 			return fmt.Errorf("error al actualizar torneo: %w", err)
 		}
 
-		return nil
+		// Si se amplió la capacidad, inscribir a quienes estaban en lista de espera
+		return promoverListaEspera(tx, torneo.ID)
 	})
 }
 
 func (r *TorneoRepository) ListTorneos(limit, offset int) ([]models.Torneo, error) {
 	query := `
 		SELECT id, nombre, modalidad, ubicacion_a_latitud, ubicacion_a_longitud,
-			nombre_ubicacion_a, fecha_inicio, fecha_fin, finalizado
+			nombre_ubicacion_a, fecha_inicio, fecha_fin, finalizado, visibilidad, cancelado
 		FROM torneos
+		WHERE visibilidad = 'publica'
 		ORDER BY fecha_inicio DESC
 		LIMIT $1 OFFSET $2`
 
@@ -274,7 +496,7 @@ func (r *TorneoRepository) ListTorneos(limit, offset int) ([]models.Torneo, erro
 			&t.ID, &t.Nombre, &t.Modalidad,
 			&t.UbicacionALatitud, &t.UbicacionALongitud,
 			&t.NombreUbicacionA, &t.FechaInicio,
-			&t.FechaFin, &t.Finalizado,
+			&t.FechaFin, &t.Finalizado, &t.Visibilidad, &t.Cancelado,
 		)
 		if err != nil {
 			return nil, err
@@ -284,19 +506,102 @@ func (r *TorneoRepository) ListTorneos(limit, offset int) ([]models.Torneo, erro
 	return torneos, nil
 }
 
-func (r *TorneoRepository) TerminarTorneo(idCreator string) error {
+// ListTorneosCercanos obtiene los torneos públicos próximos o en curso cuya ubicación A
+// está dentro de radioKm de la coordenada, ordenados por distancia y fecha de inicio.
+// Antes de calcular la distancia se descartan con el índice los torneos fuera del
+// rectángulo de latitudes y longitudes que contiene el círculo
+func (r *TorneoRepository) ListTorneosCercanos(latitud, longitud, radioKm float64, limit, offset int) ([]models.TorneoCercano, error) {
+	latMin, latMax, lngMin, lngMax := rectanguloBusqueda(latitud, longitud, radioKm)
+
+	query := `
+		SELECT id, nombre, modalidad, ubicacion_a_latitud, ubicacion_a_longitud,
+			nombre_ubicacion_a, fecha_inicio, fecha_fin, finalizado, code_id, visibilidad,
+			distancia_km
+		FROM (
+			SELECT *,
+				6371 * 2 * ASIN(SQRT(
+					POWER(SIN(RADIANS(ubicacion_a_latitud - $1) / 2), 2) +
+					COS(RADIANS($1)) * COS(RADIANS(ubicacion_a_latitud)) *
+					POWER(SIN(RADIANS(ubicacion_a_longitud - $2) / 2), 2)
+				)) AS distancia_km
+			FROM torneos
+			WHERE visibilidad = 'publica' AND finalizado = false AND fecha_fin > NOW()
+			AND ubicacion_a_latitud BETWEEN $6 AND $7
+			AND ubicacion_a_longitud BETWEEN $8 AND $9
+		) t
+		WHERE distancia_km <= $3
+		ORDER BY distancia_km, fecha_inicio
+		LIMIT $4 OFFSET $5`
+
+	rows, err := r.db.Query(query, latitud, longitud, radioKm, limit, offset,
+		latMin, latMax, lngMin, lngMax)
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar torneos cercanos: %w", err)
+	}
+	defer rows.Close()
+
+	torneos := []models.TorneoCercano{}
+	for rows.Next() {
+		var t models.TorneoCercano
+		err := rows.Scan(
+			&t.ID, &t.Nombre, &t.Modalidad,
+			&t.UbicacionALatitud, &t.UbicacionALongitud,
+			&t.NombreUbicacionA, &t.FechaInicio,
+			&t.FechaFin, &t.Finalizado, &t.CodeID, &t.Visibilidad,
+			&t.DistanciaKm,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer torneo cercano: %w", err)
+		}
+		torneos = append(torneos, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar torneos cercanos: %w", err)
+	}
+
+	return torneos, nil
+}
+
+// rectanguloBusqueda calcula los límites de latitud y longitud que contienen el círculo
+// de radioKm alrededor de la coordenada. Cerca de los polos, o si el círculo cruza el
+// antimeridiano, no se limita la longitud
+func rectanguloBusqueda(latitud, longitud, radioKm float64) (latMin, latMax, lngMin, lngMax float64) {
+	const kmPorGrado = 6371 * math.Pi / 180
+
+	deltaLat := radioKm / kmPorGrado
+	latMin = math.Max(latitud-deltaLat, -90)
+	latMax = math.Min(latitud+deltaLat, 90)
+
+	lngMin, lngMax = -180, 180
+	if latMin > -90 && latMax < 90 {
+		// La longitud se estrecha más en el extremo del rectángulo más cercano al polo
+		cosLat := math.Cos(math.Max(math.Abs(latMin), math.Abs(latMax)) * math.Pi / 180)
+		deltaLng := radioKm / (kmPorGrado * cosLat)
+		if longitud-deltaLng >= -180 && longitud+deltaLng <= 180 {
+			lngMin, lngMax = longitud-deltaLng, longitud+deltaLng
+		}
+	}
+
+	return latMin, latMax, lngMin, lngMax
+}
+
+// TerminarTorneo finaliza un torneo activo, determina su ganador y actualiza las
+// estadísticas de su dueño y participantes
+func (r *TorneoRepository) TerminarTorneo(torneoID string) error {
 	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
 		// Obtener el torneo
 		query := `
-			SELECT id, modalidad
+			SELECT id_creator, modalidad
 			FROM torneos
-			WHERE id_creator = $1 AND finalizado = false`
+			WHERE id = $1 AND finalizado = false
+			FOR UPDATE`
 
-		var torneoID, modalidad string
-		err := tx.QueryRow(query, idCreator).Scan(&torneoID, &modalidad)
+		var idCreator, modalidad string
+		err := tx.QueryRow(query, torneoID).Scan(&idCreator, &modalidad)
 		if err != nil {
 			if err == sql.ErrNoRows {
-				return fmt.Errorf("no se encontró un torneo activo para este usuario")
+				return ErrTorneoFinalizado
 			}
 			return fmt.Errorf("error al buscar torneo: %w", err)
 		}
@@ -312,39 +617,43 @@ func (r *TorneoRepository) TerminarTorneo(idCreator string) error {
 			return fmt.Errorf("error al finalizar torneo: %w", err)
 		}
 
-		// Si es modalidad "Versus", determinar el ganador
-		if modalidad == "Versus" {
-			// Obtener puntos de cada equipo
+		// En formato eliminatoria gana el campeón de la llave, si llegó a decidirse
+		campeon, err := campeonEliminatoria(tx, torneoID)
+		if err != nil {
+			return err
+		}
+
+		if campeon != nil {
+			if err := registrarCampeonEliminatoria(tx, torneoID, modalidad, *campeon); err != nil {
+				return err
+			}
+		} else if modalidad == "Versus" {
+			// Si es modalidad "Versus", determinar el equipo ganador
+			// Obtener puntos de cada equipo con participantes, de mayor a menor
 			statsQuery := `
-				SELECT equipo, SUM(puntos) as total_puntos
+				SELECT id_equipo, SUM(puntos) as total_puntos
 				FROM torneo_estadisticas
-				WHERE id_torneo = $1
-				GROUP BY equipo`
+				WHERE id_torneo = $1 AND id_equipo IS NOT NULL AND habilitado = true
+				GROUP BY id_equipo
+				ORDER BY total_puntos DESC`
 
 			rows, err := tx.Query(statsQuery, torneoID)
 			if err != nil {
 				return fmt.Errorf("error al obtener estadísticas: %w", err)
 			}
 
-			// Asegurarse de cerrar los rows inmediatamente después de su uso
-			var equipoA, equipoB bool
-			var puntosA, puntosB int
+			var equipoIDs []string
+			var puntosEquipos []int
 
 			for rows.Next() {
-				var equipo bool
+				var equipoID string
 				var puntos int
-				if err := rows.Scan(&equipo, &puntos); err != nil {
+				if err := rows.Scan(&equipoID, &puntos); err != nil {
 					rows.Close() // Cerrar explícitamente en caso de error
 					return fmt.Errorf("error al leer estadísticas: %w", err)
 				}
-
-				if equipo {
-					equipoA = true
-					puntosA = puntos
-				} else {
-					equipoB = true
-					puntosB = puntos
-				}
+				equipoIDs = append(equipoIDs, equipoID)
+				puntosEquipos = append(puntosEquipos, puntos)
 			}
 
 			// Cerrar filas y verificar errores
@@ -354,19 +663,15 @@ func (r *TorneoRepository) TerminarTorneo(idCreator string) error {
 				return fmt.Errorf("error al procesar estadísticas: %w", err)
 			}
 
-			// Determinar ganador si ambos equipos tienen participantes
-			if equipoA && equipoB {
-				var ganador bool
-				if puntosA > puntosB {
-					ganador = true
-				} else {
-					ganador = false
-				}
+			// Determinar ganador si al menos dos equipos tienen participantes
+			// y el primero no está empatado con el segundo
+			if len(equipoIDs) >= 2 && puntosEquipos[0] > puntosEquipos[1] {
+				ganador := equipoIDs[0]
 
 				// Actualizar ganador
 				updateGanadorQuery := `
 					UPDATE torneos
-					SET ganador_versus = $1
+					SET ganador_equipo = $1
 					WHERE id = $2`
 
 				_, err = tx.Exec(updateGanadorQuery, ganador, torneoID)
@@ -381,19 +686,25 @@ func (r *TorneoRepository) TerminarTorneo(idCreator string) error {
 					FROM torneo_estadisticas
 					WHERE torneo_estadisticas.id_jugador = user_stats.user_id
 					AND torneo_estadisticas.id_torneo = $1
-					AND torneo_estadisticas.equipo = $2`
+					AND torneo_estadisticas.id_equipo = $2
+					AND torneo_estadisticas.habilitado = true
+					RETURNING user_stats.user_id`
 
-				_, err = tx.Exec(updateStatsQuery, torneoID, ganador)
+				ganadores, err := listarIDs(tx, updateStatsQuery, torneoID, ganador)
 				if err != nil {
 					return fmt.Errorf("error al actualizar estadísticas de usuarios: %w", err)
 				}
+
+				if err := desbloquearItemsGanadores(tx, ganadores); err != nil {
+					return err
+				}
 			}
 		} else if modalidad == "Individual" {
 			// Para modalidad individual, determinar el ganador por puntos
 			statsQuery := `
 				SELECT id_jugador, puntos
 				FROM torneo_estadisticas
-				WHERE id_torneo = $1
+				WHERE id_torneo = $1 AND habilitado = true
 				ORDER BY puntos DESC
 				LIMIT 1`
 
@@ -426,9 +737,18 @@ func (r *TorneoRepository) TerminarTorneo(idCreator string) error {
 				if err != nil {
 					return fmt.Errorf("error al actualizar estadísticas del ganador: %w", err)
 				}
+
+				if err := desbloquearItemsGanadores(tx, []string{ganadorID}); err != nil {
+					return err
+				}
 			}
 		}
 
+		// Actualizar el rating de habilidad de los participantes
+		if err := actualizarRatingsTorneo(tx, torneoID, modalidad); err != nil {
+			return err
+		}
+
 		// Actualizar estadísticas del usuario como dueño del torneo
 		// Primero verificamos si existen estadísticas para este usuario
 		checkQuery := `
@@ -503,21 +823,23 @@ func (r *TorneoRepository) TerminarTorneo(idCreator string) error {
 	})
 }
 
-func (r *TorneoRepository) BorrarTorneoEstadisticas(idCreator string) (string, error) {
+// BorrarTorneoEstadisticas elimina un torneo activo que todavía no tiene participantes
+func (r *TorneoRepository) BorrarTorneoEstadisticas(torneoID string) (string, error) {
 	var message string
 	err := database.WithTransaction(r.db, func(tx *sql.Tx) error {
-		// Obtener el ID del torneo
+		// Obtener el dueño del torneo
 		query := `
-			SELECT id
+			SELECT id_creator
 			FROM torneos
-			WHERE id_creator = $1 AND finalizado = false`
+			WHERE id = $1 AND finalizado = false
+			FOR UPDATE`
 
-		var torneoID string
-		err := tx.QueryRow(query, idCreator).Scan(&torneoID)
+		var idCreator string
+		err := tx.QueryRow(query, torneoID).Scan(&idCreator)
 		if err != nil {
 			if err == sql.ErrNoRows {
-				message = "No se encontró un torneo activo para este usuario"
-				return fmt.Errorf("no se encontró un torneo activo para este usuario")
+				message = "No se encontró el torneo o ya finalizó"
+				return err
 			}
 			message = "Error al buscar torneo"
 			return fmt.Errorf("error al buscar torneo: %w", err)
@@ -538,7 +860,7 @@ func (r *TorneoRepository) BorrarTorneoEstadisticas(idCreator string) (string, e
 
 		// Si hay participantes, no permitir borrar el torneo
 		if cantidadParticipantes > 0 {
-			message = fmt.Sprintf("No se puede eliminar el torneo porque ya tiene %d participante(s)", cantidadParticipantes)
+			message = fmt.Sprintf("No se puede eliminar el torneo porque ya tiene %d participante(s); puedes cancelarlo", cantidadParticipantes)
 			return fmt.Errorf("no se puede eliminar el torneo porque ya tiene %d participante(s)", cantidadParticipantes)
 		}
 
@@ -581,63 +903,34 @@ func (r *TorneoRepository) BorrarTorneoEstadisticas(idCreator string) (string, e
 	return message, err
 }
 
-func (r *TorneoRepository) InscribirUsuario(codeID string, userID string, team bool) error {
-	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
-		// Verificar si el torneo existe y no está finalizado
-		torneoQuery := `
-			SELECT id, modalidad
-			FROM torneos
-			WHERE code_id = $1 AND finalizado = false`
+// InscribirUsuario inscribe a un usuario en el torneo con el código indicado.
+// En modalidad Versus con asignación libre se usa equipoID; si no se envía,
+// equipoLegacy (el antiguo booleano "team") selecciona el primer o segundo equipo.
+// Con asignación automática el equipo elegido por el cliente se ignora.
+// Las reglas de capacidad se comprueban con la fila del torneo bloqueada, así que
+// inscripciones concurrentes no pueden superar el cupo: si no hay lugar el jugador
+// queda en lista de espera
+func (r *TorneoRepository) InscribirUsuario(codeID string, userID string, equipoID *string, equipoLegacy *bool) (*models.Inscripcion, error) {
+	var inscripcion *models.Inscripcion
 
-		var torneoID, modalidad string
-		err := tx.QueryRow(torneoQuery, codeID).Scan(&torneoID, &modalidad)
+	err := database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var torneoID string
+		err := tx.QueryRow(`SELECT id FROM torneos WHERE code_id = $1`, codeID).Scan(&torneoID)
 		if err != nil {
 			if err == sql.ErrNoRows {
-				return fmt.Errorf("torneo no encontrado o ya finalizado")
+				return err
 			}
 			return fmt.Errorf("error al buscar torneo: %w", err)
 		}
 
-		// Verificar si el usuario ya está inscrito
-		checkQuery := `
-			SELECT EXISTS (
-				SELECT 1 FROM torneo_estadisticas
-				WHERE id_torneo = $1 AND id_jugador = $2
-			)`
-
-		var exists bool
-		err = tx.QueryRow(checkQuery, torneoID, userID).Scan(&exists)
-		if err != nil {
-			return fmt.Errorf("error al verificar inscripción: %w", err)
-		}
-
-		if exists {
-			return fmt.Errorf("el usuario ya está inscrito en este torneo")
-		}
-
-		// Inscribir al usuario
-		insertQuery := `
-			INSERT INTO torneo_estadisticas (id_jugador, equipo, id_torneo, modalidad, puntos, habilitado)
-			VALUES ($1, $2, $3, $4, 0, true)`
-
-		_, err = tx.Exec(insertQuery, userID, team, torneoID, modalidad)
-		if err != nil {
-			return fmt.Errorf("error al inscribir usuario: %w", err)
-		}
-
-		// Actualizar estadísticas del usuario
-		updateStatsQuery := `
-			UPDATE user_stats
-			SET torneos_participados = torneos_participados + 1, torneo_id = $1
-			WHERE user_id = $2`
-
-		_, err = tx.Exec(updateStatsQuery, torneoID, userID)
-		if err != nil {
-			return fmt.Errorf("error al actualizar estadísticas: %w", err)
-		}
-
-		return nil
+		inscripcion, err = inscribirEnTorneo(tx, torneoID, userID, equipoID, equipoLegacy, false)
+		return err
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return inscripcion, nil
 }
 
 // GetTorneosRelacionadosUsuario obtiene todos los torneos relacionados con un usuario,
@@ -646,12 +939,13 @@ func (r *TorneoRepository) GetTorneosRelacionadosUsuario(userID string) ([]model
 	// Resultado final que contendrá todos los torneos relacionados con el usuario
 	var torneos []models.TorneoResumen
 
-	// Consulta para obtener torneos creados por el usuario
+	// Consulta para obtener torneos que el usuario administra (como dueño o coorganizador)
 	queryCreados := `
-		SELECT id, nombre
-		FROM torneos
-		WHERE id_creator = $1
-		ORDER BY fecha_inicio DESC`
+		SELECT t.id, t.nombre
+		FROM torneo_staff s
+		JOIN torneos t ON t.id = s.id_torneo
+		WHERE s.user_id = $1
+		ORDER BY t.fecha_inicio DESC`
 
 	rowsCreados, err := r.db.Query(queryCreados, userID)
 	if err != nil {
@@ -675,7 +969,8 @@ FROM (
     SELECT DISTINCT ON (t.id) t.id, t.nombre, t.fecha_inicio
     FROM torneo_estadisticas te
     JOIN torneos t ON te.id_torneo = t.id
-    WHERE te.id_jugador = $1 AND t.id_creator != $1
+    WHERE te.id_jugador = $1
+      AND NOT EXISTS (SELECT 1 FROM torneo_staff s WHERE s.id_torneo = t.id AND s.user_id = $1)
     ORDER BY t.id, t.fecha_inicio DESC
 ) AS t  -- Alias correcto para la subconsulta
 ORDER BY t.fecha_inicio DESC;
@@ -721,6 +1016,23 @@ func (r *TorneoRepository) SalirTorneo(userID string, torneoID string) error {
 		}
 
 		if !exists {
+			// Si estaba en la lista de espera, salir de ella
+			result, err := tx.Exec(`
+				DELETE FROM torneo_lista_espera
+				WHERE id_torneo = $1 AND id_jugador = $2`, torneoID, userID)
+			if err != nil {
+				return fmt.Errorf("error al salir de la lista de espera: %w", err)
+			}
+
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+
+			if rowsAffected > 0 {
+				return nil
+			}
+
 			return fmt.Errorf("el usuario no está inscrito en este torneo")
 		}
 
@@ -745,19 +1057,27 @@ func (r *TorneoRepository) SalirTorneo(userID string, torneoID string) error {
 			return fmt.Errorf("error al actualizar estadísticas de usuario: %w", err)
 		}
 
-		return nil
+		err = revocarMedallasIncumplidas(tx, []string{userID}, []string{models.MetricaTorneos, models.FuenteTorneos},
+			r.zonaHoraria, "Saliste de un torneo.", &torneoID)
+		if err != nil {
+			return err
+		}
+
+		// El lugar liberado se ofrece a la lista de espera
+		return promoverListaEspera(tx, torneoID)
 	})
 }
 
-// GetEquipoUsuarioTorneo obtiene el equipo al que pertenece un usuario en un torneo específico
-func (r *TorneoRepository) GetEquipoUsuarioTorneo(torneoID string, userID string) (*bool, error) {
+// GetEquipoUsuarioTorneo obtiene el equipo al que pertenece un usuario en un torneo específico.
+// Devuelve nil si el torneo no es por equipos
+func (r *TorneoRepository) GetEquipoUsuarioTorneo(torneoID string, userID string) (*models.TorneoEquipo, error) {
 	query := `
-		SELECT equipo
+		SELECT id_equipo
 		FROM torneo_estadisticas
 		WHERE id_torneo = $1 AND id_jugador = $2`
 
-	var equipo bool
-	err := r.db.QueryRow(query, torneoID, userID).Scan(&equipo)
+	var equipoID *string
+	err := r.db.QueryRow(query, torneoID, userID).Scan(&equipoID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("el usuario no está inscrito en este torneo")
@@ -765,15 +1085,22 @@ func (r *TorneoRepository) GetEquipoUsuarioTorneo(torneoID string, userID string
 		return nil, fmt.Errorf("error al obtener equipo del usuario: %w", err)
 	}
 
-	return &equipo, nil
+	if equipoID == nil {
+		return nil, nil
+	}
+
+	return r.GetEquipo(torneoID, *equipoID)
 }
 
-// FindExpiredTournaments busca los torneos cuya fecha de fin ya ha pasado pero no están marcados como finalizados
+// FindExpiredTournaments busca los torneos cuya fecha de fin (más el periodo de
+// verificación, si lo tienen) ya ha pasado pero no están marcados como finalizados.
+// Devuelve sus IDs
 func (r *TorneoRepository) FindExpiredTournaments() ([]string, error) {
 	query := `
-		SELECT id_creator
+		SELECT id
 		FROM torneos
-		WHERE fecha_fin < NOW() AND finalizado = false`
+		WHERE fecha_fin + make_interval(mins => COALESCE(minutos_verificacion, 0)) < NOW()
+		AND finalizado = false`
 
 	rows, err := r.db.Query(query)
 	if err != nil {
@@ -781,20 +1108,20 @@ func (r *TorneoRepository) FindExpiredTournaments() ([]string, error) {
 	}
 	defer rows.Close()
 
-	var creatorIDs []string
+	var torneoIDs []string
 	for rows.Next() {
-		var creatorID string
-		if err := rows.Scan(&creatorID); err != nil {
-			return nil, fmt.Errorf("error al leer ID del creador: %w", err)
+		var torneoID string
+		if err := rows.Scan(&torneoID); err != nil {
+			return nil, fmt.Errorf("error al leer ID del torneo: %w", err)
 		}
-		creatorIDs = append(creatorIDs, creatorID)
+		torneoIDs = append(torneoIDs, torneoID)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error al procesar resultados: %w", err)
 	}
 
-	return creatorIDs, nil
+	return torneoIDs, nil
 }
 
 // UpdateTorneoFechaFin actualiza solo la fecha de fin de un torneo específico
@@ -802,12 +1129,21 @@ func (r *TorneoRepository) UpdateTorneoFechaFin(id string, fechaFin string) erro
 	query := `
 		UPDATE torneos
 		SET fecha_fin = $1
-		WHERE id = $2`
+		WHERE id = $2 AND finalizado = false`
 
-	_, err := r.db.Exec(query, fechaFin, id)
+	result, err := r.db.Exec(query, fechaFin, id)
 	if err != nil {
 		return fmt.Errorf("error al actualizar fecha de fin del torneo: %w", err)
 	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTorneoFinalizado
+	}
+
 	return nil
 }
@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/pkg/database"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNoEsDueno               = errors.New("solo el dueño del torneo puede realizar esta acción")
+	ErrYaEsOrganizador         = errors.New("el usuario ya es organizador de este torneo")
+	ErrOrganizadorNoParticipa  = errors.New("los organizadores no pueden participar en su propio torneo")
+	ErrOrganizadorNoEncontrado = errors.New("el usuario no es coorganizador de este torneo")
+	ErrNuevoDuenoOcupado       = errors.New("el nuevo dueño ya administra o participa en otro torneo activo")
+)
+
+// esStaffTorneo indica si el usuario es dueño o coorganizador del torneo
+func esStaffTorneo(tx *sql.Tx, torneoID string, userID string) (bool, error) {
+	var esStaff bool
+	err := tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM torneo_staff WHERE id_torneo = $1 AND user_id = $2
+		)`, torneoID, userID).Scan(&esStaff)
+	if err != nil {
+		return false, fmt.Errorf("error al verificar los organizadores del torneo: %w", err)
+	}
+
+	return esStaff, nil
+}
+
+// EsDueno indica si el usuario es el dueño del torneo
+func (r *TorneoRepository) EsDueno(torneoID string, userID string) (bool, error) {
+	var esDueno bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM torneo_staff
+			WHERE id_torneo = $1 AND user_id = $2 AND rol = 'dueno'
+		)`, torneoID, userID).Scan(&esDueno)
+	if err != nil {
+		return false, fmt.Errorf("error al verificar el dueño del torneo: %w", err)
+	}
+
+	return esDueno, nil
+}
+
+// GetStaff lista los organizadores de un torneo, empezando por el dueño
+func (r *TorneoRepository) GetStaff(torneoID string) ([]models.StaffTorneo, error) {
+	rows, err := r.db.Query(`
+		SELECT s.id_torneo, s.user_id, COALESCE(ub.nombre, ''), COALESCE(ub.apellido, ''),
+			s.rol, s.created_at
+		FROM torneo_staff s
+		LEFT JOIN user_basic_info ub ON ub.user_id = s.user_id
+		WHERE s.id_torneo = $1
+		ORDER BY s.rol = 'dueno' DESC, s.created_at`, torneoID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los organizadores: %w", err)
+	}
+	defer rows.Close()
+
+	staff := []models.StaffTorneo{}
+	for rows.Next() {
+		var s models.StaffTorneo
+		if err := rows.Scan(&s.IDTorneo, &s.UserID, &s.Nombre, &s.Apellido, &s.Rol, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error al leer organizador: %w", err)
+		}
+		staff = append(staff, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar los organizadores: %w", err)
+	}
+
+	return staff, nil
+}
+
+// AgregarCoorganizador suma un coorganizador al torneo. No puede estar inscrito en él;
+// si estaba en la lista de espera se lo quita
+func (r *TorneoRepository) AgregarCoorganizador(torneoID string, userID string) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		if err := verificarTorneoActivo(tx, torneoID); err != nil {
+			return err
+		}
+
+		var esStaff, inscrito bool
+		err := tx.QueryRow(`
+			SELECT
+				EXISTS (SELECT 1 FROM torneo_staff WHERE id_torneo = $1 AND user_id = $2),
+				EXISTS (SELECT 1 FROM torneo_estadisticas WHERE id_torneo = $1 AND id_jugador = $2)`,
+			torneoID, userID).Scan(&esStaff, &inscrito)
+		if err != nil {
+			return fmt.Errorf("error al verificar al usuario: %w", err)
+		}
+
+		if esStaff {
+			return ErrYaEsOrganizador
+		}
+
+		if inscrito {
+			return ErrOrganizadorNoParticipa
+		}
+
+		_, err = tx.Exec(`
+			DELETE FROM torneo_lista_espera
+			WHERE id_torneo = $1 AND id_jugador = $2`, torneoID, userID)
+		if err != nil {
+			return fmt.Errorf("error al quitar al usuario de la lista de espera: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO torneo_staff (id_torneo, user_id, rol)
+			VALUES ($1, $2, 'coorganizador')`, torneoID, userID)
+		if err != nil {
+			return fmt.Errorf("error al agregar el coorganizador: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// QuitarCoorganizador quita a un coorganizador del torneo. El dueño no puede quitarse:
+// primero debe transferir el torneo
+func (r *TorneoRepository) QuitarCoorganizador(torneoID string, userID string) error {
+	result, err := r.db.Exec(`
+		DELETE FROM torneo_staff
+		WHERE id_torneo = $1 AND user_id = $2 AND rol = 'coorganizador'`, torneoID, userID)
+	if err != nil {
+		return fmt.Errorf("error al quitar el coorganizador: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrOrganizadorNoEncontrado
+	}
+
+	return nil
+}
+
+// TransferirPropiedad cede el torneo a otro usuario, que no puede participar en él ni
+// administrar o jugar otro torneo activo. El dueño anterior puede seguir como coorganizador
+func (r *TorneoRepository) TransferirPropiedad(torneoID string, duenoActual string, nuevoDueno string, seguirComoCoorganizador bool) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		if err := verificarTorneoActivo(tx, torneoID); err != nil {
+			return err
+		}
+
+		var inscrito, ocupado bool
+		err := tx.QueryRow(`
+			SELECT
+				EXISTS (
+					SELECT 1 FROM torneo_estadisticas
+					WHERE id_torneo = $1 AND id_jugador = $2
+				),
+				EXISTS (
+					SELECT 1 FROM torneos
+					WHERE id_creator = $2 AND finalizado = false AND id <> $1
+				)
+				OR EXISTS (
+					SELECT 1 FROM torneo_estadisticas te
+					JOIN torneos t ON t.id = te.id_torneo
+					WHERE te.id_jugador = $2 AND t.finalizado = false AND t.id <> $1
+				)`, torneoID, nuevoDueno).Scan(&inscrito, &ocupado)
+		if err != nil {
+			return fmt.Errorf("error al verificar al nuevo dueño: %w", err)
+		}
+
+		if inscrito {
+			return ErrOrganizadorNoParticipa
+		}
+
+		if ocupado {
+			return ErrNuevoDuenoOcupado
+		}
+
+		// El dueño anterior pasa a coorganizador o deja el equipo de organizadores
+		if seguirComoCoorganizador {
+			_, err = tx.Exec(`
+				UPDATE torneo_staff SET rol = 'coorganizador'
+				WHERE id_torneo = $1 AND user_id = $2`, torneoID, duenoActual)
+		} else {
+			_, err = tx.Exec(`
+				DELETE FROM torneo_staff
+				WHERE id_torneo = $1 AND user_id = $2`, torneoID, duenoActual)
+		}
+		if err != nil {
+			return fmt.Errorf("error al actualizar al dueño anterior: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO torneo_staff (id_torneo, user_id, rol)
+			VALUES ($1, $2, 'dueno')
+			ON CONFLICT (id_torneo, user_id) DO UPDATE SET rol = 'dueno'`, torneoID, nuevoDueno)
+		if err != nil {
+			return fmt.Errorf("error al asignar el nuevo dueño: %w", err)
+		}
+
+		_, err = tx.Exec(`UPDATE torneos SET id_creator = $1 WHERE id = $2`, nuevoDueno, torneoID)
+		if err != nil {
+			return fmt.Errorf("error al transferir el torneo: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE user_stats
+			SET es_dueno_torneo = false, torneo_id = null
+			WHERE user_id = $1`, duenoActual)
+		if err != nil {
+			return fmt.Errorf("error al actualizar estadísticas del dueño anterior: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE user_stats
+			SET es_dueno_torneo = true, torneo_id = $1
+			WHERE user_id = $2`, torneoID, nuevoDueno)
+		if err != nil {
+			return fmt.Errorf("error al actualizar estadísticas del nuevo dueño: %w", err)
+		}
+
+		return nil
+	})
+}
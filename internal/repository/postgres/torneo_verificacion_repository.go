@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/pkg/database"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrAccionNoEncontrada = errors.New("la acción no existe o no puntuó en este torneo")
+	ErrAccionYaAnulada    = errors.New("la acción ya fue anulada")
+)
+
+// GetAccionesTorneo devuelve las acciones que puntuaron en el torneo, incluidas las
+// anuladas, de la más reciente a la más antigua
+func (r *TorneoRepository) GetAccionesTorneo(torneoID string) ([]models.UserAction, error) {
+	query := `
+		SELECT id, user_id, tipo_accion, foto, latitud, longitud, ciudad, lugar,
+			en_colaboracion, colaboradores, es_para_torneo, id_torneo,
+			puntos_torneo, created_at, anulada_at, anulada_por, motivo_anulacion, puntos_anulados
+		FROM user_actions
+		WHERE id_torneo = $1 AND deleted_at IS NULL
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query, torneoID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las acciones del torneo: %w", err)
+	}
+	defer rows.Close()
+
+	acciones := []models.UserAction{}
+	for rows.Next() {
+		var a models.UserAction
+		var colaboradores []string
+		err := rows.Scan(
+			&a.ID, &a.UserID, &a.TipoAccion, &a.Foto,
+			&a.Latitud, &a.Longitud, &a.Ciudad, &a.Lugar,
+			&a.EnColaboracion, pq.Array(&colaboradores), &a.EsParaTorneo, &a.IDTorneo,
+			&a.PuntosTorneo, &a.CreatedAt, &a.AnuladaAt, &a.AnuladaPor, &a.MotivoAnulacion, &a.PuntosAnulados,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer acción: %w", err)
+		}
+		if len(colaboradores) > 0 {
+			a.Colaboradores = &colaboradores
+		}
+		acciones = append(acciones, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar las acciones: %w", err)
+	}
+
+	return acciones, nil
+}
+
+// AnularAccion anula una acción que puntuó en un torneo sin finalizar (en curso o
+// pendiente de verificación): sus puntos se descuentan del jugador y, en eliminatoria,
+// se recalcula el enfrentamiento en el que se registró si su resultado todavía no dio
+// paso a otra ronda. La acción sigue en el historial del jugador y se le avisa
+func (r *TorneoRepository) AnularAccion(torneoID, actionID, organizadorID, motivo string) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var nombre string
+		var finalizado bool
+		err := tx.QueryRow(`
+			SELECT nombre, finalizado
+			FROM torneos
+			WHERE id = $1
+			FOR UPDATE`, torneoID).Scan(&nombre, &finalizado)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return err
+			}
+			return fmt.Errorf("error al buscar torneo: %w", err)
+		}
+
+		if finalizado {
+			return ErrTorneoFinalizado
+		}
+
+		var userID string
+		var puntos int
+		var anulada bool
+		var creada time.Time
+		err = tx.QueryRow(`
+			SELECT user_id, puntos_torneo, anulada_at IS NOT NULL, created_at
+			FROM user_actions
+			WHERE id = $1 AND id_torneo = $2 AND deleted_at IS NULL
+			FOR UPDATE`, actionID, torneoID).Scan(&userID, &puntos, &anulada, &creada)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrAccionNoEncontrada
+			}
+			return fmt.Errorf("error al buscar la acción: %w", err)
+		}
+
+		if anulada {
+			return ErrAccionYaAnulada
+		}
+
+		_, err = tx.Exec(`
+			UPDATE user_actions
+			SET anulada_at = NOW(), anulada_por = $2, motivo_anulacion = $3,
+				puntos_anulados = puntos_torneo, puntos_torneo = 0
+			WHERE id = $1`, actionID, organizadorID, motivo)
+		if err != nil {
+			return fmt.Errorf("error al anular la acción: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE torneo_estadisticas
+			SET puntos = GREATEST(0, puntos - $3)
+			WHERE id_torneo = $1 AND id_jugador = $2`, torneoID, userID, puntos)
+		if err != nil {
+			return fmt.Errorf("error al descontar los puntos de la acción: %w", err)
+		}
+
+		if err := recalcularEnfrentamiento(tx, torneoID, creada); err != nil {
+			return err
+		}
+
+		mensaje := fmt.Sprintf("Un organizador anuló una de tus acciones en el torneo \"%s\": %s", nombre, motivo)
+		if err := crearNotificacion(tx, userID, models.NotificacionAccionAnulada, mensaje, &torneoID); err != nil {
+			return err
+		}
+
+		// Los puntos del torneo bajaron, así que se revisan las medallas que los cuentan
+		return revocarMedallasIncumplidas(tx, []string{userID}, []string{models.FuenteTorneos},
+			r.zonaHoraria, fmt.Sprintf("Se anuló una de tus acciones en el torneo \"%s\".", nombre), &torneoID)
+	})
+}
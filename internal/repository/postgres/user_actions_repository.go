@@ -21,19 +21,53 @@ import (
 
 type UserActionsRepository struct {
 	db *sql.DB
+	// Zona horaria (nombre IANA) en la que empieza el día para el tope diario de puntos
+	zonaHoraria string
 }
 
-func NewUserActionsRepository(db *sql.DB) *UserActionsRepository {
-	return &UserActionsRepository{db: db}
+func NewUserActionsRepository(db *sql.DB, zonaHoraria string) *UserActionsRepository {
+	return &UserActionsRepository{db: db, zonaHoraria: zonaHoraria}
 }
 
+// puntosBaseAccion devuelve los puntos por defecto de un tipo de acción. Los torneos
+// pueden sobrescribirlos con sus propias reglas
+func puntosBaseAccion(tipoAccion string) int {
+	switch tipoAccion {
+	case "ayuda":
+		return 50
+	case "alerta":
+		return 40
+	case "descubrimiento":
+		return 25
+	default:
+		return 5
+	}
+}
+
+// CreateAction registra una acción y suma sus puntos. Si action.EsParaTorneo está
+// activo, la acción se puntúa con las reglas del torneo action.IDTorneo y el
+// resultado queda en action.ResultadoTorneo; solo se vincula al torneo si puntuó
 func (r *UserActionsRepository) CreateAction(action *models.UserAction) error {
 	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		if action.EsParaTorneo && action.IDTorneo != nil {
+			resultado, err := puntuarAccionTorneo(tx, action, *action.IDTorneo, r.zonaHoraria)
+			if err != nil {
+				return err
+			}
+
+			action.ResultadoTorneo = resultado
+			action.PuntosTorneo = resultado.Puntos
+			if !resultado.Puntua {
+				action.EsParaTorneo = false
+				action.IDTorneo = nil
+			}
+		}
+
 		query := `
 			INSERT INTO user_actions (
 				user_id, tipo_accion, foto, latitud, longitud, ciudad, lugar,
-				en_colaboracion, colaboradores, es_para_torneo, id_torneo
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+				en_colaboracion, colaboradores, es_para_torneo, id_torneo, puntos_torneo
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 			RETURNING id, created_at`
 
 		err := tx.QueryRow(
@@ -49,6 +83,7 @@ func (r *UserActionsRepository) CreateAction(action *models.UserAction) error {
 			pq.Array(action.Colaboradores),
 			action.EsParaTorneo,
 			action.IDTorneo,
+			action.PuntosTorneo,
 		).Scan(&action.ID, &action.CreatedAt)
 
 		if err != nil {
@@ -61,38 +96,140 @@ func (r *UserActionsRepository) CreateAction(action *models.UserAction) error {
 			SET acciones = acciones + 1, puntos = puntos + $1
 			WHERE user_id = $2`
 
-		var puntos int
-		switch action.TipoAccion {
-		case "ayuda":
-			puntos = 50
-		case "alerta":
-			puntos = 40
-		case "descubrimiento":
-			puntos = 25
-		default:
-			puntos = 5
+		_, err = tx.Exec(updateStatsQuery, puntosBaseAccion(action.TipoAccion), action.UserID)
+		if err != nil {
+			return err
+		}
+
+		// Con los nuevos puntos se pueden desbloquear artículos de avatar
+		_, err = desbloquearItemsAvatar(tx, action.UserID)
+		return err
+	})
+}
+
+// puntuarAccionTorneo calcula con las reglas del torneo los puntos de una acción que
+// aún no se ha guardado y los suma a las estadísticas del jugador en el torneo.
+// Si la acción no puntúa, el resultado indica el motivo. El tope diario se cuenta desde
+// la medianoche de zonaHoraria
+func puntuarAccionTorneo(tx *sql.Tx, action *models.UserAction, torneoID string, zonaHoraria string) (*models.ResultadoTorneo, error) {
+	resultado := &models.ResultadoTorneo{IDTorneo: torneoID}
+
+	// La fila del jugador se bloquea para que el tope diario se respete con acciones concurrentes
+	query := `
+		SELECT te.habilitado, t.fecha_inicio > NOW(), t.fecha_fin <= NOW(),
+			t.max_puntos_diarios, t.bono_lugar_nuevo,
+			ra.puntos, COALESCE(ra.habilitado, true)
+		FROM torneo_estadisticas te
+		JOIN torneos t ON t.id = te.id_torneo
+		LEFT JOIN torneo_reglas_accion ra ON ra.id_torneo = t.id AND ra.tipo_accion = $3
+		WHERE te.id_torneo = $1 AND te.id_jugador = $2
+		FOR UPDATE OF te`
+
+	var habilitado, noIniciado, terminado, tipoHabilitado bool
+	var maxPuntosDiarios, puntosRegla *int
+	var bonoLugarNuevo int
+	err := tx.QueryRow(query, torneoID, action.UserID, action.TipoAccion).Scan(&habilitado, &noIniciado, &terminado,
+		&maxPuntosDiarios, &bonoLugarNuevo, &puntosRegla, &tipoHabilitado)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			resultado.Motivo = "No estás inscrito en este torneo"
+			return resultado, nil
 		}
+		return nil, fmt.Errorf("error al obtener las reglas del torneo: %w", err)
+	}
+
+	// Las acciones solo puntúan dentro de las fechas del torneo
+	if noIniciado {
+		resultado.Motivo = "El torneo todavía no comienza"
+		return resultado, nil
+	}
 
-		_, err = tx.Exec(updateStatsQuery, puntos, action.UserID)
+	// Tras la fecha de fin (también durante la verificación) ya no se suman puntos
+	if terminado {
+		resultado.Motivo = "El torneo ya terminó"
+		return resultado, nil
+	}
+
+	if !habilitado {
+		resultado.Motivo = "Tu puntuación en este torneo fue deshabilitada por el organizador"
+		return resultado, nil
+	}
+
+	if !tipoHabilitado {
+		resultado.Motivo = fmt.Sprintf("Este torneo no otorga puntos por acciones de tipo %s", action.TipoAccion)
+		return resultado, nil
+	}
+
+	puntos := puntosBaseAccion(action.TipoAccion)
+	if puntosRegla != nil {
+		puntos = *puntosRegla
+	}
+
+	// Bono por la primera acción del jugador en un lugar dentro del torneo. Sin lugar
+	// no se puede saber si es nuevo, así que no hay bono
+	if bonoLugarNuevo > 0 && strings.TrimSpace(action.Lugar) != "" {
+		var visitado bool
+		err = tx.QueryRow(`
+			SELECT EXISTS (
+				SELECT 1 FROM user_actions
+				WHERE user_id = $1 AND id_torneo = $2 AND lugar = $3
+				AND deleted_at IS NULL AND anulada_at IS NULL
+			)`, action.UserID, torneoID, action.Lugar).Scan(&visitado)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("error al verificar el lugar de la acción: %w", err)
 		}
 
-		// Si es para un torneo, actualizar puntos del torneo
-		if action.EsParaTorneo && action.IDTorneo != nil {
-			updateTorneoQuery := `
-				UPDATE torneo_estadisticas
-				SET puntos = puntos + $1
-				WHERE id_jugador = $2 AND id_torneo = $3`
+		if !visitado {
+			puntos += bonoLugarNuevo
+			resultado.BonoLugarNuevo = bonoLugarNuevo
+		}
+	}
 
-			_, err = tx.Exec(updateTorneoQuery, puntos, action.UserID, *action.IDTorneo)
-			if err != nil {
-				return err
-			}
+	// Tope de puntos por jugador y día
+	if maxPuntosDiarios != nil {
+		var puntosHoy int
+		err = tx.QueryRow(`
+			SELECT COALESCE(SUM(puntos_torneo), 0)
+			FROM user_actions
+			WHERE user_id = $1 AND id_torneo = $2 AND deleted_at IS NULL
+			AND created_at >= date_trunc('day', NOW() AT TIME ZONE $3) AT TIME ZONE $3`,
+			action.UserID, torneoID, zonaHoraria).Scan(&puntosHoy)
+		if err != nil {
+			return nil, fmt.Errorf("error al obtener los puntos del día: %w", err)
 		}
 
-		return nil
-	})
+		restantes := *maxPuntosDiarios - puntosHoy
+		if restantes <= 0 {
+			resultado.BonoLugarNuevo = 0
+			resultado.Motivo = fmt.Sprintf("Alcanzaste el máximo de %d puntos diarios de este torneo", *maxPuntosDiarios)
+			return resultado, nil
+		}
+
+		if puntos > restantes {
+			puntos = restantes
+			resultado.Motivo = fmt.Sprintf("Solo sumaste %d puntos porque alcanzaste el máximo de %d puntos diarios de este torneo",
+				puntos, *maxPuntosDiarios)
+		}
+	}
+
+	if puntos == 0 {
+		resultado.Motivo = fmt.Sprintf("Este torneo no otorga puntos por acciones de tipo %s", action.TipoAccion)
+		return resultado, nil
+	}
+
+	updateTorneoQuery := `
+		UPDATE torneo_estadisticas
+		SET puntos = puntos + $1
+		WHERE id_jugador = $2 AND id_torneo = $3`
+
+	_, err = tx.Exec(updateTorneoQuery, puntos, action.UserID, torneoID)
+	if err != nil {
+		return nil, fmt.Errorf("error al actualizar puntos del torneo: %w", err)
+	}
+
+	resultado.Puntua = true
+	resultado.Puntos = puntos
+	return resultado, nil
 }
 
 func (r *UserActionsRepository) GetTorneoID(userID string) (string, error) {
@@ -113,31 +250,30 @@ func (r *UserActionsRepository) GetTorneoID(userID string) (string, error) {
 	return torneoID, nil
 }
 
-func (r *UserActionsRepository) TorneoPoints(userID string, actionType string, torneoID string) (int, error) {
-	var puntos int
-	switch actionType {
-	case "ayuda":
-		puntos = 50
-	case "alerta":
-		puntos = 40
-	case "descubrimiento":
-		puntos = 25
-	default:
-		puntos = 5
+// GetGeocercaTorneo obtiene el área del torneo en la que las acciones puntúan.
+// Si el torneo usa radio pero no lo define, se usan sus metros aproximados
+func (r *UserActionsRepository) GetGeocercaTorneo(torneoID string) (*models.Geocerca, error) {
+	query := `
+		SELECT geocerca_tipo, COALESCE(geocerca_radio_metros, metros_aproximados),
+			ubicacion_a_latitud, ubicacion_a_longitud,
+			ubicacion_b_latitud, ubicacion_b_longitud, geocerca_poligono
+		FROM torneos
+		WHERE id = $1`
+
+	var geocerca models.Geocerca
+	var latitudB, longitudB *float64
+	err := r.db.QueryRow(query, torneoID).Scan(&geocerca.Tipo, &geocerca.RadioMetros,
+		&geocerca.PuntoA.Latitud, &geocerca.PuntoA.Longitud,
+		&latitudB, &longitudB, &geocerca.Poligono)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la geocerca del torneo: %w", err)
 	}
 
-	// Actualizar puntos del torneo si es necesario
-	updateTorneoQuery := `
-		UPDATE torneo_estadisticas
-		SET puntos = puntos + $1
-		WHERE id_jugador = $2 AND id_torneo = $3`
-
-	_, err := r.db.Exec(updateTorneoQuery, puntos, userID, torneoID)
-	if err != nil {
-		return 0, fmt.Errorf("error al actualizar puntos del torneo: %w", err)
+	if latitudB != nil && longitudB != nil {
+		geocerca.PuntoB = &models.PuntoGeo{Latitud: *latitudB, Longitud: *longitudB}
 	}
 
-	return puntos, nil
+	return &geocerca, nil
 }
 
 func (r *UserActionsRepository) UploadImage(file multipart.File) (string, error) {
@@ -210,7 +346,7 @@ func (r *UserActionsRepository) GetUserActions(userID string) ([]models.UserActi
 	query := `
 		SELECT id, user_id, tipo_accion, foto, latitud, longitud, ciudad, lugar,
 			en_colaboracion, colaboradores, es_para_torneo, id_torneo,
-			created_at
+			puntos_torneo, created_at, anulada_at, motivo_anulacion, puntos_anulados
 		FROM user_actions
 		WHERE user_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC`
@@ -229,7 +365,7 @@ func (r *UserActionsRepository) GetUserActions(userID string) ([]models.UserActi
 			&a.ID, &a.UserID, &a.TipoAccion, &a.Foto,
 			&a.Latitud, &a.Longitud, &a.Ciudad, &a.Lugar,
 			&a.EnColaboracion, pq.Array(&colaboradores), &a.EsParaTorneo, &a.IDTorneo,
-			&a.CreatedAt,
+			&a.PuntosTorneo, &a.CreatedAt, &a.AnuladaAt, &a.MotivoAnulacion, &a.PuntosAnulados,
 		)
 		if err != nil {
 			return nil, err
@@ -248,13 +384,14 @@ func (r *UserActionsRepository) SoftDeleteAction(id string) error {
 		var userID, tipoAccion string
 		var esParaTorneo bool
 		var idTorneo *string
+		var puntosTorneo int
 
 		getActionQuery := `
-			SELECT user_id, tipo_accion, es_para_torneo, id_torneo
+			SELECT user_id, tipo_accion, es_para_torneo, id_torneo, puntos_torneo
 			FROM user_actions
 			WHERE id = $1 AND deleted_at IS NULL`
 
-		err := tx.QueryRow(getActionQuery, id).Scan(&userID, &tipoAccion, &esParaTorneo, &idTorneo)
+		err := tx.QueryRow(getActionQuery, id).Scan(&userID, &tipoAccion, &esParaTorneo, &idTorneo, &puntosTorneo)
 		if err != nil {
 			return err
 		}
@@ -279,6 +416,19 @@ func (r *UserActionsRepository) SoftDeleteAction(id string) error {
 			return fmt.Errorf("no se encontró la acción o ya fue eliminada")
 		}
 
+		// Descontar los puntos que la acción sumó en un torneo que sigue en curso
+		if esParaTorneo && idTorneo != nil && puntosTorneo > 0 {
+			_, err = tx.Exec(`
+				UPDATE torneo_estadisticas te
+				SET puntos = GREATEST(0, te.puntos - $1)
+				FROM torneos t
+				WHERE t.id = te.id_torneo AND t.finalizado = false
+				AND te.id_torneo = $2 AND te.id_jugador = $3`, puntosTorneo, *idTorneo, userID)
+			if err != nil {
+				return fmt.Errorf("error al descontar los puntos del torneo: %w", err)
+			}
+		}
+
 		return nil
 	})
 }
@@ -320,7 +470,7 @@ func (r *UserActionsRepository) GetAllActions() ([]models.UserAction, error) {
 	query := `
 		SELECT id, user_id, tipo_accion, foto, latitud, longitud, ciudad, lugar,
 			en_colaboracion, colaboradores, es_para_torneo, id_torneo,
-			created_at
+			puntos_torneo, created_at, anulada_at, motivo_anulacion, puntos_anulados
 		FROM user_actions
 		WHERE deleted_at IS NULL
 		ORDER BY created_at DESC`
@@ -339,7 +489,7 @@ func (r *UserActionsRepository) GetAllActions() ([]models.UserAction, error) {
 			&a.ID, &a.UserID, &a.TipoAccion, &a.Foto,
 			&a.Latitud, &a.Longitud, &a.Ciudad, &a.Lugar,
 			&a.EnColaboracion, pq.Array(&colaboradores), &a.EsParaTorneo, &a.IDTorneo,
-			&a.CreatedAt,
+			&a.PuntosTorneo, &a.CreatedAt, &a.AnuladaAt, &a.MotivoAnulacion, &a.PuntosAnulados,
 		)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,421 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/internal/utils"
+	"backend_proyecto_verde/pkg/database"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrItemAvatarNoEncontrado = errors.New("el artículo de avatar no existe")
+	ErrItemAvatarBloqueado    = errors.New("todavía no desbloqueas este artículo de avatar")
+	ErrItemAvatarDuplicado    = errors.New("ya existe un artículo de avatar con ese valor en la categoría")
+	ErrUsuarioNoEncontrado    = errors.New("usuario no encontrado")
+	ErrPerfilNoEncontrado     = errors.New("el perfil no existe")
+)
+
+const columnasAvatarItem = `ai.id, ai.categoria, ai.valor, ai.nombre, ai.tipo_desbloqueo, ai.umbral,
+	ai.id_medalla, ai.capa, ai.created_at`
+
+// ordenAvatarItems muestra primero los artículos libres y después los más fáciles
+const ordenAvatarItems = `ai.categoria, ai.tipo_desbloqueo <> 'libre', ai.tipo_desbloqueo, ai.umbral NULLS FIRST, ai.valor`
+
+func destinosAvatarItem(i *models.AvatarItem) []interface{} {
+	return []interface{}{&i.ID, &i.Categoria, &i.Valor, &i.Nombre, &i.TipoDesbloqueo, &i.Umbral,
+		&i.IDMedalla, &i.Capa, &i.CreatedAt}
+}
+
+// GetAvatarItems devuelve el catálogo de artículos de avatar, de una sola categoría si
+// se indica
+func (r *UserRepository) GetAvatarItems(categoria string) ([]models.AvatarItem, error) {
+	rows, err := r.db.Query(`
+		SELECT `+columnasAvatarItem+`
+		FROM avatar_items ai
+		WHERE $1 = '' OR ai.categoria = $1
+		ORDER BY `+ordenAvatarItems, categoria)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los artículos de avatar: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.AvatarItem{}
+	for rows.Next() {
+		var item models.AvatarItem
+		if err := rows.Scan(destinosAvatarItem(&item)...); err != nil {
+			return nil, fmt.Errorf("error al leer artículo de avatar: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// CreateAvatarItem agrega un artículo al catálogo y lo desbloquea a los usuarios que ya
+// cumplen su condición
+func (r *UserRepository) CreateAvatarItem(item *models.AvatarItem) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		if item.IDMedalla != nil {
+			var existe bool
+			err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM medallas WHERE id = $1)`, *item.IDMedalla).Scan(&existe)
+			if err != nil {
+				return fmt.Errorf("error al verificar la medalla: %w", err)
+			}
+			if !existe {
+				return ErrMedallaNoEncontrada
+			}
+		}
+
+		var duplicado bool
+		err := tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM avatar_items WHERE categoria = $1 AND valor = $2)`,
+			item.Categoria, item.Valor).Scan(&duplicado)
+		if err != nil {
+			return fmt.Errorf("error al verificar el artículo de avatar: %w", err)
+		}
+		if duplicado {
+			return ErrItemAvatarDuplicado
+		}
+
+		err = tx.QueryRow(`
+			INSERT INTO avatar_items (categoria, valor, nombre, tipo_desbloqueo, umbral, id_medalla)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, created_at`,
+			item.Categoria, item.Valor, item.Nombre, item.TipoDesbloqueo, item.Umbral, item.IDMedalla,
+		).Scan(&item.ID, &item.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("error al crear el artículo de avatar: %w", err)
+		}
+
+		return desbloquearItemExistentes(tx, item)
+	})
+}
+
+// desbloquearItemExistentes guarda un artículo nuevo en el inventario de los usuarios
+// que ya cumplen su condición
+func desbloquearItemExistentes(tx *sql.Tx, item *models.AvatarItem) error {
+	var condicion string
+	var valor interface{}
+	switch item.TipoDesbloqueo {
+	case models.DesbloqueoMedalla:
+		condicion = `u.id IN (SELECT id_usuario FROM medallas_ganadas WHERE id_medalla = $2)`
+		valor = *item.IDMedalla
+	case models.DesbloqueoPuntos:
+		condicion = `u.id IN (SELECT user_id FROM user_stats WHERE puntos >= $2)`
+		valor = *item.Umbral
+	case models.DesbloqueoNivel:
+		condicion = `u.id IN (SELECT user_id FROM user_stats WHERE puntos >= $2)`
+		valor = utils.PuntosParaNivel(*item.Umbral)
+	case models.DesbloqueoVictoriaTorneo:
+		condicion = `u.id IN (SELECT user_id FROM user_stats WHERE torneos_ganados >= $2)`
+		valor = *item.Umbral
+	default:
+		// Los artículos libres no se guardan en el inventario
+		return nil
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO user_avatar_items (id_usuario, id_item)
+		SELECT u.id, $1 FROM user_access u
+		WHERE `+condicion+`
+		ON CONFLICT (id_usuario, id_item) DO NOTHING`, item.ID, valor)
+	if err != nil {
+		return fmt.Errorf("error al desbloquear el artículo a los usuarios: %w", err)
+	}
+	return nil
+}
+
+// desbloquearItemsMedalla guarda en el inventario del usuario los artículos que se
+// desbloquean con la medalla que acaba de ganar
+func desbloquearItemsMedalla(tx *sql.Tx, userID, medallaID string) error {
+	_, err := tx.Exec(`
+		INSERT INTO user_avatar_items (id_usuario, id_item)
+		SELECT $1, id FROM avatar_items
+		WHERE tipo_desbloqueo = $3 AND id_medalla = $2
+		ON CONFLICT (id_usuario, id_item) DO NOTHING`, userID, medallaID, models.DesbloqueoMedalla)
+	if err != nil {
+		return fmt.Errorf("error al desbloquear los artículos de la medalla: %w", err)
+	}
+	return nil
+}
+
+// desbloquearItemsGanadores revisa el inventario de los usuarios que acaban de sumar
+// una victoria de torneo
+func desbloquearItemsGanadores(tx *sql.Tx, userIDs []string) error {
+	for _, userID := range userIDs {
+		if _, err := desbloquearItemsAvatar(tx, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// logrosAvatar lee los puntos, torneos ganados y medallas del usuario
+func logrosAvatar(q consultor, userID string) (models.LogrosAvatar, error) {
+	var logros models.LogrosAvatar
+	err := q.QueryRow(`
+		SELECT COALESCE(us.puntos, 0), COALESCE(us.torneos_ganados, 0),
+			ARRAY(SELECT id_medalla::text FROM medallas_ganadas WHERE id_usuario = u.id)
+		FROM (SELECT $1::uuid AS id) u
+		LEFT JOIN user_stats us ON us.user_id = u.id`, userID,
+	).Scan(&logros.Puntos, &logros.TorneosGanados, pq.Array(&logros.Medallas))
+	if err != nil {
+		return logros, fmt.Errorf("error al obtener los logros del usuario: %w", err)
+	}
+
+	return logros, nil
+}
+
+// desbloquearItemsAvatar guarda en el inventario del usuario los artículos que sus
+// logros ya alcanzan y todavía no tenía
+func desbloquearItemsAvatar(tx *sql.Tx, userID string) (models.LogrosAvatar, error) {
+	logros, err := logrosAvatar(tx, userID)
+	if err != nil {
+		return logros, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT `+columnasAvatarItem+`
+		FROM avatar_items ai
+		WHERE ai.tipo_desbloqueo <> $2
+		AND NOT EXISTS (
+			SELECT 1 FROM user_avatar_items uai
+			WHERE uai.id_usuario = $1 AND uai.id_item = ai.id
+		)`, userID, models.DesbloqueoLibre)
+	if err != nil {
+		return logros, fmt.Errorf("error al obtener los artículos bloqueados: %w", err)
+	}
+	defer rows.Close()
+
+	var nuevos []string
+	for rows.Next() {
+		var item models.AvatarItem
+		if err := rows.Scan(destinosAvatarItem(&item)...); err != nil {
+			return logros, fmt.Errorf("error al leer artículo de avatar: %w", err)
+		}
+		if utils.ItemAvatarDesbloqueado(item, logros) {
+			nuevos = append(nuevos, item.ID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return logros, err
+	}
+
+	if len(nuevos) == 0 {
+		return logros, nil
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO user_avatar_items (id_usuario, id_item)
+		SELECT $1, unnest($2::uuid[])
+		ON CONFLICT (id_usuario, id_item) DO NOTHING`, userID, pq.Array(nuevos))
+	if err != nil {
+		return logros, fmt.Errorf("error al desbloquear artículos de avatar: %w", err)
+	}
+
+	return logros, nil
+}
+
+// GetInventarioAvatar devuelve el catálogo completo indicando qué artículos tiene
+// desbloqueados el usuario. Solo lee el inventario: los desbloqueos se guardan al
+// cambiar los logros del usuario
+func (r *UserRepository) GetInventarioAvatar(userID string) (*models.InventarioAvatar, error) {
+	var existe bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM user_access WHERE id = $1)`, userID).Scan(&existe)
+	if err != nil {
+		return nil, fmt.Errorf("error al verificar el usuario: %w", err)
+	}
+	if !existe {
+		return nil, ErrUsuarioNoEncontrado
+	}
+
+	logros, err := logrosAvatar(r.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT `+columnasAvatarItem+`, uai.desbloqueado_at
+		FROM avatar_items ai
+		LEFT JOIN user_avatar_items uai ON uai.id_item = ai.id AND uai.id_usuario = $1
+		ORDER BY `+ordenAvatarItems, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el inventario de avatar: %w", err)
+	}
+	defer rows.Close()
+
+	inventario := &models.InventarioAvatar{
+		Nivel:  utils.NivelPorPuntos(logros.Puntos),
+		Puntos: logros.Puntos,
+		Items:  []models.ItemInventarioAvatar{},
+	}
+	for rows.Next() {
+		var item models.ItemInventarioAvatar
+		destinos := append(destinosAvatarItem(&item.AvatarItem), &item.DesbloqueadoAt)
+		if err := rows.Scan(destinos...); err != nil {
+			return nil, fmt.Errorf("error al leer artículo del inventario: %w", err)
+		}
+		item.Desbloqueado = item.TipoDesbloqueo == models.DesbloqueoLibre || item.DesbloqueadoAt != nil
+		inventario.Items = append(inventario.Items, item)
+	}
+
+	return inventario, rows.Err()
+}
+
+// validarItemsAvatar verifica que el usuario tenga desbloqueados los artículos que
+// quiere ponerse. Los que ya lleva puestos se aceptan aunque no estén en el catálogo
+func validarItemsAvatar(tx *sql.Tx, actual *models.UserProfile, profile *models.EditProfile) error {
+	cambios := map[string][2]*string{
+		models.CategoriaCabello:          {profile.Cabello, &actual.Cabello},
+		models.CategoriaVestimenta:       {profile.Vestimenta, &actual.Vestimenta},
+		models.CategoriaBarba:            {profile.Barba, &actual.Barba},
+		models.CategoriaDetalleFacial:    {profile.DetalleFacial, &actual.DetalleFacial},
+		models.CategoriaDetalleAdicional: {profile.DetalleAdicional, &actual.DetalleAdicional},
+	}
+
+	desbloqueados := false
+	for _, categoria := range models.CategoriasAvatar {
+		nuevo, anterior := cambios[categoria][0], cambios[categoria][1]
+		if nuevo == nil || *nuevo == *anterior {
+			continue
+		}
+
+		if !desbloqueados {
+			if _, err := desbloquearItemsAvatar(tx, actual.UserID); err != nil {
+				return err
+			}
+			desbloqueados = true
+		}
+
+		var disponible bool
+		err := tx.QueryRow(`
+			SELECT ai.tipo_desbloqueo = $4 OR uai.id_item IS NOT NULL
+			FROM avatar_items ai
+			LEFT JOIN user_avatar_items uai ON uai.id_item = ai.id AND uai.id_usuario = $1
+			WHERE ai.categoria = $2 AND ai.valor = $3`,
+			actual.UserID, categoria, *nuevo, models.DesbloqueoLibre).Scan(&disponible)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s %q", ErrItemAvatarNoEncontrado, categoria, *nuevo)
+		}
+		if err != nil {
+			return fmt.Errorf("error al verificar el artículo de avatar: %w", err)
+		}
+		if !disponible {
+			return fmt.Errorf("%w: %s %q", ErrItemAvatarBloqueado, categoria, *nuevo)
+		}
+	}
+
+	return nil
+}
+
+// CambiarCapaAvatarItem guarda la URL de la capa del artículo y devuelve la anterior
+// para que se borre del almacenamiento. Los avatares que usan el artículo pierden su
+// hash para que se vuelvan a dibujar
+func (r *UserRepository) CambiarCapaAvatarItem(itemID, capa string) (*string, error) {
+	var anterior *string
+	err := database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var categoria, valor string
+		err := tx.QueryRow(`
+			SELECT categoria, valor, capa FROM avatar_items WHERE id = $1 FOR UPDATE`, itemID,
+		).Scan(&categoria, &valor, &anterior)
+		if err == sql.ErrNoRows {
+			return ErrItemAvatarNoEncontrado
+		}
+		if err != nil {
+			return fmt.Errorf("error al obtener el artículo de avatar: %w", err)
+		}
+
+		if _, err := tx.Exec(`UPDATE avatar_items SET capa = $2 WHERE id = $1`, itemID, capa); err != nil {
+			return fmt.Errorf("error al guardar la capa del artículo de avatar: %w", err)
+		}
+
+		// La categoría es el nombre de la columna de user_profile; viene de la base de
+		// datos y su CHECK solo admite las de models.CategoriasAvatar
+		if !slices.Contains(models.CategoriasAvatar, categoria) {
+			return fmt.Errorf("categoría de avatar desconocida: %q", categoria)
+		}
+		_, err = tx.Exec(`UPDATE user_profile SET avatar_hash = NULL WHERE `+categoria+` = $1`, valor)
+		if err != nil {
+			return fmt.Errorf("error al invalidar los avatares con el artículo: %w", err)
+		}
+
+		return nil
+	})
+
+	return anterior, err
+}
+
+// GetCapasAvatar devuelve el hash del avatar del usuario y sus capas en el orden en que
+// se dibujan: la URL de la capa subida o, si el artículo no tiene, la ruta
+// <categoria>/<valor>.png de la capa que trae la app. Los artículos fuera del catálogo
+// no se dibujan. Si el perfil cambió desde el último render, el hash se recalcula y se guarda
+func (r *UserRepository) GetCapasAvatar(userID string) (string, []string, error) {
+	var hash sql.NullString
+	var valores [5]string
+	err := r.db.QueryRow(`
+		SELECT avatar_hash, COALESCE(vestimenta, ''), COALESCE(detalle_facial, ''), COALESCE(barba, ''),
+			COALESCE(cabello, ''), COALESCE(detalle_adicional, '')
+		FROM user_profile
+		WHERE user_id = $1`, userID,
+	).Scan(&hash, &valores[0], &valores[1], &valores[2], &valores[3], &valores[4])
+	if err == sql.ErrNoRows {
+		return "", nil, ErrPerfilNoEncontrado
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("error al obtener el perfil: %w", err)
+	}
+
+	// Los valores se leen en el orden de models.OrdenCapasAvatar
+	rows, err := r.db.Query(`
+		SELECT c.orden, COALESCE(ai.capa, ai.categoria || '/' || ai.valor || '.png')
+		FROM unnest($1::text[], $2::text[]) WITH ORDINALITY AS c(categoria, valor, orden)
+		JOIN avatar_items ai ON ai.categoria = c.categoria AND ai.valor = c.valor`,
+		pq.Array(models.OrdenCapasAvatar), pq.Array(valores[:]))
+	if err != nil {
+		return "", nil, fmt.Errorf("error al obtener las capas del avatar: %w", err)
+	}
+	defer rows.Close()
+
+	porOrden := make(map[int]string)
+	for rows.Next() {
+		var orden int
+		var capa string
+		if err := rows.Scan(&orden, &capa); err != nil {
+			return "", nil, fmt.Errorf("error al leer capa del avatar: %w", err)
+		}
+		porOrden[orden] = capa
+	}
+	if err := rows.Err(); err != nil {
+		return "", nil, err
+	}
+
+	capas := []string{}
+	for orden := 1; orden <= len(models.OrdenCapasAvatar); orden++ {
+		if capa, ok := porOrden[orden]; ok {
+			capas = append(capas, capa)
+		}
+	}
+
+	if hash.Valid {
+		return hash.String, capas, nil
+	}
+
+	// Solo se guarda si el perfil no cambió mientras se leían las capas
+	nuevo := utils.HashAvatar(capas)
+	_, err = r.db.Exec(`
+		UPDATE user_profile SET avatar_hash = $2
+		WHERE user_id = $1 AND avatar_hash IS NULL
+		AND COALESCE(vestimenta, '') = $3 AND COALESCE(detalle_facial, '') = $4 AND COALESCE(barba, '') = $5
+		AND COALESCE(cabello, '') = $6 AND COALESCE(detalle_adicional, '') = $7`,
+		userID, nuevo, valores[0], valores[1], valores[2], valores[3], valores[4])
+	if err != nil {
+		return "", nil, fmt.Errorf("error al guardar el hash del avatar: %w", err)
+	}
+
+	return nuevo, capas, nil
+}
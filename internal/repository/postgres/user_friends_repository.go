@@ -14,11 +14,12 @@ var (
 )
 
 type UserFriendsRepository struct {
-	db *sql.DB
+	db          *sql.DB
+	zonaHoraria string // Zona con la que se reevalúan las rachas de días de las medallas
 }
 
-func NewUserFriendsRepository(db *sql.DB) *UserFriendsRepository {
-	return &UserFriendsRepository{db: db}
+func NewUserFriendsRepository(db *sql.DB, zonaHoraria string) *UserFriendsRepository {
+	return &UserFriendsRepository{db: db, zonaHoraria: zonaHoraria}
 }
 
 func (r *UserFriendsRepository) SendFriendRequest(userID, friendIDRequest string) error {
@@ -275,6 +276,13 @@ func (r *UserFriendsRepository) RemoveFriend(userID, friendID string) error {
 			WHERE user_id IN ($1, $2);
 			`
 			_, err = tx.Exec(query, userID, friendID)
+			if err != nil {
+				return err
+			}
+
+			// Los dos pueden perder medallas por cantidad de amigos
+			return revocarMedallasIncumplidas(tx, []string{userID, friendID},
+				[]string{models.MetricaAmigos, models.FuenteAmigos}, r.zonaHoraria, "Se eliminó una amistad.", nil)
 		} else {
 			// Es una solicitud pendiente
 			// Determinamos quién es el destinatario de la solicitud
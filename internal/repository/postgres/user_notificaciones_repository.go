@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"database/sql"
+	"fmt"
+)
+
+// crearNotificacion registra un aviso para el usuario dentro de la transacción en curso
+func crearNotificacion(tx *sql.Tx, userID string, tipo string, mensaje string, torneoID *string) error {
+	_, err := tx.Exec(`
+		INSERT INTO user_notificaciones (user_id, tipo, mensaje, id_torneo)
+		VALUES ($1, $2, $3, $4)`, userID, tipo, mensaje, torneoID)
+	if err != nil {
+		return fmt.Errorf("error al crear la notificación: %w", err)
+	}
+
+	return nil
+}
+
+// GetNotificaciones obtiene los avisos más recientes del usuario
+func (r *UserRepository) GetNotificaciones(userID string, soloNoLeidas bool, limit int) ([]models.Notificacion, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, tipo, mensaje, id_torneo, leida, created_at
+		FROM user_notificaciones
+		WHERE user_id = $1 AND (NOT $2 OR leida = false)
+		ORDER BY created_at DESC
+		LIMIT $3`, userID, soloNoLeidas, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las notificaciones: %w", err)
+	}
+	defer rows.Close()
+
+	notificaciones := []models.Notificacion{}
+	for rows.Next() {
+		var n models.Notificacion
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Tipo, &n.Mensaje, &n.IDTorneo, &n.Leida, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error al leer notificación: %w", err)
+		}
+		notificaciones = append(notificaciones, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar las notificaciones: %w", err)
+	}
+
+	return notificaciones, nil
+}
+
+// MarcarNotificacionesLeidas marca como leídos todos los avisos pendientes del usuario
+func (r *UserRepository) MarcarNotificacionesLeidas(userID string) error {
+	_, err := r.db.Exec(`
+		UPDATE user_notificaciones
+		SET leida = true
+		WHERE user_id = $1 AND leida = false`, userID)
+	if err != nil {
+		return fmt.Errorf("error al marcar las notificaciones como leídas: %w", err)
+	}
+
+	return nil
+}
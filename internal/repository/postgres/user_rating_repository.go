@@ -0,0 +1,251 @@
+package postgres
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"backend_proyecto_verde/internal/utils"
+	"database/sql"
+	"fmt"
+	"math"
+)
+
+// participanteRating es un jugador habilitado de un torneo con su rating actual
+type participanteRating struct {
+	userID             string
+	equipoID           *string
+	puntos             int
+	rating             int
+	torneosCalificados int
+}
+
+// actualizarRatingsTorneo ajusta el rating de los participantes habilitados de un
+// torneo que se está finalizando. En modalidad Individual cada jugador se compara con
+// los demás según su puntuación final; en Versus se comparan los equipos (con el rating
+// promedio de sus integrantes) y cada integrante recibe la variación de su equipo.
+// En los torneos eliminatoria se compara la ronda a la que llegó cada competidor en la
+// llave en lugar de los puntos. Si no hay al menos dos jugadores o dos equipos no se
+// modifica ningún rating
+func actualizarRatingsTorneo(tx *sql.Tx, torneoID string, modalidad string) error {
+	rows, err := tx.Query(`
+		SELECT te.id_jugador, te.id_equipo, te.puntos,
+			COALESCE(ur.rating, $2), COALESCE(ur.torneos_calificados, 0)
+		FROM torneo_estadisticas te
+		LEFT JOIN user_rating ur ON ur.user_id = te.id_jugador
+		WHERE te.id_torneo = $1 AND te.habilitado = true`, torneoID, utils.RatingInicial)
+	if err != nil {
+		return fmt.Errorf("error al obtener los ratings de los participantes: %w", err)
+	}
+
+	var participantes []participanteRating
+	for rows.Next() {
+		var p participanteRating
+		if err := rows.Scan(&p.userID, &p.equipoID, &p.puntos, &p.rating, &p.torneosCalificados); err != nil {
+			rows.Close()
+			return fmt.Errorf("error al leer el rating de un participante: %w", err)
+		}
+		participantes = append(participantes, p)
+	}
+
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("error al procesar los ratings de los participantes: %w", err)
+	}
+
+	avance, err := avanceEliminatoria(tx, torneoID)
+	if err != nil {
+		return err
+	}
+
+	// resultado es lo que se compara entre competidores: la ronda alcanzada en la llave
+	// o los puntos del torneo
+	resultado := func(competidor string, puntos int) int {
+		if avance != nil {
+			return avance[competidor]
+		}
+		return puntos
+	}
+
+	// Para cada participante: desempeño relativo, posición y cantidad de competidores
+	desempeno := make([]float64, len(participantes))
+	posiciones := make([]int, len(participantes))
+	var competidores int
+
+	if modalidad == "Versus" {
+		indiceEquipo := map[string]int{}
+		var ratingsEquipos []float64
+		var puntosEquipos, integrantes []int
+
+		for _, p := range participantes {
+			if p.equipoID == nil {
+				continue
+			}
+			i, ok := indiceEquipo[*p.equipoID]
+			if !ok {
+				i = len(ratingsEquipos)
+				indiceEquipo[*p.equipoID] = i
+				ratingsEquipos = append(ratingsEquipos, 0)
+				puntosEquipos = append(puntosEquipos, 0)
+				integrantes = append(integrantes, 0)
+			}
+			ratingsEquipos[i] += float64(p.rating)
+			puntosEquipos[i] += p.puntos
+			integrantes[i]++
+		}
+
+		for equipoID, i := range indiceEquipo {
+			puntosEquipos[i] = resultado(equipoID, puntosEquipos[i])
+		}
+
+		competidores = len(ratingsEquipos)
+		if competidores < 2 {
+			return nil
+		}
+
+		for i := range ratingsEquipos {
+			ratingsEquipos[i] /= float64(integrantes[i])
+		}
+
+		desempenoEquipos := utils.DesempenoRelativo(ratingsEquipos, puntosEquipos)
+		posicionesEquipos := utils.Posiciones(puntosEquipos)
+
+		for i, p := range participantes {
+			if p.equipoID == nil {
+				continue
+			}
+			desempeno[i] = desempenoEquipos[indiceEquipo[*p.equipoID]]
+			posiciones[i] = posicionesEquipos[indiceEquipo[*p.equipoID]]
+		}
+	} else {
+		competidores = len(participantes)
+		if competidores < 2 {
+			return nil
+		}
+
+		ratings := make([]float64, len(participantes))
+		puntos := make([]int, len(participantes))
+		for i, p := range participantes {
+			ratings[i] = float64(p.rating)
+			puntos[i] = resultado(p.userID, p.puntos)
+		}
+
+		desempeno = utils.DesempenoRelativo(ratings, puntos)
+		posiciones = utils.Posiciones(puntos)
+	}
+
+	for i, p := range participantes {
+		// Un jugador de Versus sin equipo no compitió contra nadie
+		if posiciones[i] == 0 {
+			continue
+		}
+
+		variacion := int(math.Round(utils.FactorK(p.torneosCalificados) * desempeno[i]))
+
+		// La variación se aplica sobre el valor guardado por si otro torneo del
+		// jugador finalizó al mismo tiempo
+		var ratingNuevo int
+		err := tx.QueryRow(`
+			INSERT INTO user_rating (user_id, rating, torneos_calificados)
+			VALUES ($1, $2::int + $3::int, 1)
+			ON CONFLICT (user_id) DO UPDATE
+			SET rating = user_rating.rating + $3::int,
+				torneos_calificados = user_rating.torneos_calificados + 1,
+				updated_at = NOW()
+			RETURNING rating`, p.userID, utils.RatingInicial, variacion).Scan(&ratingNuevo)
+		if err != nil {
+			return fmt.Errorf("error al actualizar el rating del jugador %s: %w", p.userID, err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO user_rating_historial
+				(user_id, id_torneo, rating_anterior, rating_nuevo, posicion, participantes)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			p.userID, torneoID, ratingNuevo-variacion, ratingNuevo, posiciones[i], competidores)
+		if err != nil {
+			return fmt.Errorf("error al guardar el historial de rating del jugador %s: %w", p.userID, err)
+		}
+	}
+
+	return nil
+}
+
+// GetRankingRating obtiene la clasificación de jugadores por rating de habilidad.
+// Solo incluye a quienes completaron al menos un torneo
+func (r *UserRepository) GetRankingRating(limit, offset int) ([]models.RatingJugador, error) {
+	query := `
+		SELECT RANK() OVER (ORDER BY ur.rating DESC)::int, ur.user_id,
+			COALESCE(ub.nombre, ''), COALESCE(ub.apellido, ''),
+			ur.rating, ur.torneos_calificados, ur.updated_at
+		FROM user_rating ur
+		LEFT JOIN user_basic_info ub ON ub.user_id = ur.user_id
+		ORDER BY ur.rating DESC, ur.torneos_calificados DESC
+		LIMIT $1 OFFSET $2`
+
+	rows, err := r.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el ranking por rating: %w", err)
+	}
+	defer rows.Close()
+
+	ranking := []models.RatingJugador{}
+	for rows.Next() {
+		var j models.RatingJugador
+		err := rows.Scan(&j.Posicion, &j.UserID, &j.Nombre, &j.Apellido,
+			&j.Rating, &j.TorneosCalificados, &j.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer el ranking por rating: %w", err)
+		}
+		j.Provisional = utils.EsRatingProvisional(j.TorneosCalificados)
+		ranking = append(ranking, j)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al procesar el ranking por rating: %w", err)
+	}
+
+	return ranking, nil
+}
+
+// GetRatingUsuario obtiene el rating de un jugador y su historial más reciente. Un
+// jugador sin torneos completados tiene el rating inicial
+func (r *UserRepository) GetRatingUsuario(userID string, limiteHistorial int) (*models.RatingJugador, []models.HistorialRating, error) {
+	rating := &models.RatingJugador{UserID: userID, Rating: utils.RatingInicial}
+
+	err := r.db.QueryRow(`
+		SELECT rating, torneos_calificados, updated_at
+		FROM user_rating
+		WHERE user_id = $1`, userID).Scan(&rating.Rating, &rating.TorneosCalificados, &rating.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("error al obtener el rating del usuario: %w", err)
+	}
+	rating.Provisional = utils.EsRatingProvisional(rating.TorneosCalificados)
+
+	rows, err := r.db.Query(`
+		SELECT h.id, h.id_torneo, t.nombre, h.rating_anterior, h.rating_nuevo,
+			h.posicion, h.participantes, h.created_at
+		FROM user_rating_historial h
+		LEFT JOIN torneos t ON t.id = h.id_torneo
+		WHERE h.user_id = $1
+		ORDER BY h.created_at DESC
+		LIMIT $2`, userID, limiteHistorial)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error al obtener el historial de rating: %w", err)
+	}
+	defer rows.Close()
+
+	historial := []models.HistorialRating{}
+	for rows.Next() {
+		var h models.HistorialRating
+		err := rows.Scan(&h.ID, &h.IDTorneo, &h.NombreTorneo, &h.RatingAnterior,
+			&h.RatingNuevo, &h.Posicion, &h.Participantes, &h.CreatedAt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error al leer el historial de rating: %w", err)
+		}
+		historial = append(historial, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error al procesar el historial de rating: %w", err)
+	}
+
+	return rating, historial, nil
+}
@@ -288,7 +288,8 @@ func (r *UserRepository) CreateUserProfile(profile *models.UserProfile) error {
 			// Actualizar perfil existente
 			query = `
 				UPDATE user_profile
-				SET slogan = $1, cabello = $2, vestimenta = $3, barba = $4, detalle_facial = $5, detalle_adicional = $6
+				SET slogan = $1, cabello = $2, vestimenta = $3, barba = $4, detalle_facial = $5, detalle_adicional = $6,
+					avatar_hash = NULL
 				WHERE user_id = $7`
 
 			_, err = tx.Exec(query, profile.Slogan, profile.Cabello, profile.Vestimenta, profile.Barba, profile.DetalleFacial, profile.DetalleAdicional, profile.UserID)
@@ -307,20 +308,29 @@ func (r *UserRepository) CreateUserProfile(profile *models.UserProfile) error {
 
 func (r *UserRepository) EditUserProfile(profile *models.EditProfile) error {
 	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
-		// Verificar si ya existe un perfil para este usuario
+		// Obtener el perfil actual, bloqueado hasta terminar la edición
 		query := `
-			SELECT EXISTS (
-				SELECT 1 FROM user_profile WHERE user_id = $1
-			)`
-
-		var exists bool
-		err := tx.QueryRow(query, profile.UserID).Scan(&exists)
+			SELECT user_id, COALESCE(cabello, ''), COALESCE(vestimenta, ''), COALESCE(barba, ''),
+				COALESCE(detalle_facial, ''), COALESCE(detalle_adicional, '')
+			FROM user_profile
+			WHERE user_id = $1
+			FOR UPDATE`
+
+		var actual models.UserProfile
+		err := tx.QueryRow(query, profile.UserID).Scan(
+			&actual.UserID, &actual.Cabello, &actual.Vestimenta, &actual.Barba,
+			&actual.DetalleFacial, &actual.DetalleAdicional,
+		)
+		if err == sql.ErrNoRows {
+			return ErrPerfilNoEncontrado
+		}
 		if err != nil {
 			return err
 		}
 
-		if !exists {
-			return errors.New("el perfil no existe")
+		// Solo se pueden usar artículos de avatar desbloqueados
+		if err := validarItemsAvatar(tx, &actual, profile); err != nil {
+			return err
 		}
 
 		// Construir la consulta dinámicamente basada en los campos proporcionados
@@ -364,6 +374,12 @@ func (r *UserRepository) EditUserProfile(profile *models.EditProfile) error {
 			paramCount++
 		}
 
+		// Los renders del avatar anterior dejan de servir
+		if profile.Cabello != nil || profile.Vestimenta != nil || profile.Barba != nil ||
+			profile.DetalleFacial != nil || profile.DetalleAdicional != nil {
+			query += "avatar_hash = NULL, "
+		}
+
 		// Eliminar la última coma y espacio
 		query = query[:len(query)-2]
 
@@ -423,7 +439,6 @@ func (r *UserRepository) UpdateUserStats(stats *models.UserStats) error {
 			_, err = tx.Exec(query, stats.Puntos, stats.Acciones, stats.TorneosParticipados,
 				stats.CantidadAmigos, stats.EsDuenoTorneo, stats.TorneosGanados,
 				stats.PendingMedalla, stats.PendingAmigo, stats.UserID)
-			return err
 		} else {
 			// Crear nuevas estadísticas
 			query = `
@@ -434,8 +449,14 @@ func (r *UserRepository) UpdateUserStats(stats *models.UserStats) error {
 			_, err = tx.Exec(query, stats.UserID, stats.Puntos, stats.Acciones, stats.TorneosParticipados,
 				stats.CantidadAmigos, stats.EsDuenoTorneo, stats.TorneosGanados,
 				stats.PendingMedalla, stats.PendingAmigo)
+		}
+		if err != nil {
 			return err
 		}
+
+		// Los nuevos puntos o victorias pueden desbloquear artículos de avatar
+		_, err = desbloquearItemsAvatar(tx, stats.UserID)
+		return err
 	})
 }
 
@@ -552,12 +573,17 @@ func (r *UserRepository) GetRankingTorneo(torneoID string) ([]models.UserRanking
 			up.detalle_facial,
 			up.detalle_adicional,
 			ub.nombre,
-			ub.apellido
+			ub.apellido,
+			te.id_equipo,
+			eq.nombre,
+			eq.color
 		FROM torneo_estadisticas te
 		JOIN user_stats us ON te.id_jugador = us.user_id
 		LEFT JOIN user_profile up ON te.id_jugador = up.user_id
 		LEFT JOIN user_basic_info ub ON te.id_jugador = ub.user_id
+		LEFT JOIN torneo_equipos eq ON te.id_equipo = eq.id
 		WHERE te.id_torneo = $1
+		AND te.habilitado = true
 		AND ub.nombre IS NOT NULL
 		AND ub.nombre <> ''
 		AND ub.apellido IS NOT NULL
@@ -599,6 +625,9 @@ func (r *UserRepository) GetRankingTorneo(torneoID string) ([]models.UserRanking
 			&r.DetalleAdicional,
 			&r.Nombre,
 			&r.Apellido,
+			&r.IDEquipo,
+			&r.NombreEquipo,
+			&r.ColorEquipo,
 		)
 		if err != nil {
 			return nil, err
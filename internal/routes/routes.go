@@ -14,6 +14,9 @@ func SetupRoutes(
 	userActionsHandler *handlers.UserActionsHandler,
 	userFriendsHandler *handlers.UserFriendsHandler,
 	medallasHandler *handlers.MedallasHandler,
+	temporadasHandler *handlers.TemporadasHandler,
+	marcadorHandler *handlers.MarcadorHandler,
+	avatarHandler *handlers.AvatarHandler,
 ) *mux.Router {
 	r := mux.NewRouter()
 
@@ -44,21 +47,44 @@ func SetupRoutes(
 	r.HandleFunc("/api/users/{id}/basic-info", userHandler.CreateOrUpdateUserBasicInfo).Methods("PUT")
 	r.HandleFunc("/api/users/{id}/profile", userHandler.GetUserProfile).Methods("GET")
 	r.HandleFunc("/api/users/{id}/profile/edit", userHandler.UpdateUserProfileEdit).Methods("PUT")
+	r.HandleFunc("/api/users/{id}/avatar/items", userHandler.GetInventarioAvatar).Methods("GET")
+	r.HandleFunc("/api/users/{id}/avatar.{formato:svg|png}", avatarHandler.GetAvatar).Methods("GET")
 	r.HandleFunc("/api/users/{id}/stats", userHandler.GetUserStats).Methods("GET")
 	r.HandleFunc("/api/users/{id}/stats", userHandler.UpdateUserStats).Methods("PUT")
+	r.HandleFunc("/api/users/{id}/rating", userHandler.GetRatingUsuario).Methods("GET")
+	r.HandleFunc("/api/users/{id}/notificaciones", userHandler.GetNotificaciones).Methods("GET")
+	r.HandleFunc("/api/users/{id}/notificaciones/leidas", userHandler.MarcarNotificacionesLeidas).Methods("PUT")
+
+	// Rutas del catálogo de avatar
+	r.HandleFunc("/api/avatar/items", userHandler.GetAvatarItems).Methods("GET")
+	r.HandleFunc("/api/avatar/items", userHandler.CreateAvatarItem).Methods("POST")
+	r.HandleFunc("/api/avatar/items/{id}/capa", avatarHandler.SubirCapaAvatarItem).Methods("PUT")
 
 	// Rutas de ranking
 	r.HandleFunc("/api/ranking", userHandler.GetRanking).Methods("GET")
+	r.HandleFunc("/api/ranking/rating", userHandler.GetRankingRating).Methods("GET")
 	r.HandleFunc("/api/ranking/torneo/{torneo_id}", userHandler.GetRankingTorneo).Methods("GET")
+	r.HandleFunc("/api/ranking/torneo/{torneo_id}/equipos", torneoHandler.GetRankingEquipos).Methods("GET")
+	r.HandleFunc("/api/torneos/{id}/marcador/stream", marcadorHandler.StreamMarcador).Methods("GET")
 
 	// Rutas de torneos
 	r.HandleFunc("/api/torneos", torneoHandler.CreateTorneo).Methods("POST")
 	r.HandleFunc("/api/torneos", torneoHandler.ListTorneos).Methods("GET")
+	r.HandleFunc("/api/torneos/cercanos", torneoHandler.ListTorneosCercanos).Methods("GET")
 	r.HandleFunc("/api/torneos/{id}", torneoHandler.GetTorneo).Methods("GET")
 	r.HandleFunc("/api/torneos/code/{code_id}", torneoHandler.GetTorneoByCodeID).Methods("GET")
-	r.HandleFunc("/api/torneos/admin/{id}", torneoHandler.GetTorneoAdmin).Methods("GET")
-	r.HandleFunc("/api/torneos/admin/{id}/terminar", torneoHandler.TerminarTorneo).Methods("POST")
-	r.HandleFunc("/api/torneos/admin/{id}/borrar", torneoHandler.BorrarTorneo).Methods("POST")
+	// Rutas anteriores de administración, con el ID del dueño en lugar del torneo
+	r.HandleFunc("/api/torneos/admin/{id}", torneoHandler.GetTorneoAdminCreador).Methods("GET")
+	r.HandleFunc("/api/torneos/admin/{id}/terminar", torneoHandler.TerminarTorneoCreador).Methods("POST")
+	r.HandleFunc("/api/torneos/admin/{id}/borrar", torneoHandler.BorrarTorneoCreador).Methods("POST")
+	r.HandleFunc("/api/torneos/{id}/admin", torneoHandler.GetTorneoAdmin).Methods("GET")
+	r.HandleFunc("/api/torneos/{id}/terminar", torneoHandler.TerminarTorneo).Methods("POST")
+	r.HandleFunc("/api/torneos/{id}/borrar", torneoHandler.BorrarTorneo).Methods("POST")
+	r.HandleFunc("/api/torneos/{id}/cancelar", torneoHandler.CancelarTorneo).Methods("POST")
+	r.HandleFunc("/api/torneos/{id}/staff", torneoHandler.GetStaff).Methods("GET")
+	r.HandleFunc("/api/torneos/{id}/staff", torneoHandler.AgregarCoorganizador).Methods("POST")
+	r.HandleFunc("/api/torneos/{id}/staff/{user_id}", torneoHandler.QuitarCoorganizador).Methods("DELETE")
+	r.HandleFunc("/api/torneos/{id}/transferir", torneoHandler.TransferirPropiedad).Methods("POST")
 	r.HandleFunc("/api/torneos/inscribir/{code_id}", torneoHandler.InscribirUsuario).Methods("POST")
 	r.HandleFunc("/api/torneos/{torneo_id}/usuario/{user_id}", torneoHandler.SalirTorneo).Methods("DELETE")
 	r.HandleFunc("/api/torneos/{id}", torneoHandler.UpdateTorneo).Methods("PUT")
@@ -66,6 +92,38 @@ func SetupRoutes(
 	r.HandleFunc("/api/torneos/{id}/estadisticas", torneoHandler.GetTorneoStats).Methods("GET")
 	r.HandleFunc("/api/users/{user_id}/torneos", torneoHandler.GetTorneosUsuario).Methods("GET")
 	r.HandleFunc("/api/torneos/{torneo_id}/usuario/{user_id}/equipo", torneoHandler.GetEquipoUsuarioTorneo).Methods("GET")
+	r.HandleFunc("/api/torneos/{id}/equipos", torneoHandler.GetEquiposTorneo).Methods("GET")
+	r.HandleFunc("/api/torneos/{id}/equipos", torneoHandler.CreateEquipo).Methods("POST")
+	r.HandleFunc("/api/torneos/{id}/equipos/{equipo_id}", torneoHandler.UpdateEquipo).Methods("PUT")
+	r.HandleFunc("/api/torneos/{id}/equipos/{equipo_id}", torneoHandler.DeleteEquipo).Methods("DELETE")
+	r.HandleFunc("/api/torneos/{id}/configuracion-equipos", torneoHandler.UpdateConfiguracionEquipos).Methods("PUT")
+	r.HandleFunc("/api/torneos/{torneo_id}/usuario/{user_id}/equipo", torneoHandler.SolicitarCambioEquipo).Methods("PUT")
+	r.HandleFunc("/api/torneos/{id}/cambios-equipo", torneoHandler.GetCambiosEquipo).Methods("GET")
+	r.HandleFunc("/api/torneos/{id}/cambios-equipo/{cambio_id}", torneoHandler.ResolverCambioEquipo).Methods("PUT")
+	r.HandleFunc("/api/torneos/{torneo_id}/usuario/{user_id}/estado", torneoHandler.GetEstadoParticipante).Methods("GET")
+	r.HandleFunc("/api/torneos/{torneo_id}/participantes/{user_id}/habilitado", torneoHandler.SetHabilitadoParticipante).Methods("PUT")
+	r.HandleFunc("/api/torneos/{torneo_id}/participantes/{user_id}/expulsar", torneoHandler.ExpulsarParticipante).Methods("POST")
+	r.HandleFunc("/api/torneos/{torneo_id}/participantes/{user_id}/baneo", torneoHandler.LevantarBaneo).Methods("DELETE")
+	r.HandleFunc("/api/torneos/{id}/acciones", torneoHandler.GetAccionesTorneo).Methods("GET")
+	r.HandleFunc("/api/torneos/{id}/acciones/{action_id}/anular", torneoHandler.AnularAccion).Methods("POST")
+	r.HandleFunc("/api/torneos/{id}/lista-espera", torneoHandler.GetListaEspera).Methods("GET")
+	r.HandleFunc("/api/torneos/{id}/llave", torneoHandler.GetLlave).Methods("GET")
+	r.HandleFunc("/api/torneos/{id}/reglas", torneoHandler.GetReglasPuntuacion).Methods("GET")
+	r.HandleFunc("/api/torneos/{id}/reglas", torneoHandler.UpdateReglasPuntuacion).Methods("PUT")
+	r.HandleFunc("/api/torneos/{id}/invitaciones", torneoHandler.InvitarAmigo).Methods("POST")
+	r.HandleFunc("/api/torneos/{id}/invitaciones/{invitacion_id}", torneoHandler.CancelarInvitacion).Methods("DELETE")
+	r.HandleFunc("/api/users/{user_id}/invitaciones-torneo", torneoHandler.GetInvitacionesUsuario).Methods("GET")
+	r.HandleFunc("/api/users/{user_id}/invitaciones-torneo/{invitacion_id}", torneoHandler.ResponderInvitacion).Methods("PUT")
+
+	// Rutas de temporadas y plantillas de torneos recurrentes
+	r.HandleFunc("/api/temporadas", temporadasHandler.CreateTemporada).Methods("POST")
+	r.HandleFunc("/api/temporadas/{id}", temporadasHandler.GetTemporada).Methods("GET")
+	r.HandleFunc("/api/temporadas/{id}/torneos", temporadasHandler.GetTorneosTemporada).Methods("GET")
+	r.HandleFunc("/api/temporadas/{id}/ranking", temporadasHandler.GetRankingTemporada).Methods("GET")
+	r.HandleFunc("/api/plantillas-torneo", temporadasHandler.CreatePlantilla).Methods("POST")
+	r.HandleFunc("/api/plantillas-torneo/{id}/activa", temporadasHandler.SetPlantillaActiva).Methods("PUT")
+	r.HandleFunc("/api/plantillas-torneo/{id}", temporadasHandler.DeletePlantilla).Methods("DELETE")
+	r.HandleFunc("/api/users/{user_id}/plantillas-torneo", temporadasHandler.GetPlantillasUsuario).Methods("GET")
 
 	// Rutas de acciones de usuario
 	r.HandleFunc("/api/users/{user_id}/actions", userActionsHandler.CreateAction).Methods("POST")
@@ -82,7 +140,15 @@ func SetupRoutes(
 	// Rutas de medallas
 	r.HandleFunc("/api/medallas", medallasHandler.CreateMedalla).Methods("POST")
 	r.HandleFunc("/api/medallas", medallasHandler.GetMedallas).Methods("GET")
+	r.HandleFunc("/api/medallas/simular", medallasHandler.SimularMedalla).Methods("POST")
+	r.HandleFunc("/api/medallas/{id}", medallasHandler.UpdateMedalla).Methods("PUT")
+	r.HandleFunc("/api/medallas/{id}", medallasHandler.RetirarMedalla).Methods("DELETE")
+	r.HandleFunc("/api/medallas/{id}/imagen", medallasHandler.SubirImagenMedalla).Methods("PUT")
+	r.HandleFunc("/api/medallas/{id}/backfill", medallasHandler.GetBackfillMedalla).Methods("GET")
+	r.HandleFunc("/api/medallas/{id}/backfill", medallasHandler.IniciarBackfillMedalla).Methods("POST")
 	r.HandleFunc("/api/users/{user_id}/medallas", medallasHandler.GetMedallasUsuario).Methods("GET")
+	r.HandleFunc("/api/users/{user_id}/medallas/progreso", medallasHandler.GetProgresoMedallas).Methods("GET")
+	r.HandleFunc("/api/users/{user_id}/medallas/revocadas", medallasHandler.GetMedallasRevocadas).Methods("GET")
 	r.HandleFunc("/api/users/{user_id}/medallas/{medalla_id}", medallasHandler.AsignarMedalla).Methods("POST")
 	r.HandleFunc("/api/users/{user_id}/medallas/slogans", medallasHandler.GetSlogansMedallasGanadas).Methods("GET")
 	r.HandleFunc("/api/users/{user_id}/medallas/reset-pending", medallasHandler.ResetPendingMedallas).Methods("GET")
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"slices"
+)
+
+// puntosPrimerNivel son los puntos para pasar del nivel 1 al 2; cada nivel siguiente
+// pide esa misma cantidad más que el anterior
+const puntosPrimerNivel = 100
+
+// PuntosParaNivel devuelve los puntos que pide llegar a un nivel: 100 * n * (n - 1) / 2,
+// es decir 100 para el 2, 300 para el 3, 600 para el 4...
+func PuntosParaNivel(nivel int) int {
+	return puntosPrimerNivel * nivel * (nivel - 1) / 2
+}
+
+// NivelPorPuntos devuelve el nivel de un usuario según sus puntos
+func NivelPorPuntos(puntos int) int {
+	nivel := 1
+	for puntos >= PuntosParaNivel(nivel+1) {
+		nivel++
+	}
+	return nivel
+}
+
+// ItemAvatarDesbloqueado indica si los logros del usuario alcanzan para el artículo
+func ItemAvatarDesbloqueado(item models.AvatarItem, logros models.LogrosAvatar) bool {
+	umbral := 0
+	if item.Umbral != nil {
+		umbral = *item.Umbral
+	}
+
+	switch item.TipoDesbloqueo {
+	case models.DesbloqueoLibre:
+		return true
+	case models.DesbloqueoMedalla:
+		return item.IDMedalla != nil && slices.Contains(logros.Medallas, *item.IDMedalla)
+	case models.DesbloqueoPuntos:
+		return logros.Puntos >= umbral
+	case models.DesbloqueoNivel:
+		return NivelPorPuntos(logros.Puntos) >= umbral
+	case models.DesbloqueoVictoriaTorneo:
+		return logros.TorneosGanados >= umbral
+	}
+	return false
+}
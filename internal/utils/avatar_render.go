@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+)
+
+// HashAvatar identifica un avatar por las capas que lo forman, en orden. Dos usuarios
+// con las mismas capas comparten el hash y los renders en caché
+func HashAvatar(capas []string) string {
+	suma := sha256.Sum256([]byte(strings.Join(capas, "\n")))
+	return hex.EncodeToString(suma[:16])
+}
+
+// EscalarImagen devuelve la imagen escalada a un cuadrado de lado x lado con
+// interpolación bilineal
+func EscalarImagen(src image.Image, lado int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, lado, lado))
+	b := src.Bounds()
+	if b.Empty() {
+		return dst
+	}
+
+	escalaX := float64(b.Dx()) / float64(lado)
+	escalaY := float64(b.Dy()) / float64(lado)
+	for y := 0; y < lado; y++ {
+		// Centro del píxel destino en coordenadas de la imagen original
+		sy := (float64(y)+0.5)*escalaY - 0.5
+		y0, fy := partirCoordenada(sy, b.Dy())
+		for x := 0; x < lado; x++ {
+			sx := (float64(x)+0.5)*escalaX - 0.5
+			x0, fx := partirCoordenada(sx, b.Dx())
+
+			x1, y1 := min(x0+1, b.Dx()-1), min(y0+1, b.Dy()-1)
+			c00 := color.RGBA64Model.Convert(src.At(b.Min.X+x0, b.Min.Y+y0)).(color.RGBA64)
+			c10 := color.RGBA64Model.Convert(src.At(b.Min.X+x1, b.Min.Y+y0)).(color.RGBA64)
+			c01 := color.RGBA64Model.Convert(src.At(b.Min.X+x0, b.Min.Y+y1)).(color.RGBA64)
+			c11 := color.RGBA64Model.Convert(src.At(b.Min.X+x1, b.Min.Y+y1)).(color.RGBA64)
+
+			// Los canales están premultiplicados, así que se interpolan por separado
+			mezclar := func(v00, v10, v01, v11 uint16) uint8 {
+				arriba := float64(v00)*(1-fx) + float64(v10)*fx
+				abajo := float64(v01)*(1-fx) + float64(v11)*fx
+				return uint8((arriba*(1-fy)+abajo*fy)/257 + 0.5)
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: mezclar(c00.R, c10.R, c01.R, c11.R),
+				G: mezclar(c00.G, c10.G, c01.G, c11.G),
+				B: mezclar(c00.B, c10.B, c01.B, c11.B),
+				A: mezclar(c00.A, c10.A, c01.A, c11.A),
+			})
+		}
+	}
+
+	return dst
+}
+
+// partirCoordenada separa una coordenada en el píxel de la izquierda y la fracción
+// hacia el siguiente, sin salirse de la imagen
+func partirCoordenada(s float64, tamano int) (int, float64) {
+	if s <= 0 {
+		return 0, 0
+	}
+	if s >= float64(tamano-1) {
+		return tamano - 1, 0
+	}
+	entero := int(s)
+	return entero, s - float64(entero)
+}
+
+// ComponerAvatar dibuja las capas una sobre otra, de la primera a la última, en un
+// cuadrado transparente de lado x lado
+func ComponerAvatar(capas []image.Image, lado int) *image.RGBA {
+	lienzo := image.NewRGBA(image.Rect(0, 0, lado, lado))
+	for _, capa := range capas {
+		draw.Draw(lienzo, lienzo.Bounds(), EscalarImagen(capa, lado), image.Point{}, draw.Over)
+	}
+	return lienzo
+}
+
+// AvatarSVG arma un SVG de lado x lado con las capas PNG incrustadas, de la primera a
+// la última. El navegador escala las capas, así que se incrustan tal como están
+func AvatarSVG(capas [][]byte, lado int) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, lado, lado, lado, lado)
+	for _, capa := range capas {
+		fmt.Fprintf(&b, `<image x="0" y="0" width="%d" height="%d" href="data:image/png;base64,%s"/>`,
+			lado, lado, base64.StdEncoding.EncodeToString(capa))
+	}
+	b.WriteString(`</svg>`)
+	return b.Bytes()
+}
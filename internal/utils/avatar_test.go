@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+)
+
+func TestNivelPorPuntos(t *testing.T) {
+	casos := []struct {
+		puntos   int
+		esperado int
+	}{
+		{0, 1},
+		{99, 1},
+		{100, 2},
+		{299, 2},
+		{300, 3},
+		{600, 4},
+		{4500, 10},
+		{-20, 1},
+	}
+
+	for _, c := range casos {
+		if got := NivelPorPuntos(c.puntos); got != c.esperado {
+			t.Errorf("NivelPorPuntos(%d): se esperaba %d, se obtuvo %d", c.puntos, c.esperado, got)
+		}
+	}
+
+	// Los puntos de cada nivel son justo los que lo alcanzan
+	for nivel := 1; nivel <= 20; nivel++ {
+		puntos := PuntosParaNivel(nivel)
+		if NivelPorPuntos(puntos) != nivel || (nivel > 1 && NivelPorPuntos(puntos-1) != nivel-1) {
+			t.Errorf("PuntosParaNivel(%d) = %d no coincide con NivelPorPuntos", nivel, puntos)
+		}
+	}
+}
+
+func TestItemAvatarDesbloqueado(t *testing.T) {
+	umbral := func(n int) *int { return &n }
+	medalla := "medalla-1"
+	logros := models.LogrosAvatar{Puntos: 350, TorneosGanados: 1, Medallas: []string{medalla}}
+
+	casos := []struct {
+		nombre   string
+		item     models.AvatarItem
+		esperado bool
+	}{
+		{"libre", models.AvatarItem{TipoDesbloqueo: models.DesbloqueoLibre}, true},
+		{"medalla ganada", models.AvatarItem{TipoDesbloqueo: models.DesbloqueoMedalla, IDMedalla: &medalla}, true},
+		{"medalla sin ganar", models.AvatarItem{TipoDesbloqueo: models.DesbloqueoMedalla, IDMedalla: new(string)}, false},
+		{"puntos alcanzados", models.AvatarItem{TipoDesbloqueo: models.DesbloqueoPuntos, Umbral: umbral(350)}, true},
+		{"puntos insuficientes", models.AvatarItem{TipoDesbloqueo: models.DesbloqueoPuntos, Umbral: umbral(351)}, false},
+		{"nivel alcanzado", models.AvatarItem{TipoDesbloqueo: models.DesbloqueoNivel, Umbral: umbral(3)}, true},
+		{"nivel insuficiente", models.AvatarItem{TipoDesbloqueo: models.DesbloqueoNivel, Umbral: umbral(4)}, false},
+		{"torneo ganado", models.AvatarItem{TipoDesbloqueo: models.DesbloqueoVictoriaTorneo, Umbral: umbral(1)}, true},
+		{"torneos insuficientes", models.AvatarItem{TipoDesbloqueo: models.DesbloqueoVictoriaTorneo, Umbral: umbral(2)}, false},
+		{"tipo desconocido", models.AvatarItem{TipoDesbloqueo: "regalo"}, false},
+	}
+
+	for _, c := range casos {
+		if got := ItemAvatarDesbloqueado(c.item, logros); got != c.esperado {
+			t.Errorf("%s: se esperaba %v, se obtuvo %v", c.nombre, c.esperado, got)
+		}
+	}
+}
+
+func TestHashAvatar(t *testing.T) {
+	capas := []string{"https://zona/avatar/a.png", "https://zona/avatar/b.png"}
+
+	if HashAvatar(capas) != HashAvatar([]string{"https://zona/avatar/a.png", "https://zona/avatar/b.png"}) {
+		t.Error("las mismas capas deben dar el mismo hash")
+	}
+	if HashAvatar(capas) == HashAvatar([]string{capas[1], capas[0]}) {
+		t.Error("el orden de las capas debe cambiar el hash")
+	}
+	if HashAvatar(capas) == HashAvatar([]string{"https://zona/avatar/a.png"}) {
+		t.Error("quitar una capa debe cambiar el hash")
+	}
+	if len(HashAvatar(nil)) != 32 {
+		t.Errorf("se esperaba un hash de 32 caracteres, se obtuvo %q", HashAvatar(nil))
+	}
+}
+
+// imagenLisa devuelve un cuadrado de un solo color
+func imagenLisa(lado int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, lado, lado))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: c}, image.Point{}, draw.Src)
+	return img
+}
+
+func TestEscalarImagen(t *testing.T) {
+	rojo := color.RGBA{R: 200, A: 255}
+
+	for _, lado := range []int{1, 16, 64, 200} {
+		img := EscalarImagen(imagenLisa(64, rojo), lado)
+		if img.Bounds().Dx() != lado || img.Bounds().Dy() != lado {
+			t.Errorf("lado %d: se obtuvo %v", lado, img.Bounds())
+			continue
+		}
+		for _, p := range []image.Point{{0, 0}, {lado / 2, lado / 2}, {lado - 1, lado - 1}} {
+			if got := img.RGBAAt(p.X, p.Y); got != rojo {
+				t.Errorf("lado %d, píxel %v: se esperaba %v, se obtuvo %v", lado, p, rojo, got)
+			}
+		}
+	}
+
+	// La mitad izquierda negra y la derecha blanca se conservan al reducir
+	mitades := imagenLisa(4, color.RGBA{A: 255})
+	draw.Draw(mitades, image.Rect(2, 0, 4, 4), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	img := EscalarImagen(mitades, 2)
+	if got := img.RGBAAt(0, 0); got.R != 0 {
+		t.Errorf("se esperaba negro a la izquierda, se obtuvo %v", got)
+	}
+	if got := img.RGBAAt(1, 0); got.R != 255 {
+		t.Errorf("se esperaba blanco a la derecha, se obtuvo %v", got)
+	}
+}
+
+func TestComponerAvatar(t *testing.T) {
+	azul := color.RGBA{B: 255, A: 255}
+	verde := color.RGBA{G: 255, A: 255}
+
+	// Una capa que solo cubre la mitad de arriba
+	mitad := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(mitad, image.Rect(0, 0, 8, 4), &image.Uniform{C: verde}, image.Point{}, draw.Src)
+
+	img := ComponerAvatar([]image.Image{imagenLisa(8, azul), mitad}, 8)
+	if got := img.RGBAAt(4, 1); got != verde {
+		t.Errorf("arriba se esperaba la capa superior %v, se obtuvo %v", verde, got)
+	}
+	if got := img.RGBAAt(4, 6); got != azul {
+		t.Errorf("abajo se esperaba la capa inferior %v, se obtuvo %v", azul, got)
+	}
+
+	if got := ComponerAvatar(nil, 8).RGBAAt(0, 0); got.A != 0 {
+		t.Errorf("sin capas se esperaba un avatar transparente, se obtuvo %v", got)
+	}
+}
+
+func TestAvatarSVG(t *testing.T) {
+	svg := string(AvatarSVG([][]byte{{1, 2, 3}, {4}}, 128))
+
+	if !strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="128" height="128"`) {
+		t.Errorf("encabezado inesperado: %s", svg)
+	}
+	if n := strings.Count(svg, "data:image/png;base64,"); n != 2 {
+		t.Errorf("se esperaban 2 capas, se obtuvieron %d", n)
+	}
+	if strings.Index(svg, "AQID") > strings.Index(svg, "BA==") {
+		t.Error("las capas deben ir en orden, de abajo hacia arriba")
+	}
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"fmt"
+	"math"
+)
+
+const radioTierraMetros = 6371000.0
+
+// DistanciaMetros calcula la distancia entre dos coordenadas con la fórmula de haversine
+func DistanciaMetros(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLng := (lng2 - lng1) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*
+			math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * radioTierraMetros * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// toleranciaBorde es el margen, en grados, para considerar que un punto está sobre
+// un lado del polígono (alrededor de un centímetro)
+const toleranciaBorde = 1e-7
+
+// PuntoEnPoligono indica si la coordenada está dentro del polígono (ray casting).
+// Los puntos sobre el borde cuentan como dentro. Las distancias de un evento son
+// pequeñas, así que se tratan las coordenadas como planas
+func PuntoEnPoligono(lat, lng float64, poligono models.PoligonoGeo) bool {
+	dentro := false
+	for i, j := 0, len(poligono)-1; i < len(poligono); j, i = i, i+1 {
+		a, b := poligono[i], poligono[j]
+		if enSegmento(lat, lng, a, b) {
+			return true
+		}
+		if (a.Latitud > lat) != (b.Latitud > lat) &&
+			lng < (b.Longitud-a.Longitud)*(lat-a.Latitud)/(b.Latitud-a.Latitud)+a.Longitud {
+			dentro = !dentro
+		}
+	}
+	return dentro
+}
+
+// enSegmento indica si la coordenada está sobre el segmento entre a y b
+func enSegmento(lat, lng float64, a, b models.PuntoGeo) bool {
+	cruz := (b.Latitud-a.Latitud)*(lng-a.Longitud) - (b.Longitud-a.Longitud)*(lat-a.Latitud)
+	largo := math.Hypot(b.Latitud-a.Latitud, b.Longitud-a.Longitud)
+	if largo == 0 {
+		return math.Hypot(lat-a.Latitud, lng-a.Longitud) <= toleranciaBorde
+	}
+	if math.Abs(cruz)/largo > toleranciaBorde {
+		return false
+	}
+
+	return lat >= math.Min(a.Latitud, b.Latitud)-toleranciaBorde &&
+		lat <= math.Max(a.Latitud, b.Latitud)+toleranciaBorde &&
+		lng >= math.Min(a.Longitud, b.Longitud)-toleranciaBorde &&
+		lng <= math.Max(a.Longitud, b.Longitud)+toleranciaBorde
+}
+
+// EvaluarGeocerca indica si una acción hecha en (lat, lng) está dentro del área del
+// torneo. Si no lo está, devuelve el motivo para mostrarlo al jugador
+func EvaluarGeocerca(geocerca *models.Geocerca, lat, lng float64) (bool, string) {
+	switch geocerca.Tipo {
+	case models.GeocercaRadio:
+		if geocerca.RadioMetros == nil {
+			return true, ""
+		}
+		radio := float64(*geocerca.RadioMetros)
+
+		distancia := DistanciaMetros(lat, lng, geocerca.PuntoA.Latitud, geocerca.PuntoA.Longitud)
+		if geocerca.PuntoB != nil {
+			distancia = math.Min(distancia, DistanciaMetros(lat, lng, geocerca.PuntoB.Latitud, geocerca.PuntoB.Longitud))
+		}
+
+		if distancia > radio {
+			return false, fmt.Sprintf("La acción está a %.0f metros del área del torneo (radio permitido: %d metros)",
+				distancia-radio, *geocerca.RadioMetros)
+		}
+	case models.GeocercaPoligono:
+		if len(geocerca.Poligono) < 3 {
+			return true, ""
+		}
+
+		if !PuntoEnPoligono(lat, lng, geocerca.Poligono) {
+			return false, "La acción se hizo fuera del área delimitada por el organizador del torneo"
+		}
+	}
+
+	return true, ""
+}
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"math"
+	"testing"
+)
+
+func TestDistanciaMetros(t *testing.T) {
+	casos := []struct {
+		nombre           string
+		lat1, lng1       float64
+		lat2, lng2       float64
+		esperado, margen float64
+	}{
+		{"mismo punto", 21.0389, -89.6614, 21.0389, -89.6614, 0, 0.001},
+		{"un grado de latitud", 0, 0, 1, 0, 111195, 1},
+		{"un grado de longitud en el ecuador", 0, 0, 0, 1, 111195, 1},
+		{"un grado de longitud a 60 grados", 60, 0, 60, 1, 55597, 5},
+		{"Mérida a Cancún", 20.9674, -89.5926, 21.1619, -86.8515, 285000, 2000},
+		{"simétrica", 21.1619, -86.8515, 20.9674, -89.5926, 285000, 2000},
+	}
+
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			distancia := DistanciaMetros(c.lat1, c.lng1, c.lat2, c.lng2)
+			if math.Abs(distancia-c.esperado) > c.margen {
+				t.Errorf("se esperaba %.1f ± %.1f metros, se obtuvo %.1f", c.esperado, c.margen, distancia)
+			}
+		})
+	}
+}
+
+func TestPuntoEnPoligono(t *testing.T) {
+	cuadrado := models.PoligonoGeo{
+		{Latitud: 0, Longitud: 0},
+		{Latitud: 0, Longitud: 1},
+		{Latitud: 1, Longitud: 1},
+		{Latitud: 1, Longitud: 0},
+	}
+	// Forma de L: la esquina superior derecha queda fuera
+	ele := models.PoligonoGeo{
+		{Latitud: 0, Longitud: 0},
+		{Latitud: 0, Longitud: 2},
+		{Latitud: 1, Longitud: 2},
+		{Latitud: 1, Longitud: 1},
+		{Latitud: 2, Longitud: 1},
+		{Latitud: 2, Longitud: 0},
+	}
+
+	casos := []struct {
+		nombre   string
+		lat, lng float64
+		poligono models.PoligonoGeo
+		esperado bool
+	}{
+		{"centro del cuadrado", 0.5, 0.5, cuadrado, true},
+		{"fuera del cuadrado", 1.5, 0.5, cuadrado, false},
+		{"fuera a la izquierda", 0.5, -0.5, cuadrado, false},
+		{"sobre el lado inferior", 0, 0.5, cuadrado, true},
+		{"sobre el lado derecho", 0.5, 1, cuadrado, true},
+		{"sobre el lado superior", 1, 0.5, cuadrado, true},
+		{"sobre un vértice", 1, 1, cuadrado, true},
+		{"a la altura de un vértice por fuera", 1, 2, cuadrado, false},
+		{"brazo de la L", 1.5, 0.5, ele, true},
+		{"hueco de la L", 1.5, 1.5, ele, false},
+		{"sobre el borde interior de la L", 1.5, 1, ele, true},
+		{"polígono vacío", 0.5, 0.5, models.PoligonoGeo{}, false},
+	}
+
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if got := PuntoEnPoligono(c.lat, c.lng, c.poligono); got != c.esperado {
+				t.Errorf("se esperaba %v, se obtuvo %v", c.esperado, got)
+			}
+		})
+	}
+}
+
+func TestEvaluarGeocerca(t *testing.T) {
+	radio := 100
+	puntoA := models.PuntoGeo{Latitud: 21.0, Longitud: -89.6}
+	puntoB := models.PuntoGeo{Latitud: 21.01, Longitud: -89.6}
+	// Un metro hacia el norte equivale a unos 0.000009 grados de latitud
+	const gradosPorMetro = 1 / 111195.0
+
+	casos := []struct {
+		nombre   string
+		geocerca models.Geocerca
+		lat, lng float64
+		esperado bool
+	}{
+		{
+			nombre:   "sin geocerca",
+			geocerca: models.Geocerca{Tipo: models.GeocercaNinguna},
+			lat:      0, lng: 0,
+			esperado: true,
+		},
+		{
+			nombre:   "dentro del radio",
+			geocerca: models.Geocerca{Tipo: models.GeocercaRadio, RadioMetros: &radio, PuntoA: puntoA},
+			lat:      puntoA.Latitud + 50*gradosPorMetro, lng: puntoA.Longitud,
+			esperado: true,
+		},
+		{
+			nombre:   "fuera del radio",
+			geocerca: models.Geocerca{Tipo: models.GeocercaRadio, RadioMetros: &radio, PuntoA: puntoA},
+			lat:      puntoA.Latitud + 150*gradosPorMetro, lng: puntoA.Longitud,
+			esperado: false,
+		},
+		{
+			nombre:   "cerca de la ubicación B",
+			geocerca: models.Geocerca{Tipo: models.GeocercaRadio, RadioMetros: &radio, PuntoA: puntoA, PuntoB: &puntoB},
+			lat:      puntoB.Latitud + 50*gradosPorMetro, lng: puntoB.Longitud,
+			esperado: true,
+		},
+		{
+			nombre:   "lejos de A y de B",
+			geocerca: models.Geocerca{Tipo: models.GeocercaRadio, RadioMetros: &radio, PuntoA: puntoA, PuntoB: &puntoB},
+			lat:      puntoB.Latitud + 500*gradosPorMetro, lng: puntoB.Longitud,
+			esperado: false,
+		},
+		{
+			nombre:   "radio sin definir acepta la acción",
+			geocerca: models.Geocerca{Tipo: models.GeocercaRadio, PuntoA: puntoA},
+			lat:      0, lng: 0,
+			esperado: true,
+		},
+		{
+			nombre: "dentro del polígono",
+			geocerca: models.Geocerca{Tipo: models.GeocercaPoligono, Poligono: models.PoligonoGeo{
+				{Latitud: 0, Longitud: 0}, {Latitud: 0, Longitud: 1}, {Latitud: 1, Longitud: 0},
+			}},
+			lat: 0.2, lng: 0.2,
+			esperado: true,
+		},
+		{
+			nombre: "fuera del polígono",
+			geocerca: models.Geocerca{Tipo: models.GeocercaPoligono, Poligono: models.PoligonoGeo{
+				{Latitud: 0, Longitud: 0}, {Latitud: 0, Longitud: 1}, {Latitud: 1, Longitud: 0},
+			}},
+			lat: 0.8, lng: 0.8,
+			esperado: false,
+		},
+		{
+			nombre: "sobre la hipotenusa del polígono",
+			geocerca: models.Geocerca{Tipo: models.GeocercaPoligono, Poligono: models.PoligonoGeo{
+				{Latitud: 0, Longitud: 0}, {Latitud: 0, Longitud: 1}, {Latitud: 1, Longitud: 0},
+			}},
+			lat: 0.5, lng: 0.5,
+			esperado: true,
+		},
+		{
+			nombre: "polígono con menos de 3 vértices acepta la acción",
+			geocerca: models.Geocerca{Tipo: models.GeocercaPoligono, Poligono: models.PoligonoGeo{
+				{Latitud: 0, Longitud: 0}, {Latitud: 1, Longitud: 1},
+			}},
+			lat: 5, lng: 5,
+			esperado: true,
+		},
+	}
+
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			dentro, motivo := EvaluarGeocerca(&c.geocerca, c.lat, c.lng)
+			if dentro != c.esperado {
+				t.Fatalf("se esperaba %v, se obtuvo %v (%s)", c.esperado, dentro, motivo)
+			}
+			if dentro && motivo != "" {
+				t.Errorf("no se esperaba motivo para una acción dentro del área, se obtuvo %q", motivo)
+			}
+			if !dentro && motivo == "" {
+				t.Errorf("se esperaba el motivo del rechazo")
+			}
+		})
+	}
+}
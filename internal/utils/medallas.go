@@ -0,0 +1,88 @@
+package utils
+
+import "backend_proyecto_verde/internal/models"
+
+// CompararCriterio indica si el valor cumple la comparación con el umbral
+func CompararCriterio(valor int, operador string, umbral int) bool {
+	switch operador {
+	case ">=":
+		return valor >= umbral
+	case ">":
+		return valor > umbral
+	case "<=":
+		return valor <= umbral
+	case "<":
+		return valor < umbral
+	case "=":
+		return valor == umbral
+	}
+	return false
+}
+
+// CumpleCriterios evalúa los criterios de una medalla con los valores de las métricas
+// del usuario: basta con un grupo que cumpla todos sus criterios. Una medalla sin
+// criterios no se puede ganar automáticamente
+func CumpleCriterios(criterios []models.CriterioMedalla, valores map[string]int) bool {
+	grupos := make(map[int]bool)
+	for _, c := range criterios {
+		cumple := CompararCriterio(valores[c.Metrica], c.Operador, c.Umbral)
+		if anterior, ok := grupos[c.Grupo]; ok {
+			cumple = anterior && cumple
+		}
+		grupos[c.Grupo] = cumple
+	}
+
+	for _, cumple := range grupos {
+		if cumple {
+			return true
+		}
+	}
+	return false
+}
+
+// PorcentajeCriterio indica de 0 a 100 qué tan cerca está el valor de cumplir la
+// comparación. Solo los mínimos (>= y >) avanzan poco a poco; el resto se cumple o no
+func PorcentajeCriterio(valor int, operador string, umbral int) int {
+	if CompararCriterio(valor, operador, umbral) {
+		return 100
+	}
+
+	objetivo := umbral
+	if operador == ">" {
+		objetivo = umbral + 1
+	}
+	if (operador != ">=" && operador != ">") || valor <= 0 || objetivo <= 0 {
+		return 0
+	}
+
+	return valor * 100 / objetivo
+}
+
+// ProgresoCriterios devuelve el avance hacia una medalla y el índice del criterio que
+// más falta en el grupo más avanzado. El avance de un grupo es el de su criterio más
+// atrasado. Sin criterios devuelve 0 y -1
+func ProgresoCriterios(criterios []models.CriterioMedalla, valores map[string]int) (int, int) {
+	porcentajes := make(map[int]int)
+	limitantes := make(map[int]int)
+	var orden []int
+	for i, c := range criterios {
+		porcentaje := PorcentajeCriterio(valores[c.Metrica], c.Operador, c.Umbral)
+		anterior, ok := porcentajes[c.Grupo]
+		if !ok {
+			orden = append(orden, c.Grupo)
+		}
+		if !ok || porcentaje < anterior {
+			porcentajes[c.Grupo] = porcentaje
+			limitantes[c.Grupo] = i
+		}
+	}
+
+	mejor, limitante := 0, -1
+	for _, grupo := range orden {
+		if limitante == -1 || porcentajes[grupo] > mejor {
+			mejor, limitante = porcentajes[grupo], limitantes[grupo]
+		}
+	}
+
+	return mejor, limitante
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"testing"
+)
+
+func TestCompararCriterio(t *testing.T) {
+	casos := []struct {
+		valor    int
+		operador string
+		umbral   int
+		esperado bool
+	}{
+		{10, ">=", 10, true},
+		{9, ">=", 10, false},
+		{10, ">", 10, false},
+		{11, ">", 10, true},
+		{10, "<=", 10, true},
+		{10, "<", 10, false},
+		{10, "=", 10, true},
+		{10, "!", 10, false},
+	}
+
+	for _, c := range casos {
+		if got := CompararCriterio(c.valor, c.operador, c.umbral); got != c.esperado {
+			t.Errorf("CompararCriterio(%d %s %d): se esperaba %v, se obtuvo %v", c.valor, c.operador, c.umbral, c.esperado, got)
+		}
+	}
+}
+
+func TestCumpleCriterios(t *testing.T) {
+	// (10 amigos Y 500 puntos) O 3 torneos ganados
+	criterios := []models.CriterioMedalla{
+		{Grupo: 0, Metrica: models.MetricaAmigos, Operador: ">=", Umbral: 10},
+		{Grupo: 0, Metrica: models.MetricaPuntos, Operador: ">=", Umbral: 500},
+		{Grupo: 1, Metrica: models.MetricaTorneosGanados, Operador: ">=", Umbral: 3},
+	}
+
+	casos := []struct {
+		nombre   string
+		valores  map[string]int
+		esperado bool
+	}{
+		{"cumple el primer grupo", map[string]int{"amigos": 10, "puntos": 500}, true},
+		{"solo amigos", map[string]int{"amigos": 12, "puntos": 100}, false},
+		{"solo puntos", map[string]int{"amigos": 2, "puntos": 900}, false},
+		{"cumple el segundo grupo", map[string]int{"torneos_ganados": 3}, true},
+		{"sin métricas", map[string]int{}, false},
+	}
+
+	for _, c := range casos {
+		if got := CumpleCriterios(criterios, c.valores); got != c.esperado {
+			t.Errorf("%s: se esperaba %v, se obtuvo %v", c.nombre, c.esperado, got)
+		}
+	}
+
+	if CumpleCriterios(nil, map[string]int{"puntos": 1000}) {
+		t.Error("una medalla sin criterios no debería cumplirse")
+	}
+}
+
+func TestPorcentajeCriterio(t *testing.T) {
+	casos := []struct {
+		valor    int
+		operador string
+		umbral   int
+		esperado int
+	}{
+		{5, ">=", 10, 50},
+		{10, ">=", 10, 100},
+		{15, ">=", 10, 100},
+		{4, ">", 4, 80},
+		{0, ">=", 10, 0},
+		{3, "=", 5, 0},
+		{12, "<=", 10, 0},
+		{8, "<=", 10, 100},
+		{0, ">=", 0, 100},
+	}
+
+	for _, c := range casos {
+		if got := PorcentajeCriterio(c.valor, c.operador, c.umbral); got != c.esperado {
+			t.Errorf("PorcentajeCriterio(%d %s %d): se esperaba %d, se obtuvo %d", c.valor, c.operador, c.umbral, c.esperado, got)
+		}
+	}
+}
+
+func TestProgresoCriterios(t *testing.T) {
+	// (10 amigos Y 500 puntos) O 4 torneos ganados
+	criterios := []models.CriterioMedalla{
+		{Grupo: 0, Metrica: models.MetricaAmigos, Operador: ">=", Umbral: 10},
+		{Grupo: 0, Metrica: models.MetricaPuntos, Operador: ">=", Umbral: 500},
+		{Grupo: 1, Metrica: models.MetricaTorneosGanados, Operador: ">=", Umbral: 4},
+	}
+
+	casos := []struct {
+		nombre     string
+		valores    map[string]int
+		porcentaje int
+		limitante  int
+	}{
+		{"el grupo avanza según su criterio más atrasado", map[string]int{"amigos": 8, "puntos": 100}, 20, 1},
+		{"gana el grupo más avanzado", map[string]int{"amigos": 8, "puntos": 100, "torneos_ganados": 3}, 75, 2},
+		{"empate se queda con el primer grupo", map[string]int{"amigos": 5, "puntos": 250, "torneos_ganados": 2}, 50, 0},
+		{"cumplida", map[string]int{"amigos": 10, "puntos": 600}, 100, 0},
+		{"sin métricas", map[string]int{}, 0, 0},
+	}
+
+	for _, c := range casos {
+		porcentaje, limitante := ProgresoCriterios(criterios, c.valores)
+		if porcentaje != c.porcentaje || limitante != c.limitante {
+			t.Errorf("%s: se esperaba (%d, %d), se obtuvo (%d, %d)", c.nombre, c.porcentaje, c.limitante, porcentaje, limitante)
+		}
+	}
+
+	if porcentaje, limitante := ProgresoCriterios(nil, map[string]int{}); porcentaje != 0 || limitante != -1 {
+		t.Errorf("sin criterios: se esperaba (0, -1), se obtuvo (%d, %d)", porcentaje, limitante)
+	}
+}
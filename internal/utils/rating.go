@@ -0,0 +1,75 @@
+package utils
+
+import "math"
+
+const (
+	// RatingInicial es el rating de un jugador que aún no completó ningún torneo
+	RatingInicial = 1500
+	// TorneosProvisionales es la cantidad de torneos durante la que el rating es provisional
+	TorneosProvisionales = 5
+)
+
+// FactorK devuelve cuánto puede variar el rating en un torneo. Los jugadores con
+// rating provisional se ajustan más rápido
+func FactorK(torneosCalificados int) float64 {
+	if torneosCalificados < TorneosProvisionales {
+		return 40
+	}
+	return 20
+}
+
+// EsRatingProvisional indica si el rating aún no es fiable
+func EsRatingProvisional(torneosCalificados int) bool {
+	return torneosCalificados < TorneosProvisionales
+}
+
+// probabilidadVictoria es el resultado esperado de a frente a b según la fórmula ELO
+func probabilidadVictoria(ratingA, ratingB float64) float64 {
+	return 1 / (1 + math.Pow(10, (ratingB-ratingA)/400))
+}
+
+// DesempenoRelativo compara cada competidor (jugador o equipo) con todos los demás como
+// enfrentamientos de a dos: gana quien tiene más puntos y empatan si tienen los mismos.
+// Devuelve para cada uno el promedio de (resultado - resultado esperado), que multiplicado
+// por FactorK da la variación de rating
+func DesempenoRelativo(ratings []float64, puntos []int) []float64 {
+	desempeno := make([]float64, len(ratings))
+	if len(ratings) < 2 {
+		return desempeno
+	}
+
+	for i := range ratings {
+		for j := range ratings {
+			if i == j {
+				continue
+			}
+
+			resultado := 0.5
+			if puntos[i] > puntos[j] {
+				resultado = 1
+			} else if puntos[i] < puntos[j] {
+				resultado = 0
+			}
+
+			desempeno[i] += resultado - probabilidadVictoria(ratings[i], ratings[j])
+		}
+		desempeno[i] /= float64(len(ratings) - 1)
+	}
+
+	return desempeno
+}
+
+// Posiciones devuelve la posición de cada competidor según sus puntos (1 es el mejor).
+// Los empatados comparten posición
+func Posiciones(puntos []int) []int {
+	posiciones := make([]int, len(puntos))
+	for i := range puntos {
+		posiciones[i] = 1
+		for j := range puntos {
+			if puntos[j] > puntos[i] {
+				posiciones[i]++
+			}
+		}
+	}
+	return posiciones
+}
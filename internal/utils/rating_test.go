@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFactorK(t *testing.T) {
+	casos := []struct {
+		torneos  int
+		esperado float64
+	}{
+		{0, 40},
+		{1, 40},
+		{TorneosProvisionales - 1, 40},
+		{TorneosProvisionales, 20},
+		{100, 20},
+	}
+
+	for _, c := range casos {
+		if got := FactorK(c.torneos); got != c.esperado {
+			t.Errorf("FactorK(%d): se esperaba %v, se obtuvo %v", c.torneos, c.esperado, got)
+		}
+	}
+}
+
+func TestDesempenoRelativo(t *testing.T) {
+	const margen = 1e-9
+
+	casos := []struct {
+		nombre   string
+		ratings  []float64
+		puntos   []int
+		esperado []float64
+	}{
+		{
+			nombre:   "sin rivales",
+			ratings:  []float64{1500},
+			puntos:   []int{10},
+			esperado: []float64{0},
+		},
+		{
+			nombre:   "mismo rating, gana el de más puntos",
+			ratings:  []float64{1500, 1500},
+			puntos:   []int{10, 5},
+			esperado: []float64{0.5, -0.5},
+		},
+		{
+			nombre:   "empate con el mismo rating",
+			ratings:  []float64{1500, 1500},
+			puntos:   []int{7, 7},
+			esperado: []float64{0, 0},
+		},
+		{
+			// Con 400 puntos de diferencia el favorito tiene una probabilidad de 10/11
+			nombre:   "empate entre favorito y no favorito",
+			ratings:  []float64{1900, 1500},
+			puntos:   []int{3, 3},
+			esperado: []float64{0.5 - 10.0/11, 0.5 - 1.0/11},
+		},
+		{
+			nombre:   "tres jugadores con el mismo rating",
+			ratings:  []float64{1500, 1500, 1500},
+			puntos:   []int{30, 20, 10},
+			esperado: []float64{0.5, 0, -0.5},
+		},
+		{
+			nombre:   "tres jugadores con empate en el primer puesto",
+			ratings:  []float64{1500, 1500, 1500},
+			puntos:   []int{30, 30, 10},
+			esperado: []float64{0.25, 0.25, -0.5},
+		},
+	}
+
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			got := DesempenoRelativo(c.ratings, c.puntos)
+			if len(got) != len(c.esperado) {
+				t.Fatalf("se esperaban %d resultados, se obtuvieron %d", len(c.esperado), len(got))
+			}
+
+			var suma float64
+			for i := range got {
+				if math.Abs(got[i]-c.esperado[i]) > margen {
+					t.Errorf("competidor %d: se esperaba %v, se obtuvo %v", i, c.esperado[i], got[i])
+				}
+				suma += got[i]
+			}
+
+			// Lo que gana uno lo pierden los demás
+			if math.Abs(suma) > margen {
+				t.Errorf("la suma de los desempeños debería ser 0, se obtuvo %v", suma)
+			}
+		})
+	}
+}
+
+func TestPosiciones(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		puntos   []int
+		esperado []int
+	}{
+		{"vacío", []int{}, []int{}},
+		{"un competidor", []int{5}, []int{1}},
+		{"sin empates", []int{10, 30, 20}, []int{3, 1, 2}},
+		{"empate en el primer puesto", []int{30, 30, 10}, []int{1, 1, 3}},
+		{"empate en el medio", []int{30, 20, 20, 10}, []int{1, 2, 2, 4}},
+		{"todos empatados", []int{0, 0, 0}, []int{1, 1, 1}},
+	}
+
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if got := Posiciones(c.puntos); !reflect.DeepEqual(got, c.esperado) {
+				t.Errorf("se esperaba %v, se obtuvo %v", c.esperado, got)
+			}
+		})
+	}
+}
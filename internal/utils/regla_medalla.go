@@ -0,0 +1,405 @@
+package utils
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxLargoRegla y maxComparacionesRegla limitan el costo de evaluar una regla
+	maxLargoRegla         = 1000
+	maxComparacionesRegla = 20
+)
+
+// Tipos de los campos que se pueden filtrar dentro de count(...)
+const (
+	TipoCampoTexto    = "texto"
+	TipoCampoEntero   = "entero"
+	TipoCampoBooleano = "booleano"
+)
+
+// FuentesRegla son las fuentes de count(...) y el tipo de cada campo que se puede filtrar
+var FuentesRegla = map[string]map[string]string{
+	models.FuenteAcciones: {
+		"tipo":            TipoCampoTexto,
+		"ciudad":          TipoCampoTexto,
+		"lugar":           TipoCampoTexto,
+		"en_colaboracion": TipoCampoBooleano,
+		"es_para_torneo":  TipoCampoBooleano,
+	},
+	models.FuenteTorneos: {
+		"modalidad":  TipoCampoTexto,
+		"formato":    TipoCampoTexto,
+		"finalizado": TipoCampoBooleano,
+		"ganado":     TipoCampoBooleano,
+		"puntos":     TipoCampoEntero,
+	},
+	models.FuenteAmigos: {},
+}
+
+// MetricaRegla es una métrica de una regla de medalla: una métrica simple por nombre
+// o un conteo sobre una fuente con condiciones opcionales
+type MetricaRegla struct {
+	Nombre      string // Métrica simple o "count"
+	Fuente      string // Solo en count: actions, tournaments o friends
+	Condiciones []CondicionRegla
+}
+
+// CondicionRegla filtra las filas que cuenta un count(...)
+type CondicionRegla struct {
+	Campo    string
+	Operador string
+	Valor    interface{} // string, int o bool según el tipo del campo
+}
+
+// String devuelve la forma canónica de la métrica, que es la que se guarda en los criterios
+func (m MetricaRegla) String() string {
+	if m.Nombre != "count" {
+		return m.Nombre
+	}
+
+	var b strings.Builder
+	b.WriteString("count(")
+	b.WriteString(m.Fuente)
+	for i, c := range m.Condiciones {
+		if i == 0 {
+			b.WriteString(" where ")
+		} else {
+			b.WriteString(" and ")
+		}
+		fmt.Fprintf(&b, "%s %s ", c.Campo, c.Operador)
+		switch v := c.Valor.(type) {
+		case string:
+			b.WriteString("'" + strings.ReplaceAll(v, "'", "''") + "'")
+		default:
+			fmt.Fprint(&b, v)
+		}
+	}
+	b.WriteString(")")
+
+	return b.String()
+}
+
+// ParsearRegla convierte una regla como
+// "count(actions where tipo='descubrimiento' and ciudad='Mérida') >= 5 or streak_days >= 7"
+// en criterios de medalla. Las comparaciones unidas con "and" forman un grupo y "or"
+// empieza el grupo siguiente
+func ParsearRegla(texto string) ([]models.CriterioMedalla, error) {
+	p, err := nuevoParserRegla(texto)
+	if err != nil {
+		return nil, err
+	}
+
+	var criterios []models.CriterioMedalla
+	grupo := 0
+	for {
+		if len(criterios) == maxComparacionesRegla {
+			return nil, fmt.Errorf("la regla no puede tener más de %d comparaciones", maxComparacionesRegla)
+		}
+
+		criterio, err := p.comparacion()
+		if err != nil {
+			return nil, err
+		}
+		criterio.Grupo = grupo
+		criterios = append(criterios, criterio)
+
+		if p.palabra("and") {
+			continue
+		}
+		if p.palabra("or") {
+			grupo++
+			continue
+		}
+		break
+	}
+
+	if !p.terminado() {
+		return nil, fmt.Errorf("se esperaba and, or o el fin de la regla en %q", p.actual().texto)
+	}
+
+	return criterios, nil
+}
+
+// ParsearMetrica valida una métrica suelta, por ejemplo la de un criterio enviado sin regla
+func ParsearMetrica(texto string) (*MetricaRegla, error) {
+	p, err := nuevoParserRegla(texto)
+	if err != nil {
+		return nil, err
+	}
+
+	metrica, err := p.metrica()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.terminado() {
+		return nil, fmt.Errorf("texto inesperado %q después de la métrica", p.actual().texto)
+	}
+
+	return metrica, nil
+}
+
+type tipoToken int
+
+const (
+	tokenFin tipoToken = iota
+	tokenNombre
+	tokenEntero
+	tokenTexto
+	tokenOperador
+	tokenAbre
+	tokenCierra
+)
+
+type token struct {
+	tipo  tipoToken
+	texto string
+}
+
+type parserRegla struct {
+	tokens []token
+	pos    int
+}
+
+func nuevoParserRegla(texto string) (*parserRegla, error) {
+	if strings.TrimSpace(texto) == "" {
+		return nil, fmt.Errorf("la regla está vacía")
+	}
+	if len(texto) > maxLargoRegla {
+		return nil, fmt.Errorf("la regla no puede superar los %d caracteres", maxLargoRegla)
+	}
+
+	tokens, err := separarTokens(texto)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parserRegla{tokens: tokens}, nil
+}
+
+// separarTokens divide la regla en nombres, enteros, textos entre comillas simples
+// (dos comillas seguidas representan una comilla), operadores y paréntesis
+func separarTokens(texto string) ([]token, error) {
+	runas := []rune(texto)
+	var tokens []token
+
+	for i := 0; i < len(runas); {
+		r := runas[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			inicio := i
+			for i < len(runas) && (unicode.IsLetter(runas[i]) || unicode.IsDigit(runas[i]) || runas[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{tokenNombre, strings.ToLower(string(runas[inicio:i]))})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runas) && unicode.IsDigit(runas[i+1])):
+			inicio := i
+			i++
+			for i < len(runas) && unicode.IsDigit(runas[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokenEntero, string(runas[inicio:i])})
+		case r == '\'':
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(runas) {
+					return nil, fmt.Errorf("falta cerrar una comilla en la regla")
+				}
+				if runas[i] == '\'' {
+					if i+1 < len(runas) && runas[i+1] == '\'' {
+						b.WriteRune('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteRune(runas[i])
+				i++
+			}
+			tokens = append(tokens, token{tokenTexto, b.String()})
+		case r == '(':
+			tokens = append(tokens, token{tokenAbre, "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenCierra, ")"})
+			i++
+		case strings.ContainsRune("<>=!", r):
+			if i+1 < len(runas) && runas[i+1] == '=' && r != '=' {
+				tokens = append(tokens, token{tokenOperador, string(runas[i : i+2])})
+				i += 2
+			} else if r == '!' {
+				return nil, fmt.Errorf("operador no válido en la posición %d", i+1)
+			} else {
+				tokens = append(tokens, token{tokenOperador, string(r)})
+				i++
+			}
+		default:
+			return nil, fmt.Errorf("carácter inesperado %q en la posición %d", r, i+1)
+		}
+	}
+
+	return append(tokens, token{tipo: tokenFin, texto: "fin de la regla"}), nil
+}
+
+func (p *parserRegla) actual() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parserRegla) terminado() bool {
+	return p.actual().tipo == tokenFin
+}
+
+// palabra consume la palabra clave indicada si es el token actual
+func (p *parserRegla) palabra(clave string) bool {
+	if t := p.actual(); t.tipo == tokenNombre && t.texto == clave {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parserRegla) esperar(tipo tipoToken, descripcion string) (token, error) {
+	t := p.actual()
+	if t.tipo != tipo {
+		return t, fmt.Errorf("se esperaba %s en lugar de %q", descripcion, t.texto)
+	}
+	p.pos++
+	return t, nil
+}
+
+// comparacion lee "métrica operador entero"
+func (p *parserRegla) comparacion() (models.CriterioMedalla, error) {
+	metrica, err := p.metrica()
+	if err != nil {
+		return models.CriterioMedalla{}, err
+	}
+
+	operador, err := p.esperar(tokenOperador, "un operador de comparación")
+	if err != nil {
+		return models.CriterioMedalla{}, err
+	}
+	if !slices.Contains(models.OperadoresCriterio, operador.texto) {
+		return models.CriterioMedalla{}, fmt.Errorf("el operador %q no se puede usar para comparar una métrica", operador.texto)
+	}
+
+	umbral, err := p.entero()
+	if err != nil {
+		return models.CriterioMedalla{}, err
+	}
+
+	return models.CriterioMedalla{Metrica: metrica.String(), Operador: operador.texto, Umbral: umbral}, nil
+}
+
+func (p *parserRegla) entero() (int, error) {
+	t, err := p.esperar(tokenEntero, "un número entero")
+	if err != nil {
+		return 0, err
+	}
+
+	valor, err := strconv.Atoi(t.texto)
+	if err != nil {
+		return 0, fmt.Errorf("número fuera de rango: %s", t.texto)
+	}
+
+	return valor, nil
+}
+
+// metrica lee una métrica simple o "count(fuente [where condición [and condición]...])"
+func (p *parserRegla) metrica() (*MetricaRegla, error) {
+	nombre, err := p.esperar(tokenNombre, "una métrica")
+	if err != nil {
+		return nil, err
+	}
+
+	if nombre.texto != "count" {
+		if !slices.Contains(models.MetricasSimples, nombre.texto) {
+			return nil, fmt.Errorf("métrica desconocida %q; las disponibles son %s y count(...)",
+				nombre.texto, strings.Join(models.MetricasSimples, ", "))
+		}
+		return &MetricaRegla{Nombre: nombre.texto}, nil
+	}
+
+	if _, err := p.esperar(tokenAbre, "( después de count"); err != nil {
+		return nil, err
+	}
+
+	fuente, err := p.esperar(tokenNombre, "la fuente de count (actions, tournaments o friends)")
+	if err != nil {
+		return nil, err
+	}
+	campos, ok := FuentesRegla[fuente.texto]
+	if !ok {
+		return nil, fmt.Errorf("fuente desconocida %q; las disponibles son actions, tournaments y friends", fuente.texto)
+	}
+
+	metrica := &MetricaRegla{Nombre: "count", Fuente: fuente.texto}
+	if p.palabra("where") {
+		for {
+			condicion, err := p.condicion(fuente.texto, campos)
+			if err != nil {
+				return nil, err
+			}
+			metrica.Condiciones = append(metrica.Condiciones, condicion)
+
+			if !p.palabra("and") {
+				break
+			}
+		}
+	}
+
+	if _, err := p.esperar(tokenCierra, ") al final de count"); err != nil {
+		return nil, err
+	}
+
+	return metrica, nil
+}
+
+// condicion lee "campo operador valor" y comprueba que el valor sea del tipo del campo
+func (p *parserRegla) condicion(fuente string, campos map[string]string) (CondicionRegla, error) {
+	campo, err := p.esperar(tokenNombre, "un campo")
+	if err != nil {
+		return CondicionRegla{}, err
+	}
+
+	tipo, ok := campos[campo.texto]
+	if !ok {
+		return CondicionRegla{}, fmt.Errorf("la fuente %s no tiene el campo %q", fuente, campo.texto)
+	}
+
+	operador, err := p.esperar(tokenOperador, "un operador")
+	if err != nil {
+		return CondicionRegla{}, err
+	}
+	if tipo != TipoCampoEntero && operador.texto != "=" && operador.texto != "!=" {
+		return CondicionRegla{}, fmt.Errorf("el campo %s solo admite = y !=", campo.texto)
+	}
+
+	condicion := CondicionRegla{Campo: campo.texto, Operador: operador.texto}
+	valor := p.actual()
+	switch {
+	case tipo == TipoCampoTexto && valor.tipo == tokenTexto:
+		condicion.Valor = valor.texto
+	case tipo == TipoCampoEntero && valor.tipo == tokenEntero:
+		numero, err := strconv.Atoi(valor.texto)
+		if err != nil {
+			return CondicionRegla{}, fmt.Errorf("número fuera de rango: %s", valor.texto)
+		}
+		condicion.Valor = numero
+	case tipo == TipoCampoBooleano && valor.tipo == tokenNombre && (valor.texto == "true" || valor.texto == "false"):
+		condicion.Valor = valor.texto == "true"
+	default:
+		return CondicionRegla{}, fmt.Errorf("el campo %s necesita un valor de tipo %s en lugar de %q", campo.texto, tipo, valor.texto)
+	}
+	p.pos++
+
+	return condicion, nil
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"backend_proyecto_verde/internal/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsearRegla(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		regla    string
+		esperado []models.CriterioMedalla
+	}{
+		{
+			nombre: "conteo con condiciones",
+			regla:  "count(actions where tipo='descubrimiento' and ciudad='Mérida') >= 5",
+			esperado: []models.CriterioMedalla{
+				{Grupo: 0, Metrica: "count(actions where tipo = 'descubrimiento' and ciudad = 'Mérida')", Operador: ">=", Umbral: 5},
+			},
+		},
+		{
+			nombre: "métrica simple",
+			regla:  "streak_days >= 7",
+			esperado: []models.CriterioMedalla{
+				{Grupo: 0, Metrica: "streak_days", Operador: ">=", Umbral: 7},
+			},
+		},
+		{
+			nombre: "and y or forman grupos",
+			regla:  "amigos >= 10 AND puntos >= 500 or count(tournaments where ganado = true and puntos > 100) >= 3",
+			esperado: []models.CriterioMedalla{
+				{Grupo: 0, Metrica: "amigos", Operador: ">=", Umbral: 10},
+				{Grupo: 0, Metrica: "puntos", Operador: ">=", Umbral: 500},
+				{Grupo: 1, Metrica: "count(tournaments where ganado = true and puntos > 100)", Operador: ">=", Umbral: 3},
+			},
+		},
+		{
+			nombre: "comillas escapadas y conteo sin condiciones",
+			regla:  "count(actions where lugar != 'Parque d''Arc') > 0 and count(friends) = 2",
+			esperado: []models.CriterioMedalla{
+				{Grupo: 0, Metrica: "count(actions where lugar != 'Parque d''Arc')", Operador: ">", Umbral: 0},
+				{Grupo: 0, Metrica: "count(friends)", Operador: "=", Umbral: 2},
+			},
+		},
+	}
+
+	for _, c := range casos {
+		criterios, err := ParsearRegla(c.regla)
+		if err != nil {
+			t.Errorf("%s: error inesperado: %v", c.nombre, err)
+			continue
+		}
+		if !reflect.DeepEqual(criterios, c.esperado) {
+			t.Errorf("%s: se esperaba %+v, se obtuvo %+v", c.nombre, c.esperado, criterios)
+		}
+
+		// La forma canónica de cada métrica se vuelve a leer igual
+		for _, criterio := range criterios {
+			metrica, err := ParsearMetrica(criterio.Metrica)
+			if err != nil {
+				t.Errorf("%s: la métrica canónica %q no se pudo leer: %v", c.nombre, criterio.Metrica, err)
+				continue
+			}
+			if metrica.String() != criterio.Metrica {
+				t.Errorf("%s: se esperaba %q, se obtuvo %q", c.nombre, criterio.Metrica, metrica.String())
+			}
+		}
+	}
+}
+
+func TestParsearReglaErrores(t *testing.T) {
+	casos := []struct {
+		regla   string
+		mensaje string
+	}{
+		{"", "vacía"},
+		{"nivel >= 3", "métrica desconocida"},
+		{"count(photos) >= 1", "fuente desconocida"},
+		{"count(actions where color = 'rojo') >= 1", "no tiene el campo"},
+		{"count(actions where tipo = 3) >= 1", "necesita un valor de tipo texto"},
+		{"count(tournaments where ganado > true) >= 1", "solo admite = y !="},
+		{"count(actions where ciudad = 'Mérida) >= 1", "falta cerrar una comilla"},
+		{"puntos != 100", "no se puede usar para comparar"},
+		{"puntos >= 100 puntos", "se esperaba and, or"},
+		{"puntos >=", "un número entero"},
+		{"count(actions >= 1", ") al final de count"},
+		{"puntos >= 10; DROP TABLE medallas", "carácter inesperado"},
+		{strings.Repeat("puntos >= 1 and ", 20) + "puntos >= 1", "más de 20 comparaciones"},
+	}
+
+	for _, c := range casos {
+		_, err := ParsearRegla(c.regla)
+		if err == nil {
+			t.Errorf("%q: se esperaba un error", c.regla)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.mensaje) {
+			t.Errorf("%q: se esperaba un error con %q, se obtuvo %q", c.regla, c.mensaje, err.Error())
+		}
+	}
+}